@@ -29,16 +29,67 @@ func SetupAdminRouter(r *gin.Engine, apiPrefix string) {
 			users.DELETE("/:id", controllers.DeleteUser)
 		}
 
-		// 管理员产品相关路由
-		products := admin.Group("/products")
+		// 管理员产品相关路由，修改商品和批量导入导出需要管理员权限
+		products := admin.Group("/products", middleware.AdminMiddleware())
 		{
 			products.GET("/", controllers.GetAllProducts)
+			products.GET("/export", controllers.ExportProducts)
+			products.POST("/import", controllers.ImportProducts)
+			products.GET("/import/:job_id", controllers.GetProductImportJob)
 			products.GET("/:id", controllers.GetProduct)
 			products.POST("/", controllers.CreateProduct)
 			products.PUT("/:id", controllers.UpdateProduct)
 			products.DELETE("/:id", controllers.DeleteProduct)
 		}
 
+		// 商品分类路由，需要管理员权限
+		categories := admin.Group("/categories", middleware.AdminMiddleware())
+		{
+			categories.GET("/", controllers.GetCategoryTree)
+			categories.GET("/:id", controllers.GetCategory)
+			categories.POST("/", controllers.CreateCategory)
+			categories.PUT("/:id", controllers.UpdateCategory)
+			categories.DELETE("/:id", controllers.DeleteCategory)
+		}
+
+		// 库存流水和低库存路由，需要管理员权限
+		inventory := admin.Group("/inventory", middleware.AdminMiddleware())
+		{
+			inventory.GET("/ledger", controllers.GetStockLedger)
+			inventory.GET("/low-stock", controllers.GetLowStockProducts)
+			inventory.POST("/adjust", controllers.AdjustStock)
+		}
+
+		// 商品搜索索引路由，重建索引需要管理员权限
+		admin.POST("/search/rebuild", middleware.AdminMiddleware(), controllers.RebuildSearchIndex)
+
+		// 会员钱包相关路由，冻结余额和提现审核打款需要管理员权限
+		wallet := admin.Group("/wallet", middleware.AdminMiddleware())
+		{
+			wallet.POST("/freeze", controllers.FreezeBalance)
+			wallet.POST("/unfreeze", controllers.UnfreezeBalance)
+			wallet.GET("/withdraws", controllers.GetWithdrawList)
+			wallet.PUT("/withdraws/:id/audit", controllers.AuditWithdraw)
+			wallet.POST("/withdraws/batch", controllers.CreateWithdrawBatch)
+			wallet.GET("/withdraws/batch/:batch_no", controllers.DownloadWithdrawBatch)
+		}
+
+		// 团长相关路由，审核和设置等级需要管理员权限
+		tuanzhang := admin.Group("/tuanzhang", middleware.AdminMiddleware())
+		{
+			tuanzhang.GET("/", controllers.GetTuanzhangList)
+			tuanzhang.PUT("/:id/audit", controllers.AuditTuanzhang)
+			tuanzhang.PUT("/:id/level", controllers.SetTuanzhangLevel)
+		}
+
+		// 收入报表路由，kind为operatingcity或tuanzhang，需要管理员权限；会员查看自己的收入使用api端接口
+		reports := admin.Group("/reports", middleware.AdminMiddleware())
+		{
+			reports.GET("/income/:kind", controllers.GetIncomeReport)
+			reports.GET("/income/:kind/lines", controllers.GetIncomeReportLines)
+			reports.GET("/income/:kind/export", controllers.ExportIncomeReport)
+		}
+
 	}
 
 }
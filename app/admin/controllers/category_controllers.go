@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+	"myapi/app/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCategoryTree 处理获取分类树的请求，包含停用的分类
+func GetCategoryTree(c *gin.Context) {
+	categoryService := services.NewCategoryService()
+	tree, err := categoryService.GetAdminTree(helper.GetWeid())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分类失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取分类成功",
+		"data":    tree,
+		"count":   len(tree),
+	})
+}
+
+// GetCategory 处理获取单个分类的请求
+func GetCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分类ID"})
+		return
+	}
+
+	categoryService := services.NewCategoryService()
+	category, err := categoryService.GetByID(helper.GetWeid(), id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取分类成功",
+		"data":    category,
+	})
+}
+
+// CreateCategory 处理新增分类的请求
+func CreateCategory(c *gin.Context) {
+	var category structs.Category
+	if err := c.ShouldBindJSON(&category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	category.Weid = helper.GetWeid()
+
+	categoryService := services.NewCategoryService()
+	if err := categoryService.Create(&category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "新增分类成功",
+		"data":    category,
+	})
+}
+
+// UpdateCategory 处理修改分类的请求，不能把分类移动到自己或下级分类下
+func UpdateCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分类ID"})
+		return
+	}
+
+	var category structs.Category
+	if err := c.ShouldBindJSON(&category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	category.ID = id
+	category.Weid = helper.GetWeid()
+
+	categoryService := services.NewCategoryService()
+	if err := categoryService.Update(&category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "修改分类成功",
+		"data":    category,
+	})
+}
+
+// DeleteCategory 处理删除分类的请求，有下级分类或商品时不能删除
+func DeleteCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分类ID"})
+		return
+	}
+
+	categoryService := services.NewCategoryService()
+	if err := categoryService.Delete(helper.GetWeid(), id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除分类成功"})
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+	"myapi/app/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stockAdjustRequest 调整库存请求参数，change为正数补货、负数扣减
+type stockAdjustRequest struct {
+	GoodsID int    `json:"goods_id" binding:"required,gt=0"`
+	Sku     string `json:"sku"`
+	Change  int    `json:"change" binding:"required"`
+	Remark  string `json:"remark"`
+}
+
+// AdjustStock 处理后台调整商品库存的请求，Sku不为空时同时调整该规格库存
+func AdjustStock(c *gin.Context) {
+	var req stockAdjustRequest
+	if !helper.ValidateRequest(c, &req) {
+		return
+	}
+	if req.Remark == "" {
+		req.Remark = "后台调整"
+	}
+
+	inventoryService := services.NewInventoryService()
+	if err := inventoryService.Adjust(helper.GetWeid(), req.GoodsID, req.Sku, req.Change, req.Remark); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "调整库存成功"})
+}
+
+// GetStockLedger 处理查询库存流水的请求，可按商品、订单和变动类型筛选
+func GetStockLedger(c *gin.Context) {
+	page, limit := helper.GetPage(c)
+	f := structs.StockLedgerFilter{
+		Weid: helper.GetWeid(),
+		Type: c.Query("type"),
+	}
+	f.GoodsID, _ = strconv.Atoi(c.Query("goods_id"))
+	f.OrderID, _ = strconv.Atoi(c.Query("order_id"))
+
+	inventoryService := services.NewInventoryService()
+	list, total, err := inventoryService.GetLedger(f, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取库存流水成功",
+		"data":    list,
+		"count":   total,
+	})
+}
+
+// GetLowStockProducts 处理查询低库存商品的请求，商品或任一规格达到提醒阈值都会列出
+func GetLowStockProducts(c *gin.Context) {
+	page, limit := helper.GetPage(c)
+
+	inventoryService := services.NewInventoryService()
+	list, total, err := inventoryService.GetLowStock(helper.GetWeid(), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取低库存商品成功",
+		"data":    list,
+		"count":   total,
+	})
+}
@@ -4,13 +4,14 @@ import (
 	"net/http"
 	"strconv"
 
+	"myapi/app/helper"
 	"myapi/app/services"
 	"myapi/app/structs"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GetProduct 处理获取单个产品的请求
+// GetProduct 处理获取单个产品的请求，返回编辑需要的规格、图片、描述、数量折扣和分类关联
 func GetProduct(c *gin.Context) {
 	// 1. 从URL参数中获取id
 	idStr := c.Param("id")
@@ -27,12 +28,18 @@ func GetProduct(c *gin.Context) {
 	// 3. 创建服务实例
 	productService := services.NewProductService()
 	// 4. 调用服务层方法
-	product := productService.GetProductByID(id)
+	form, err := productService.GetProductForm(helper.GetWeid(), id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
 	// 5. 返回JSON响应
 	c.JSON(http.StatusOK, gin.H{
 		"message": "获取产品成功",
-		"data":    product,
+		"data":    form,
 	})
 }
 
@@ -53,43 +60,84 @@ func GetAllProducts(c *gin.Context) {
 // CreateProduct 处理创建产品的请求
 func CreateProduct(c *gin.Context) {
 	// 1. 解析请求体
-	var product structs.Product
-	if err := c.ShouldBindJSON(&product); err != nil {
+	var form structs.ProductForm
+	if err := c.ShouldBindJSON(&form); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
+	form.Goods.Weid = helper.GetWeid()
 
 	// 2. 创建服务实例
 	productService := services.NewProductService()
 	// 3. 调用服务层方法
-	err := productService.CreateProduct(&product)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "创建产品失败",
+	if err := productService.CreateProduct(&form); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "创建产品成功",
-		"data":    product,
+		"data":    form,
 	})
 }
 
-// UpdateProduct 处理更新产品的请求
+// UpdateProduct 处理更新产品的请求，规格、图片、描述、数量折扣和分类关联按提交内容整体替换
 func UpdateProduct(c *gin.Context) {
-	// 实现逻辑类似CreateProduct
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的产品ID",
+		})
+		return
+	}
+
+	var form structs.ProductForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	form.Goods.ID = id
+	form.Goods.Weid = helper.GetWeid()
+
+	productService := services.NewProductService()
+	if err := productService.UpdateProduct(&form); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "更新产品功能待实现",
+		"message": "更新产品成功",
+		"data":    form,
 	})
 }
 
-// DeleteProduct 处理删除产品的请求
+// DeleteProduct 处理删除产品的请求，商品软删除并下架
 func DeleteProduct(c *gin.Context) {
-	// 实现逻辑类似GetProduct
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "无效的产品ID",
+		})
+		return
+	}
+
+	productService := services.NewProductService()
+	if err := productService.DeleteProduct(helper.GetWeid(), id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "删除产品功能待实现",
+		"message": "删除产品成功",
 	})
 }
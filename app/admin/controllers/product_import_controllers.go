@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"myapi/app/config"
+	"myapi/app/helper"
+	"myapi/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportProducts 处理导出商品的请求，format为csv或xlsx，每个SKU一行
+func ExportProducts(c *gin.Context) {
+	format := c.DefaultQuery("format", helper.ExportCSV)
+
+	importService := services.NewProductImportService()
+	name, data, err := importService.Export(helper.GetWeid(), format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+name)
+	c.Data(http.StatusOK, helper.ExportContentType(format), data)
+}
+
+// ImportProducts 处理导入商品的请求，上传字段为file，dry_run=1时只返回校验结果和预览
+// 商品较多时转为后台任务，返回job_id供查询进度
+func ImportProducts(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传导入文件"})
+		return
+	}
+	maxSize := int64(config.GetInt("productImportMaxSize", 10)) << 20
+	if header.Size > maxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("导入文件不能超过%dMB", maxSize>>20)})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取导入文件失败"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取导入文件失败"})
+		return
+	}
+
+	dryRun := c.PostForm("dry_run") == "1" || c.Query("dry_run") == "1"
+	importService := services.NewProductImportService()
+	result, job, err := importService.Import(helper.GetWeid(), c.GetInt("adminID"), header.Filename, data, dryRun)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if job != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "导入任务已创建",
+			"data":    job,
+		})
+		return
+	}
+	message := "导入完成"
+	if dryRun {
+		message = "校验完成"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    result,
+		"count":   len(result.Errors),
+	})
+}
+
+// GetProductImportJob 处理查询后台导入任务进度的请求
+func GetProductImportJob(c *gin.Context) {
+	importService := services.NewProductImportService()
+	job, err := importService.GetJob(helper.GetWeid(), c.GetInt("adminID"), c.Param("job_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取导入任务成功",
+		"data":    job,
+	})
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+	"myapi/app/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// incomeReportFilter 从请求参数构建收入报表查询条件，value指定时只查该周期，用于下钻
+func incomeReportFilter(c *gin.Context) structs.IncomeReportFilter {
+	f := structs.IncomeReportFilter{
+		Kind:   c.Param("kind"),
+		Weid:   helper.GetWeid(),
+		Period: c.Query("period"),
+		From:   c.Query("from"),
+		To:     c.Query("to"),
+	}
+	if accountID, _ := strconv.Atoi(c.Query("account_id")); accountID > 0 {
+		f.AccountIDs = []int{accountID}
+	}
+	if value := c.Query("value"); value != "" {
+		f.From, f.To = value, value
+	}
+	return f
+}
+
+// GetIncomeReport 处理获取代理或团长收入汇总报表的请求
+func GetIncomeReport(c *gin.Context) {
+	page, limit := helper.GetPage(c)
+
+	reportService := services.NewReportService()
+	result, err := reportService.GetIncomeSummary(incomeReportFilter(c), page, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取收入报表成功",
+		"data":    result,
+		"count":   result["total"],
+	})
+}
+
+// GetIncomeReportLines 处理获取收入明细的请求
+func GetIncomeReportLines(c *gin.Context) {
+	page, limit := helper.GetPage(c)
+
+	reportService := services.NewReportService()
+	result, err := reportService.GetIncomeLines(incomeReportFilter(c), page, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取收入明细成功",
+		"data":    result["list"],
+		"count":   result["total"],
+	})
+}
+
+// ExportIncomeReport 处理导出收入报表的请求，format为csv或xlsx，detail=1时导出明细
+func ExportIncomeReport(c *gin.Context) {
+	format := c.DefaultQuery("format", helper.ExportCSV)
+
+	reportService := services.NewReportService()
+	name, data, err := reportService.ExportIncome(incomeReportFilter(c), format, c.Query("detail") == "1")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+name)
+	c.Data(http.StatusOK, helper.ExportContentType(format), data)
+}
@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"net/http"
+
+	"myapi/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RebuildSearchIndex 从数据库全量重建商品搜索索引
+func RebuildSearchIndex(c *gin.Context) {
+	searchService := services.NewSearchService()
+	count, err := searchService.Rebuild()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "重建搜索索引成功",
+		"count":   count,
+	})
+}
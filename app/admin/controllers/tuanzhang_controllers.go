@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTuanzhangList 处理获取团长列表的请求，status默认为全部
+func GetTuanzhangList(c *gin.Context) {
+	page, limit := helper.GetPage(c)
+	status, err := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	if err != nil {
+		status = -1
+	}
+
+	tuanzhangService := services.NewTuanzhangService()
+	list, total, err := tuanzhangService.GetList(helper.GetWeid(), status, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取团长列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取团长列表成功",
+		"data":    list,
+		"count":   total,
+	})
+}
+
+// AuditTuanzhang 处理审核团长申请的请求，通过时需要指定团长等级，驳回时需要填写原因
+func AuditTuanzhang(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的团长ID"})
+		return
+	}
+
+	var req struct {
+		Pass   bool   `json:"pass"`
+		Level  int    `json:"level"`
+		Remark string `json:"remark"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tuanzhangService := services.NewTuanzhangService()
+	if err := tuanzhangService.Audit(id, req.Pass, req.Level, req.Remark); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "审核成功"})
+}
+
+// SetTuanzhangLevel 处理调整团长等级的请求
+func SetTuanzhangLevel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的团长ID"})
+		return
+	}
+
+	var req struct {
+		Level int `json:"level" binding:"required,gt=0"`
+	}
+	if !helper.ValidateRequest(c, &req) {
+		return
+	}
+
+	tuanzhangService := services.NewTuanzhangService()
+	if err := tuanzhangService.SetLevel(id, req.Level); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "调整等级成功"})
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// walletFreezeRequest 冻结/解冻余额请求参数
+type walletFreezeRequest struct {
+	Uid     int     `json:"uid" binding:"required,gt=0"`
+	Amount  float64 `json:"amount" binding:"required,gt=0"`
+	BizType string  `json:"biz_type"`
+	BizID   int     `json:"biz_id"`
+	Remark  string  `json:"remark"`
+}
+
+// FreezeBalance 处理冻结会员余额的请求
+func FreezeBalance(c *gin.Context) {
+	var req walletFreezeRequest
+	if !helper.ValidateRequest(c, &req) {
+		return
+	}
+
+	walletService := services.NewWalletService()
+	if err := walletService.Freeze(helper.GetWeid(), req.Uid, req.Amount, req.BizType, req.BizID, req.Remark); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "冻结成功"})
+}
+
+// UnfreezeBalance 处理解冻会员余额的请求
+func UnfreezeBalance(c *gin.Context) {
+	var req walletFreezeRequest
+	if !helper.ValidateRequest(c, &req) {
+		return
+	}
+
+	walletService := services.NewWalletService()
+	if err := walletService.Unfreeze(helper.GetWeid(), req.Uid, req.Amount, req.BizType, req.BizID, req.Remark); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "解冻成功"})
+}
+
+// GetWithdrawList 处理获取提现申请列表的请求
+func GetWithdrawList(c *gin.Context) {
+	page, limit := helper.GetPage(c)
+	uid, _ := strconv.Atoi(c.DefaultQuery("uid", "0"))
+	status, err := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	if err != nil {
+		status = -1
+	}
+
+	walletService := services.NewWalletService()
+	list, total, err := walletService.GetWithdrawList(helper.GetWeid(), uid, c.Query("ptype"), status, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取提现申请失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取提现申请成功",
+		"data":    list,
+		"count":   total,
+	})
+}
+
+// AuditWithdraw 处理审核提现申请的请求
+func AuditWithdraw(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的提现申请ID"})
+		return
+	}
+
+	var req struct {
+		Pass   bool   `json:"pass"`
+		Remark string `json:"remark"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	walletService := services.NewWalletService()
+	if err := walletService.AuditWithdraw(id, req.Pass, req.Remark); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "审核成功"})
+}
+
+// CreateWithdrawBatch 处理生成打款批次的请求，把审核通过未打款的提现申请编入新批次并下载CSV文件
+func CreateWithdrawBatch(c *gin.Context) {
+	exportWithdrawBatch(c, "")
+}
+
+// DownloadWithdrawBatch 处理重新下载打款批次文件的请求
+func DownloadWithdrawBatch(c *gin.Context) {
+	exportWithdrawBatch(c, c.Param("batch_no"))
+}
+
+// exportWithdrawBatch 导出打款批次CSV文件
+func exportWithdrawBatch(c *gin.Context, batchNo string) {
+	walletService := services.NewWalletService()
+	batchNo, data, err := walletService.ExportWithdrawBatch(helper.GetWeid(), batchNo)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=withdraw_"+batchNo+".csv")
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+}
@@ -42,6 +42,68 @@ func SetupAPIRoutes(r *gin.Engine, apiPrefix string) {
 
 			products.POST("/buynowinfo", controllers.BuyNowInfo)
 		}
+
+		// 秒杀排队路由
+		miaoshaQueue := api.Group("/miaosha/queue")
+		{
+			miaoshaQueue.POST("", controllers.JoinMiaoshaQueue)             // 进入排队
+			miaoshaQueue.GET("/:ticket", controllers.GetMiaoshaQueueTicket) // 查询排队结果
+		}
+
+		// 拼团路由
+		tuan := api.Group("/tuan")
+		{
+			tuan.POST("/open", controllers.OpenTuan)     // 开团
+			tuan.POST("/join", controllers.JoinTuan)     // 参团
+			tuan.GET("/my", controllers.GetMyTuanFounds) // 我的拼团
+		}
+
+		// 团长路由
+		tuanzhang := api.Group("/tuanzhang")
+		{
+			tuanzhang.POST("/apply", controllers.ApplyTuanzhang)            // 申请成为团长
+			tuanzhang.GET("/info", controllers.GetMyTuanzhang)              // 团长信息和审核状态
+			tuanzhang.GET("/dashboard", controllers.GetTuanzhangDashboard)  // 团长工作台
+			tuanzhang.GET("/incomelog", controllers.GetTuanzhangIncomeLogs) // 团长收入明细
+		}
+
+		// 推荐团队路由
+		referral := api.Group("/referral")
+		{
+			referral.GET("/stat", controllers.GetReferralTeamStat)     // 团队人数统计
+			referral.GET("/team", controllers.GetReferralTeam)         // 团队成员
+			referral.GET("/upline", controllers.GetReferralUpline)     // 上级推荐人
+			referral.GET("/invite", controllers.GetReferralInvite)     // 邀请码和分享令牌
+			referral.GET("/invitees", controllers.GetReferralInvitees) // 直接邀请的会员
+			referral.POST("/bind", controllers.BindReferrer)           // 绑定推荐人
+		}
+
+		// 收入报表路由，kind为operatingcity或tuanzhang
+		reports := api.Group("/reports")
+		{
+			reports.GET("/income/:kind", controllers.GetMyIncomeReport)           // 按月或按年汇总
+			reports.GET("/income/:kind/lines", controllers.GetMyIncomeLines)      // 收入明细
+			reports.GET("/income/:kind/export", controllers.ExportMyIncomeReport) // 导出CSV或XLSX
+		}
+
+		// 订单路由
+		orders := api.Group("/orders")
+		{
+			orders.POST("/:id/confirm", controllers.ConfirmOrder) // 确认收货
+			orders.POST("/:id/cancel", controllers.CancelOrder)   // 取消待付款订单
+		}
+
+		// 钱包相关路由
+		wallet := api.Group("/wallet")
+		{
+			wallet.GET("/info", controllers.GetWallet)                        // 余额和冻结金额
+			wallet.GET("/ledger", controllers.GetWalletLedger)                // 钱包流水
+			wallet.POST("/recharge", controllers.Recharge)                    // 余额充值
+			wallet.POST("/payorder", controllers.PayOrderByBalance)           // 余额支付订单
+			wallet.POST("/withdraw", controllers.ApplyWithdraw)               // 申请提现
+			wallet.GET("/withdraws", controllers.GetWithdrawList)             // 提现记录
+			wallet.GET("/withdraw/accounts", controllers.GetWithdrawAccounts) // 可提现账户
+		}
 	}
 
 	// 公共API路由组，不需要认证
@@ -63,10 +125,39 @@ func SetupAPIRoutes(r *gin.Engine, apiPrefix string) {
 		public.POST("/login/sms", controllers.LoginBySmsCode)
 		public.POST("/login/sendsms", controllers.SendSmsCode)
 
+		// 支付通知路由
+		public.POST("/pay/notify/:code", controllers.PayNotify)
+
 		// 产品相关路由
 		products := public.Group("/products")
 		{
-			products.GET("/", controllers.GetAllProducts)
+			products.GET("/", controllers.GetProductList)
+			products.GET("/:id", controllers.GetProductDetail)
+			products.GET("/:id/combination", controllers.GetCombinationDetail)
+		}
+
+		// 商品分类路由
+		public.GET("/categories/tree", controllers.GetCategoryTree)
+
+		// 商品搜索路由
+		search := public.Group("/search")
+		{
+			search.GET("/", controllers.SearchProducts)
+			search.GET("/suggest", controllers.SearchSuggest)
+		}
+
+		// 秒杀场次路由
+		miaosha := public.Group("/miaosha")
+		{
+			miaosha.GET("/sessions", controllers.GetMiaoshaSessions)
+			miaosha.GET("/sessions/:id/goods", controllers.GetMiaoshaSessionGoods)
+		}
+
+		// 拼团展示路由
+		tuanPublic := public.Group("/tuan")
+		{
+			tuanPublic.GET("/goods/:id/founds", controllers.GetTuanOpenFounds) // 正在拼团中的开团
+			tuanPublic.GET("/founds/:id", controllers.GetTuanFoundDetail)      // 开团详情
 		}
 
 	}
@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"myapi/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCategoryTree 获取分类树，ptype按分类类型筛选，is_show_home按是否首页展示筛选顶级分类，不传时不筛选
+func GetCategoryTree(c *gin.Context) {
+	ptype, _ := strconv.Atoi(c.DefaultQuery("ptype", "0"))
+	isShowHome, err := strconv.Atoi(c.DefaultQuery("is_show_home", "-1"))
+	if err != nil {
+		isShowHome = -1
+	}
+
+	categoryService := services.NewCategoryService()
+	tree, err := categoryService.GetTree(getWeid(c), ptype, isShowHome)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": tree})
+}
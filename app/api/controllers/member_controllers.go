@@ -4,6 +4,7 @@ import (
 	"log"
 	"myapi/app/helper"
 	"myapi/app/services"
+	"myapi/app/structs"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -15,6 +16,7 @@ func LoginByPassword(c *gin.Context) {
 	var loginData struct {
 		Username string `json:"username" binding:"required"`
 		Password string `json:"password" binding:"required"`
+		Invite   string `json:"invite"` // 邀请码或分享令牌，可选
 	}
 
 	// 使用公共验证器验证请求
@@ -38,6 +40,9 @@ func LoginByPassword(c *gin.Context) {
 		return
 	}
 
+	// 携带邀请信息时尝试绑定推荐人，绑定失败不影响登录
+	bindInviter(member, loginData.Invite)
+
 	// 5. 返回登录成功响应
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
@@ -55,6 +60,7 @@ func LoginBySmsCode(c *gin.Context) {
 	var loginData struct {
 		Telephone string `json:"telephone" binding:"required,len=11" customvalidate:"mobile"`
 		Code      string `json:"code" binding:"required,len=6"`
+		Invite    string `json:"invite"` // 邀请码或分享令牌，可选
 	}
 	// 使用公共验证器验证请求
 	if !helper.ValidateRequest(c, &loginData) {
@@ -77,6 +83,9 @@ func LoginBySmsCode(c *gin.Context) {
 		return
 	}
 
+	// 携带邀请信息时尝试绑定推荐人，绑定失败不影响登录
+	bindInviter(member, loginData.Invite)
+
 	// 5. 返回登录成功响应
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
@@ -120,3 +129,13 @@ func SendSmsCode(c *gin.Context) {
 		"message": "验证码已发送，请注意查收",
 	})
 }
+
+// bindInviter 登录时携带了邀请码或分享令牌且会员未绑定推荐人时绑定推荐人
+func bindInviter(member *structs.Member, invite string) {
+	if invite == "" || member.Pid > 0 {
+		return
+	}
+	if err := services.NewReferralService().Bind(member.ID, invite); err != nil {
+		log.Printf("会员%d登录时绑定推荐人失败: %v", member.ID, err)
+	}
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMiaoshaSessions 获取当天的秒杀场次
+func GetMiaoshaSessions(c *gin.Context) {
+	miaoshaService := services.NewMiaoshaService()
+	sessions, err := miaoshaService.GetSessions(getWeid(c))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": sessions})
+}
+
+// GetMiaoshaSessionGoods 获取秒杀场次中的商品
+func GetMiaoshaSessionGoods(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "秒杀场次ID无效"})
+		return
+	}
+	page, limit := helper.GetPage(c)
+
+	miaoshaService := services.NewMiaoshaService()
+	result, err := miaoshaService.GetSessionGoods(getWeid(c), id, page, limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": result})
+}
+
+// JoinMiaoshaQueue 秒杀请求进入排队，返回排队凭证
+func JoinMiaoshaQueue(c *gin.Context) {
+	var queueData struct {
+		GoodsID   int64  `json:"goodsId" binding:"required,gt=0"`
+		Msid      int64  `json:"msid" binding:"required,gt=0"`
+		BuyNumber int64  `json:"buyNumber" binding:"required,gt=0"`
+		Sku       string `json:"sku"`
+		AddressID int    `json:"address_id"`
+	}
+	if !helper.ValidateRequest(c, &queueData) {
+		return
+	}
+
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	queueService := services.NewSeckillQueueService()
+	ticket, err := queueService.Enqueue(&services.SeckillQueueRequest{
+		Weid:      getWeid(c),
+		Uid:       uid,
+		GoodsID:   queueData.GoodsID,
+		Msid:      queueData.Msid,
+		Sku:       queueData.Sku,
+		BuyNumber: queueData.BuyNumber,
+		AddressID: queueData.AddressID,
+		Ip:        helper.GetRealIP(c),
+	})
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": helper.ErrorCode(err), "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": ticket})
+}
+
+// GetMiaoshaQueueTicket 查询秒杀排队结果
+func GetMiaoshaQueueTicket(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	queueService := services.NewSeckillQueueService()
+	ticket, err := queueService.GetTicket(uid, c.Param("ticket"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": ticket})
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfirmOrder 确认收货，订单完成后分销佣金解冻到推荐人余额
+func ConfirmOrder(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || orderID <= 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "订单ID错误"})
+		return
+	}
+
+	orderService := services.NewOrderService()
+	order, err := orderService.Complete(uid, orderID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": order})
+}
+
+// CancelOrder 取消待付款订单，预留的库存回补
+func CancelOrder(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || orderID <= 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "订单ID错误"})
+		return
+	}
+
+	orderService := services.NewOrderService()
+	order, err := orderService.Cancel(uid, orderID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": order})
+}
@@ -3,25 +3,56 @@ package controllers
 import (
 	"log"
 	"net/http"
+	"strconv"
 
 	"myapi/app/helper"
 	"myapi/app/services" // 导入服务层
+	"myapi/app/structs"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GetAllProducts 处理获取所有产品的请求
-func GetAllProducts(c *gin.Context) {
-	// 1. 创建服务实例
+// GetProductList 分页获取商品列表，支持分类、价格区间、标签、代理和店铺筛选，按价格、销量或上架时间排序
+// 传cursor时按游标翻页，忽略page
+func GetProductList(c *gin.Context) {
+	page, limit := helper.GetPage(c)
+	filter := structs.ProductListFilter{Weid: getWeid(c)}
+	filter.CatID, _ = strconv.Atoi(c.Query("cat_id"))
+	filter.Ocid, _ = strconv.Atoi(c.Query("ocid"))
+	filter.Sid, _ = strconv.Atoi(c.Query("sid"))
+	filter.MinPrice, _ = strconv.ParseFloat(c.Query("min_price"), 64)
+	filter.MaxPrice, _ = strconv.ParseFloat(c.Query("max_price"), 64)
+	filter.IsHot, _ = strconv.Atoi(c.Query("is_hot"))
+	filter.IsNew, _ = strconv.Atoi(c.Query("is_new"))
+	filter.IsRecommended, _ = strconv.Atoi(c.Query("is_recommended"))
+
+	// 价格默认从低到高，销量和上架时间默认从高到低
+	filter.Sort = c.DefaultQuery("sort", structs.ProductSortDefault)
+	filter.Desc = filter.Sort == structs.ProductSortSales || filter.Sort == structs.ProductSortNewest
+	switch c.Query("order") {
+	case "asc":
+		filter.Desc = false
+	case "desc":
+		filter.Desc = true
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		parsed, err := services.ParseProductCursor(cursor)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+			return
+		}
+		filter.Cursor = parsed
+	}
+
 	productService := services.NewProductService()
-	// 2. 调用服务层方法
-	products := productService.GetAllProducts()
+	result, err := productService.GetProductList(filter, page, limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "获取产品列表成功",
-		"data":    products,
-		"count":   len(products),
-	})
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": result})
 }
 
 // BuyNowInfo 处理立即购买信息请求
@@ -37,6 +68,8 @@ func BuyNowInfo(c *gin.Context) {
 		Sku        string `json:"sku"`         // SKU，可选
 		IsSkumore  int    `json:"is_skumore"`  // 是否多规格，可选
 		Skumore    int    `json:"skumore"`     // 多规格详情，可选
+		AddressID  int    `json:"address_id"`  // 收货地址ID，可选，用于计算运费
+		Ticket     string `json:"ticket"`      // 秒杀排队凭证，秒杀时必填
 	}
 
 	// 使用公共验证器验证请求
@@ -55,6 +88,7 @@ func BuyNowInfo(c *gin.Context) {
 	params["Skumore"] = buyData.Skumore
 	params["BuyNumber"] = buyData.BuyNumber
 	params["GoodsID"] = buyData.Goods_id
+	params["AddressID"] = buyData.AddressID
 
 	// 解析goods_id为整数
 	params["Uid"] = helper.UID(c)
@@ -80,6 +114,14 @@ func BuyNowInfo(c *gin.Context) {
 	// 	}
 	// }
 	params["Uid"] = helper.UID(c)
+	// 秒杀须先排队，只有排队已放行的凭证才能直接获取立即购买信息
+	if buyData.Msid > 0 && !services.NewSeckillQueueService().Admitted(helper.UID(c), buyData.Msid, buyData.Ticket) {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 1,
+			"msg":  "请先排队抢购",
+		})
+		return
+	}
 
 	log.Printf("验证后id: %v", params["Uid"])
 	// 设置weid和ip字段
@@ -98,7 +140,7 @@ func BuyNowInfo(c *gin.Context) {
 	result, err := productService.GetBuyNowInfo(params)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
-			"code": 1,
+			"code": helper.ErrorCode(err),
 			"msg":  err.Error(),
 		})
 		return
@@ -111,3 +153,39 @@ func BuyNowInfo(c *gin.Context) {
 		"data": result,
 	})
 }
+
+// GetCombinationDetail 获取套装商品详情及组件列表
+func GetCombinationDetail(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "商品ID无效"})
+		return
+	}
+
+	productService := services.NewProductService()
+	detail, err := productService.GetCombinationDetail(id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": detail})
+}
+
+// GetProductDetail 获取商品详情，包括图片、描述、规格、数量折扣和进行中的活动
+func GetProductDetail(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "商品ID无效"})
+		return
+	}
+
+	productService := services.NewProductService()
+	detail, err := productService.GetProductDetail(id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": detail})
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReferralTeamStat 获取我的团队总人数和各层级人数
+func GetReferralTeamStat(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+	maxDepth, _ := strconv.Atoi(c.DefaultQuery("max_depth", "0"))
+
+	referralService := services.NewReferralService()
+	result, err := referralService.GetTeamStat(uid, maxDepth)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": result})
+}
+
+// GetReferralTeam 分页获取我的团队成员，depth指定层级，不传时返回整个团队
+func GetReferralTeam(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+	depth, _ := strconv.Atoi(c.DefaultQuery("depth", "0"))
+	page, limit := helper.GetPage(c)
+
+	referralService := services.NewReferralService()
+	list, total, err := referralService.GetTeam(uid, depth, page, limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": gin.H{
+		"list":  list,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	}})
+}
+
+// GetReferralUpline 获取我的上级推荐人
+func GetReferralUpline(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	referralService := services.NewReferralService()
+	list, err := referralService.GetUpline(uid)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": list})
+}
+
+// GetReferralInvite 获取我的邀请码、分享令牌、推荐人和直推人数
+func GetReferralInvite(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	referralService := services.NewReferralService()
+	result, err := referralService.GetInviteInfo(uid)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": result})
+}
+
+// GetReferralInvitees 分页获取我直接邀请的会员
+func GetReferralInvitees(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+	page, limit := helper.GetPage(c)
+
+	referralService := services.NewReferralService()
+	list, total, err := referralService.GetTeam(uid, 1, page, limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": gin.H{
+		"list":  list,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	}})
+}
+
+// BindReferrer 通过邀请码或分享令牌绑定推荐人
+func BindReferrer(c *gin.Context) {
+	var req struct {
+		Invite string `json:"invite" binding:"required"`
+	}
+	if !helper.ValidateRequest(c, &req) {
+		return
+	}
+
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	referralService := services.NewReferralService()
+	if err := referralService.Bind(uid, req.Invite); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "绑定成功"})
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+	"myapi/app/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memberIncomeFilter 从请求参数构建收入报表查询条件，并限制在会员自己名下的代理或团长
+func memberIncomeFilter(c *gin.Context, uid int) (structs.IncomeReportFilter, error) {
+	f := structs.IncomeReportFilter{
+		Kind:   c.Param("kind"),
+		Weid:   getWeid(c),
+		Period: c.Query("period"),
+		From:   c.Query("from"),
+		To:     c.Query("to"),
+	}
+	if value := c.Query("value"); value != "" {
+		f.From, f.To = value, value
+	}
+	accountID, _ := strconv.Atoi(c.Query("account_id"))
+	err := services.NewReportService().MemberFilter(&f, uid, accountID)
+	return f, err
+}
+
+// GetMyIncomeReport 获取我的代理或团长收入汇总报表，period为month或year
+func GetMyIncomeReport(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+	f, err := memberIncomeFilter(c, uid)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+	page, limit := helper.GetPage(c)
+
+	reportService := services.NewReportService()
+	result, err := reportService.GetIncomeSummary(f, page, limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": result})
+}
+
+// GetMyIncomeLines 获取我的收入明细，value指定月份或年份时只查该周期
+func GetMyIncomeLines(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+	f, err := memberIncomeFilter(c, uid)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+	page, limit := helper.GetPage(c)
+
+	reportService := services.NewReportService()
+	result, err := reportService.GetIncomeLines(f, page, limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": result})
+}
+
+// ExportMyIncomeReport 导出我的收入报表，format为csv或xlsx，detail=1时导出明细
+func ExportMyIncomeReport(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+	f, err := memberIncomeFilter(c, uid)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+	format := c.DefaultQuery("format", helper.ExportCSV)
+
+	reportService := services.NewReportService()
+	name, data, err := reportService.ExportIncome(f, format, c.Query("detail") == "1")
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+name)
+	c.Data(http.StatusOK, helper.ExportContentType(format), data)
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchProducts 搜索商品，支持中文、英文前缀和拼音首字母，结果按相关度和销量排序
+func SearchProducts(c *gin.Context) {
+	page, limit := helper.GetPage(c)
+
+	searchService := services.NewSearchService()
+	result, err := searchService.Search(getWeid(c), c.Query("keyword"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": result})
+}
+
+// SearchSuggest 获取搜索联想词
+func SearchSuggest(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "0"))
+
+	searchService := services.NewSearchService()
+	words, err := searchService.Suggest(getWeid(c), c.Query("keyword"), limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": words})
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenTuan 开团，返回的开团ID在下单时作为jointuanid传入
+func OpenTuan(c *gin.Context) {
+	var openData struct {
+		Tuanid int `json:"tuanid" binding:"required,gt=0"`
+	}
+	if !helper.ValidateRequest(c, &openData) {
+		return
+	}
+
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	tuanService := services.NewTuanService()
+	found, follow, err := tuanService.Open(uid, openData.Tuanid)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": gin.H{"found": found, "follow": follow}})
+}
+
+// JoinTuan 参加已有的团
+func JoinTuan(c *gin.Context) {
+	var joinData struct {
+		Jointuanid int `json:"jointuanid" binding:"required,gt=0"`
+	}
+	if !helper.ValidateRequest(c, &joinData) {
+		return
+	}
+
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	tuanService := services.NewTuanService()
+	found, follow, err := tuanService.Join(uid, joinData.Jointuanid)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": gin.H{"found": found, "follow": follow}})
+}
+
+// GetTuanOpenFounds 获取团购商品正在拼团中的开团
+func GetTuanOpenFounds(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "团购商品ID无效"})
+		return
+	}
+	page, limit := helper.GetPage(c)
+
+	tuanService := services.NewTuanService()
+	result, err := tuanService.GetOpenFounds(getWeid(c), id, page, limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": result})
+}
+
+// GetTuanFoundDetail 获取开团详情和参团成员
+func GetTuanFoundDetail(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "开团ID无效"})
+		return
+	}
+
+	tuanService := services.NewTuanService()
+	result, err := tuanService.GetFoundDetail(id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": result})
+}
+
+// GetMyTuanFounds 获取我参加的拼团
+func GetMyTuanFounds(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+	page, limit := helper.GetPage(c)
+
+	tuanService := services.NewTuanService()
+	result, err := tuanService.GetMyFounds(uid, page, limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": result})
+}
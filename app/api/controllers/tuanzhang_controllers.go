@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ApplyTuanzhang 提交团长申请，需要上传身份证正反面照片等待后台审核
+func ApplyTuanzhang(c *gin.Context) {
+	var req services.TuanzhangApplyRequest
+	if !helper.ValidateRequest(c, &req) {
+		return
+	}
+
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	tuanzhangService := services.NewTuanzhangService()
+	tuanzhang, err := tuanzhangService.Apply(getWeid(c), uid, &req)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "申请已提交，请等待审核", "data": tuanzhang})
+}
+
+// GetMyTuanzhang 获取自己的团长信息和审核状态
+func GetMyTuanzhang(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	tuanzhangService := services.NewTuanzhangService()
+	tuanzhang, err := tuanzhangService.GetMine(getWeid(c), uid)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": tuanzhang})
+}
+
+// GetTuanzhangDashboard 获取团长工作台数据，months为按月汇总的月数
+func GetTuanzhangDashboard(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+	months, _ := strconv.Atoi(c.DefaultQuery("months", "6"))
+
+	tuanzhangService := services.NewTuanzhangService()
+	result, err := tuanzhangService.GetDashboard(getWeid(c), uid, months)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": result})
+}
+
+// GetTuanzhangIncomeLogs 获取团长收入明细
+func GetTuanzhangIncomeLogs(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+	page, limit := helper.GetPage(c)
+
+	tuanzhangService := services.NewTuanzhangService()
+	list, total, err := tuanzhangService.GetIncomeLogs(getWeid(c), uid, page, limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": gin.H{
+		"list":  list,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	}})
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+	"myapi/app/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getWeid 获取当前站点ID，未登录或token中没有weid时使用默认值
+func getWeid(c *gin.Context) int {
+	weid := helper.Weid(c)
+	if weid <= 0 {
+		weid = helper.GetWeid()
+	}
+	return weid
+}
+
+// GetWallet 获取会员钱包信息
+func GetWallet(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	walletService := services.NewWalletService()
+	wallet, err := walletService.GetWallet(uid)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": wallet})
+}
+
+// GetWalletLedger 获取会员钱包流水
+func GetWalletLedger(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+	page, limit := helper.GetPage(c)
+
+	walletService := services.NewWalletService()
+	list, total, err := walletService.GetLedger(uid, page, limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{"list": list, "total": total, "page": page, "limit": limit},
+	})
+}
+
+// Recharge 余额充值
+func Recharge(c *gin.Context) {
+	var rechargeData struct {
+		Amount      float64 `json:"amount" binding:"required,gt=0"`
+		PaymentCode string  `json:"payment_code" binding:"required"`
+	}
+	if !helper.ValidateRequest(c, &rechargeData) {
+		return
+	}
+
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	walletService := services.NewWalletService()
+	result, err := walletService.Recharge(getWeid(c), uid, rechargeData.Amount, rechargeData.PaymentCode)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": result})
+}
+
+// PayOrderByBalance 余额支付订单
+func PayOrderByBalance(c *gin.Context) {
+	var payData struct {
+		OrderID      int    `json:"order_id" binding:"required,gt=0"`
+		SeckillToken string `json:"seckill_token"` // 秒杀订单的预留令牌，立即购买时返回
+	}
+	if !helper.ValidateRequest(c, &payData) {
+		return
+	}
+
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	walletService := services.NewWalletService()
+	order, err := walletService.PayOrder(uid, payData.OrderID, payData.SeckillToken)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": helper.ErrorCode(err), "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "支付成功",
+		"data": gin.H{"order_id": order.ID, "order_status_id": order.OrderStatusID},
+	})
+}
+
+// ApplyWithdraw 申请提现
+func ApplyWithdraw(c *gin.Context) {
+	var withdrawData struct {
+		Ptype       string  `json:"ptype"`      // 提现账户类型：member/operatingcity/tuanzhang，默认member
+		AccountID   int     `json:"account_id"` // 城市代理提现时为运营城市ID
+		Amount      float64 `json:"amount" binding:"required,gt=0"`
+		CollectType string  `json:"collect_type" binding:"required"`
+		AccountName string  `json:"account_name" binding:"required"`
+		AccountNo   string  `json:"account_no" binding:"required"`
+		BankName    string  `json:"bank_name"`
+	}
+	if !helper.ValidateRequest(c, &withdrawData) {
+		return
+	}
+
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	withdraw := &structs.Withdraw{
+		Weid:        getWeid(c),
+		Uid:         uid,
+		Ptype:       withdrawData.Ptype,
+		AccountID:   withdrawData.AccountID,
+		Amount:      withdrawData.Amount,
+		CollectType: withdrawData.CollectType,
+		AccountName: withdrawData.AccountName,
+		AccountNo:   withdrawData.AccountNo,
+		BankName:    withdrawData.BankName,
+	}
+
+	walletService := services.NewWalletService()
+	if err := walletService.ApplyWithdraw(withdraw); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "提现申请已提交",
+		"data": gin.H{"id": withdraw.ID, "withdraw_sn": withdraw.WithdrawSn, "fee": withdraw.Fee, "actual_amount": withdraw.ActualAmount},
+	})
+}
+
+// GetWithdrawAccounts 获取可提现的账户和可提现金额
+func GetWithdrawAccounts(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+
+	walletService := services.NewWalletService()
+	accounts, err := walletService.GetWithdrawAccounts(getWeid(c), uid)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": accounts})
+}
+
+// GetWithdrawList 获取会员提现记录
+func GetWithdrawList(c *gin.Context) {
+	uid := helper.UID(c)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "未登录"})
+		return
+	}
+	page, limit := helper.GetPage(c)
+	status, err := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	if err != nil {
+		status = -1
+	}
+
+	walletService := services.NewWalletService()
+	list, total, err := walletService.GetWithdrawList(getWeid(c), uid, c.Query("ptype"), status, page, limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{"list": list, "total": total, "page": page, "limit": limit},
+	})
+}
+
+// PayNotify 处理支付渠道异步通知
+func PayNotify(c *gin.Context) {
+	if err := services.HandlePayNotify(c.Param("code"), c.Request); err != nil {
+		log.Printf("处理支付通知失败: %v", err)
+		c.String(http.StatusBadRequest, "fail")
+		return
+	}
+	c.String(http.StatusOK, "success")
+}
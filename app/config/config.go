@@ -39,17 +39,7 @@ func Init() {
 						log.Printf("成功加载配置文件: %s", envFile)
 						// 将配置合并到全局config map中
 						for key, value := range envMap {
-							// 尝试将值转换为合适的类型
-							if boolValue, err := strconv.ParseBool(value); err == nil {
-								config[key] = boolValue
-							} else if intValue, err := strconv.Atoi(value); err == nil {
-								config[key] = intValue
-							} else if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
-								config[key] = floatValue
-							} else {
-								// 保留为字符串
-								config[key] = value
-							}
+							config[key] = ParseValue(value)
 						}
 					}
 				}
@@ -59,6 +49,21 @@ func Init() {
 	})
 }
 
+// ParseValue 把配置文件中的值转换为合适的类型，依次尝试int、float64、bool，都不是时保留为字符串
+// 数字优先于布尔值，避免0和1被当作false和true，导致GetInt、GetFloat取不到值
+func ParseValue(value string) interface{} {
+	if intValue, err := strconv.Atoi(value); err == nil {
+		return intValue
+	}
+	if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+		return floatValue
+	}
+	if boolValue, err := strconv.ParseBool(value); err == nil {
+		return boolValue
+	}
+	return value
+}
+
 // GetConfig 获取全局配置map（单例模式）
 func GetConfig() map[string]interface{} {
 	if config == nil {
@@ -119,6 +124,11 @@ func GetBool(key string, defaultValue bool) bool {
 		return boolValue
 	}
 
+	// 0和1按整数加载，非0为true
+	if intValue, ok := value.(int); ok {
+		return intValue != 0
+	}
+
 	// 尝试将字符串转换为布尔值
 	if strValue, ok := value.(string); ok {
 		if boolValue, err := strconv.ParseBool(strValue); err == nil {
@@ -0,0 +1,37 @@
+package helper
+
+import "errors"
+
+// 业务错误码，接口返回的code字段，客户端可按错误码展示提示
+const (
+	CodeError              = 1     // 通用错误
+	CodeSeckillSoldOut     = 10301 // 秒杀商品已抢光
+	CodeSeckillMemberLimit = 10302 // 超过秒杀每人限购数量
+	CodeSeckillSkuNotFound = 10303 // 秒杀规格不存在
+	CodeSeckillQueueFull   = 10304 // 秒杀排队人数已满
+)
+
+// BizError 带业务错误码的错误
+type BizError struct {
+	Code int
+	Msg  string
+}
+
+// Error 实现error接口
+func (e *BizError) Error() string {
+	return e.Msg
+}
+
+// NewBizError 创建带业务错误码的错误
+func NewBizError(code int, msg string) *BizError {
+	return &BizError{Code: code, Msg: msg}
+}
+
+// ErrorCode 获取错误对应的业务错误码，普通错误返回通用错误码
+func ErrorCode(err error) int {
+	var bizErr *BizError
+	if errors.As(err, &bizErr) {
+		return bizErr.Code
+	}
+	return CodeError
+}
@@ -0,0 +1,156 @@
+package helper
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+)
+
+// 导出文件格式
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+)
+
+// ExportContentType 导出文件的Content-Type
+func ExportContentType(format string) string {
+	if format == ExportXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// ExportFile 按格式生成导出文件，format不是xlsx时生成CSV
+func ExportFile(format, sheet string, header []string, rows [][]interface{}) ([]byte, error) {
+	if format == ExportXLSX {
+		return XLSXBytes(sheet, header, rows)
+	}
+	return CSVBytes(header, rows)
+}
+
+// CSVBytes 生成CSV文件内容，带UTF-8 BOM以便Excel直接打开，小数按两位输出
+func CSVBytes(header []string, rows [][]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = exportText(v)
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// XLSXBytes 生成只有一个工作表的xlsx文件，数字写为数值单元格，其他写为文本单元格
+func XLSXBytes(sheet string, header []string, rows [][]interface{}) ([]byte, error) {
+	var sheetXML bytes.Buffer
+	sheetXML.WriteString(xml.Header)
+	sheetXML.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	headerRow := make([]interface{}, len(header))
+	for i, h := range header {
+		headerRow[i] = h
+	}
+	writeXLSXRow(&sheetXML, headerRow)
+	for _, row := range rows {
+		writeXLSXRow(&sheetXML, row)
+	}
+	sheetXML.WriteString(`</sheetData></worksheet>`)
+
+	var sheetName bytes.Buffer
+	if err := xml.EscapeText(&sheetName, []byte(sheet)); err != nil {
+		return nil, err
+	}
+	files := []struct {
+		name, body string
+	}{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="` + sheetName.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`},
+		{"xl/worksheets/sheet1.xml", sheetXML.String()},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(f.body)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeXLSXRow 写入一行单元格
+func writeXLSXRow(buf *bytes.Buffer, row []interface{}) {
+	buf.WriteString("<row>")
+	for _, v := range row {
+		switch n := v.(type) {
+		case int, int64, float64:
+			buf.WriteString(`<c><v>` + exportNumber(n) + `</v></c>`)
+		default:
+			buf.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(buf, []byte(exportText(v)))
+			buf.WriteString(`</t></is></c>`)
+		}
+	}
+	buf.WriteString("</row>")
+}
+
+// exportNumber 数值单元格的值
+func exportNumber(v interface{}) string {
+	switch n := v.(type) {
+	case int:
+		return strconv.Itoa(n)
+	case int64:
+		return strconv.FormatInt(n, 10)
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	return "0"
+}
+
+// exportText 单元格的文本，小数保留两位
+func exportText(v interface{}) string {
+	switch n := v.(type) {
+	case string:
+		return n
+	case float64:
+		return fmt.Sprintf("%.2f", n)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(n)
+	}
+}
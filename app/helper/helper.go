@@ -127,6 +127,22 @@ func Tzid(c *gin.Context) int {
 	return tzid
 }
 
+// GetPage 获取分页参数page和limit，limit最大100
+func GetPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return page, limit
+}
+
 // GetClient 获取客户端类型
 func GetClient(c *gin.Context) string {
 	ptype := c.Query("from")
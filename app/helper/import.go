@@ -0,0 +1,218 @@
+package helper
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ReadSpreadsheet 读取上传的CSV或xlsx文件，按扩展名判断格式，xlsx只读取第一个工作表
+// 返回的每行已去掉单元格首尾空白，空行被跳过
+func ReadSpreadsheet(filename string, data []byte) ([][]string, error) {
+	var records [][]string
+	var err error
+	switch strings.ToLower(path.Ext(filename)) {
+	case "." + ExportCSV:
+		records, err = readCSV(data)
+	case "." + ExportXLSX:
+		records, err = readXLSX(data)
+	default:
+		return nil, errors.New("只支持csv或xlsx文件")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([][]string, 0, len(records))
+	for _, record := range records {
+		empty := true
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+			if record[i] != "" {
+				empty = false
+			}
+		}
+		if !empty {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+// readCSV 读取CSV，去掉UTF-8 BOM，允许各行列数不同
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, errors.New("CSV文件格式错误")
+	}
+	return records, nil
+}
+
+// xlsxText 共享字符串或行内字符串，富文本由多个r组成
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+// String 合并富文本
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// xlsxWorksheet 工作表中需要读取的部分
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX 读取xlsx第一个工作表的单元格文本
+func readXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.New("xlsx文件格式错误")
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxText `xml:"si"`
+		}
+		if err := decodeZipXML(f, &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			shared = append(shared, item.String())
+		}
+	}
+
+	f, ok := files[firstXLSXSheet(files)]
+	if !ok {
+		return nil, errors.New("xlsx文件没有工作表")
+	}
+	var sheet xlsxWorksheet
+	if err := decodeZipXML(f, &sheet); err != nil {
+		return nil, err
+	}
+
+	records := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		record := make([]string, 0, len(row.Cells))
+		for i, cell := range row.Cells {
+			col := xlsxColumnIndex(cell.Ref)
+			if col < 0 {
+				col = i
+			}
+			for len(record) < col {
+				record = append(record, "")
+			}
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				idx, err := strconv.Atoi(cell.Value)
+				value = ""
+				if err == nil && idx >= 0 && idx < len(shared) {
+					value = shared[idx]
+				}
+			case "inlineStr":
+				value = cell.Inline.String()
+			}
+			if col < len(record) {
+				record[col] = value
+			} else {
+				record = append(record, value)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// firstXLSXSheet 从workbook和关系文件中找到第一个工作表的路径，找不到时使用sheet1.xml
+func firstXLSXSheet(files map[string]*zip.File) string {
+	const fallback = "xl/worksheets/sheet1.xml"
+	var workbook struct {
+		Sheets []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	wf, ok1 := files["xl/workbook.xml"]
+	rf, ok2 := files["xl/_rels/workbook.xml.rels"]
+	if !ok1 || !ok2 || decodeZipXML(wf, &workbook) != nil || decodeZipXML(rf, &rels) != nil || len(workbook.Sheets) == 0 {
+		return fallback
+	}
+	for _, rel := range rels.Items {
+		if rel.ID != workbook.Sheets[0].RID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/")
+		}
+		return path.Join("xl", rel.Target)
+	}
+	return fallback
+}
+
+// decodeZipXML 解析压缩包中的XML文件
+func decodeZipXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return errors.New("xlsx文件格式错误")
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return errors.New("xlsx文件格式错误")
+	}
+	return nil
+}
+
+// xlsxColumnIndex 把单元格引用(如C12)的列字母转换为从0开始的列号，无法识别时返回-1
+func xlsxColumnIndex(ref string) int {
+	col := 0
+	n := 0
+	for _, ch := range ref {
+		if ch >= 'A' && ch <= 'Z' {
+			col = col*26 + int(ch-'A'+1)
+			n++
+			continue
+		}
+		break
+	}
+	if n == 0 {
+		return -1
+	}
+	return col - 1
+}
@@ -0,0 +1,161 @@
+package helper
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// searchRun 文本中连续的同类字符，han为true表示汉字
+type searchRun struct {
+	text []rune
+	han  bool
+}
+
+// splitSearchRuns 把文本切成连续的汉字段和字母数字段，其余字符作为分隔符，字母统一转小写
+func splitSearchRuns(text string) []searchRun {
+	runs := make([]searchRun, 0)
+	var current *searchRun
+	for _, r := range text {
+		han := unicode.Is(unicode.Han, r)
+		if !han && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			current = nil
+			continue
+		}
+		if current == nil || current.han != han {
+			runs = append(runs, searchRun{han: han})
+			current = &runs[len(runs)-1]
+		}
+		current.text = append(current.text, unicode.ToLower(r))
+	}
+	return runs
+}
+
+// SegmentText 切分待索引文本，字母数字按连续字符成词，汉字同时输出单字和相邻双字
+func SegmentText(text string) []string {
+	terms := make([]string, 0)
+	for _, run := range splitSearchRuns(text) {
+		if !run.han {
+			terms = append(terms, string(run.text))
+			continue
+		}
+		for i := range run.text {
+			terms = append(terms, string(run.text[i]))
+			if i+1 < len(run.text) {
+				terms = append(terms, string(run.text[i:i+2]))
+			}
+		}
+	}
+	return terms
+}
+
+// SegmentQuery 切分搜索词，汉字只取相邻双字，单个汉字才用单字，结果去重
+func SegmentQuery(text string) []string {
+	terms := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	for _, run := range splitSearchRuns(text) {
+		if !run.han || len(run.text) == 1 {
+			add(string(run.text))
+			continue
+		}
+		for i := 0; i+1 < len(run.text); i++ {
+			add(string(run.text[i : i+2]))
+		}
+	}
+	return terms
+}
+
+// pinyinInitialBounds GB2312一级汉字按拼音排序，每个声母对应的起始编码
+var pinyinInitialBounds = []struct {
+	code    int
+	initial byte
+}{
+	{0xB0A1, 'a'}, {0xB0C5, 'b'}, {0xB2C1, 'c'}, {0xB4EE, 'd'}, {0xB6EA, 'e'},
+	{0xB7A2, 'f'}, {0xB8C1, 'g'}, {0xB9FE, 'h'}, {0xBBF7, 'j'}, {0xBFA6, 'k'},
+	{0xC0AC, 'l'}, {0xC2E8, 'm'}, {0xC4C3, 'n'}, {0xC5B6, 'o'}, {0xC5BE, 'p'},
+	{0xC6DA, 'q'}, {0xC8BB, 'r'}, {0xC8F6, 's'}, {0xCBFA, 't'}, {0xCDDA, 'w'},
+	{0xCEF4, 'x'}, {0xD1B9, 'y'}, {0xD4D1, 'z'},
+}
+
+// pinyinInitialEnd GB2312一级汉字的结束编码，二级汉字按部首排序，无法取声母
+const pinyinInitialEnd = 0xD7F9
+
+// PinyinInitials 获取文本的拼音首字母，字母数字原样转小写保留，无法识别的汉字和其他字符忽略
+func PinyinInitials(text string) string {
+	encoder := simplifiedchinese.GBK.NewEncoder()
+	var b strings.Builder
+	for _, r := range text {
+		if r < unicode.MaxASCII {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				b.WriteRune(unicode.ToLower(r))
+			}
+			continue
+		}
+		if !unicode.Is(unicode.Han, r) {
+			continue
+		}
+		encoded, err := encoder.String(string(r))
+		if err != nil || len(encoded) != 2 {
+			continue
+		}
+		code := int(encoded[0])<<8 | int(encoded[1])
+		if code < pinyinInitialBounds[0].code || code > pinyinInitialEnd {
+			continue
+		}
+		for i := len(pinyinInitialBounds) - 1; i >= 0; i-- {
+			if code >= pinyinInitialBounds[i].code {
+				b.WriteByte(pinyinInitialBounds[i].initial)
+				break
+			}
+		}
+	}
+	return b.String()
+}
+
+// HighlightText 用em标签包裹文本中命中的检索词，忽略字母大小写，相邻或重叠的命中合并为一段，其余内容做HTML转义
+func HighlightText(text string, terms []string) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	for _, term := range terms {
+		needle := []rune(strings.ToLower(term))
+		if len(needle) == 0 {
+			continue
+		}
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) == string(needle) {
+				for j := i; j < i+len(needle); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			b.WriteString("<em>" + segment + "</em>")
+		} else {
+			b.WriteString(segment)
+		}
+		i = j
+	}
+	return b.String()
+}
@@ -0,0 +1,14 @@
+package helper
+
+// TreeHasCycle 判断把节点id的上级改为pid后是否形成环，parents为节点ID到上级ID的映射，0表示顶级
+// 从pid沿上级链向上查找，遇到id或已有的环都视为成环
+func TreeHasCycle(parents map[int]int, id, pid int) bool {
+	visited := make(map[int]bool)
+	for current := pid; current != 0; current = parents[current] {
+		if current == id || visited[current] {
+			return true
+		}
+		visited[current] = true
+	}
+	return false
+}
@@ -1,39 +1,44 @@
 package middleware
 
 import (
+	"net/http"
+
+	"myapi/app/helper"
+	"myapi/app/models"
+
 	"github.com/gin-gonic/gin"
 )
 
-// AdminMiddleware 管理员权限验证中间件
+// AdminMiddleware 管理员权限验证中间件，须放在AuthMiddleware之后
+// 登录会员需在ims_users_relation中绑定(ptype=admin)一个启用的管理员账号，验证通过后把管理员ID写入上下文adminID
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		userID := helper.UID(c)
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "未认证",
+			})
+			c.Abort()
+			return
+		}
 
-		// 实际项目中，这里应该从请求中获取用户信息，并验证用户是否为管理员
-		// 这里为了演示，直接假设用户已经通过了认证，并且是管理员
-		// 你可以根据实际情况修改这部分逻辑
-
-		// 例如，从上下文中获取用户ID
-		// userID := helper.UID(c)
-		// if userID == 0 {
-		// 	c.JSON(http.StatusUnauthorized, gin.H{
-		// 		"error": "未认证",
-		// 	})
-		// 	c.Abort()
-		// 	return
-		// }
-
-		// 假设这里有一个函数用于检查用户是否为管理员
-		// isAdmin := checkIfUserIsAdmin(userID.(int))
-		// if !isAdmin {
-		// 	c.JSON(http.StatusForbidden, gin.H{
-		// 		"error": "权限不足，需要管理员权限",
-		// 	})
-		// 	c.Abort()
-		// 	return
-		// }
+		adminID, err := models.GetActiveAdminID(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "验证管理员权限失败",
+			})
+			c.Abort()
+			return
+		}
+		if adminID == 0 {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "权限不足，需要管理员权限",
+			})
+			c.Abort()
+			return
+		}
 
-		// 如果是管理员，继续处理请求
+		c.Set("adminID", adminID)
 		c.Next()
-		return
 	}
 }
@@ -0,0 +1,208 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"myapi/app/helper"
+	"myapi/app/storage"
+	"myapi/app/structs"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 分类维护错误
+var (
+	ErrCategoryNotFound       = errors.New("分类不存在")
+	ErrCategoryParentNotFound = errors.New("上级分类不存在")
+	ErrCategoryCycle          = errors.New("不能把分类移动到自己或下级分类下")
+	ErrCategoryHasChildren    = errors.New("请先删除下级分类")
+	ErrCategoryHasGoods       = errors.New("分类下还有商品，不能删除")
+)
+
+// categoryEditableColumns 后台可修改的分类字段
+var categoryEditableColumns = []string{
+	"pid", "title", "is_binding", "is_ordercount", "is_storagelocation", "ptype", "servicetime_ptype",
+	"deliverymode", "submit_order_txt", "ordergoodsremark", "order1remark", "image", "meta_keyword",
+	"meta_description", "sort", "status", "is_show_home", "update_time",
+}
+
+// GetCategories 获取站点的分类，onlyEnabled为true时只取启用的分类，按排序值和ID升序
+func GetCategories(weid int, onlyEnabled bool) ([]structs.Category, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	query := gormDB.Table(categoryTableName).Where("weid = ?", weid)
+	if onlyEnabled {
+		query = query.Where("status = 1")
+	}
+	var categories []structs.Category
+	if err := query.Order("sort ASC, id ASC").Find(&categories).Error; err != nil {
+		log.Printf("查询分类列表失败: %v", err)
+		return nil, err
+	}
+	return categories, nil
+}
+
+// GetCategoryGoodsCounts 统计每个分类下的上架商品数，主分类和多分类关联的商品都计入，同一商品只算一次
+func GetCategoryGoodsCounts(weid int) (map[int]int, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var rows []struct {
+		CategoryID int
+		Total      int
+	}
+	err := gormDB.Raw("SELECT t.category_id, COUNT(DISTINCT t.goods_id) AS total FROM ("+
+		"SELECT id AS goods_id, cat_id AS category_id FROM "+goodsTableName+" WHERE weid = ? AND status = 1 AND cat_id > 0 "+
+		"UNION ALL SELECT gc.goods_id, gc.category_id FROM "+goodsToCategoryTableName+" AS gc "+
+		"JOIN "+goodsTableName+" AS g ON g.id = gc.goods_id WHERE g.weid = ? AND g.status = 1"+
+		") AS t GROUP BY t.category_id", weid, weid).Scan(&rows).Error
+	if err != nil {
+		log.Printf("统计分类商品数失败: %v", err)
+		return nil, err
+	}
+
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Total
+	}
+	return counts, nil
+}
+
+// categoryParents 锁定站点的全部分类，返回分类ID到上级ID的映射
+func categoryParents(tx *gorm.DB, weid int) (map[int]int, error) {
+	var rows []structs.Category
+	if err := tx.Table(categoryTableName).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id, pid").
+		Where("weid = ?", weid).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	parents := make(map[int]int, len(rows))
+	for _, row := range rows {
+		parents[row.ID] = row.Pid
+	}
+	return parents, nil
+}
+
+// CreateCategory 新增分类，上级分类必须属于同一站点
+func CreateCategory(category *structs.Category) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		parents, err := categoryParents(tx, category.Weid)
+		if err != nil {
+			log.Printf("查询分类失败: %v", err)
+			return err
+		}
+		if _, ok := parents[category.Pid]; category.Pid != 0 && !ok {
+			return ErrCategoryParentNotFound
+		}
+
+		now := int(time.Now().Unix())
+		category.ID = 0
+		category.CreateTime = now
+		category.UpdateTime = now
+		if err := tx.Table(categoryTableName).Create(category).Error; err != nil {
+			log.Printf("新增分类失败: %v", err)
+			return err
+		}
+		return nil
+	})
+}
+
+// UpdateCategory 修改分类，修改上级时拒绝移动到自己或下级分类下
+func UpdateCategory(category *structs.Category) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		parents, err := categoryParents(tx, category.Weid)
+		if err != nil {
+			log.Printf("查询分类失败: %v", err)
+			return err
+		}
+		if _, ok := parents[category.ID]; !ok {
+			return ErrCategoryNotFound
+		}
+		if _, ok := parents[category.Pid]; category.Pid != 0 && !ok {
+			return ErrCategoryParentNotFound
+		}
+		if helper.TreeHasCycle(parents, category.ID, category.Pid) {
+			return ErrCategoryCycle
+		}
+
+		category.UpdateTime = int(time.Now().Unix())
+		if err := tx.Table(categoryTableName).
+			Where("id = ? AND weid = ?", category.ID, category.Weid).
+			Select(categoryEditableColumns).
+			Updates(category).Error; err != nil {
+			log.Printf("修改分类失败: %v", err)
+			return err
+		}
+		return nil
+	})
+}
+
+// DeleteCategory 删除分类，有下级分类或主分类商品时拒绝删除，同时清除多分类关联
+func DeleteCategory(weid, id int) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		parents, err := categoryParents(tx, weid)
+		if err != nil {
+			log.Printf("查询分类失败: %v", err)
+			return err
+		}
+		if _, ok := parents[id]; !ok {
+			return ErrCategoryNotFound
+		}
+		for _, pid := range parents {
+			if pid == id {
+				return ErrCategoryHasChildren
+			}
+		}
+
+		var goods int64
+		if err := tx.Table(goodsTableName).Where("cat_id = ?", id).Count(&goods).Error; err != nil {
+			log.Printf("统计分类商品失败: %v", err)
+			return err
+		}
+		if goods > 0 {
+			return ErrCategoryHasGoods
+		}
+
+		if err := tx.Table(goodsToCategoryTableName).Where("category_id = ?", id).
+			Delete(&structs.GoodsToCategory{}).Error; err != nil {
+			log.Printf("删除分类商品关联失败: %v", err)
+			return err
+		}
+		if err := tx.Table(categoryTableName).Where("id = ? AND weid = ?", id, weid).
+			Delete(&structs.Category{}).Error; err != nil {
+			log.Printf("删除分类失败: %v", err)
+			return err
+		}
+		return nil
+	})
+}
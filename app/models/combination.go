@@ -0,0 +1,228 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"myapi/app/storage"
+	"myapi/app/structs"
+
+	"gorm.io/gorm"
+)
+
+// findGoodsSkuValue 按规格值查找商品SKU，规格值以逗号分隔，匹配方式与CartGoods一致
+func findGoodsSkuValue(db *gorm.DB, goodsID int, sku string) (*structs.GoodsSkuValue, error) {
+	var skuValue structs.GoodsSkuValue
+	if err := whereSkuValue(db.Table(goodsSkuValueTableName), goodsID, sku).First(&skuValue).Error; err != nil {
+		return nil, err
+	}
+	return &skuValue, nil
+}
+
+// GetCombinationItems 获取套装商品的组件明细
+func GetCombinationItems(parentID int) ([]structs.CombinationItem, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var combinations []structs.GoodsCombination
+	result := gormDB.Table(goodsCombinationTableName).
+		Where("parent_id = ?", parentID).
+		Order("id ASC").
+		Find(&combinations)
+	if result.Error != nil {
+		log.Printf("查询套装组件失败: %v", result.Error)
+		return nil, result.Error
+	}
+	if len(combinations) == 0 {
+		return []structs.CombinationItem{}, nil
+	}
+
+	goodsIDs := make([]int, 0, len(combinations))
+	for _, combination := range combinations {
+		goodsIDs = append(goodsIDs, combination.GoodsID)
+	}
+	var goods []structs.Product
+	if err := gormDB.Table(goodsTableName).Where("id IN ?", goodsIDs).Find(&goods).Error; err != nil {
+		log.Printf("查询套装组件商品失败: %v", err)
+		return nil, err
+	}
+	goodsMap := make(map[int]structs.Product, len(goods))
+	for _, g := range goods {
+		goodsMap[g.ID] = g
+	}
+
+	items := make([]structs.CombinationItem, 0, len(combinations))
+	for _, combination := range combinations {
+		g, ok := goodsMap[combination.GoodsID]
+		if !ok {
+			return nil, fmt.Errorf("套装组件商品%d不存在", combination.GoodsID)
+		}
+
+		item := structs.CombinationItem{
+			GoodsID:  g.ID,
+			Name:     g.Name,
+			Image:    g.Image,
+			Sku:      combination.Sku,
+			Price:    g.Price,
+			Numbers:  combination.Numbers,
+			Quantity: g.Quantity,
+			Subtract: g.Subtract,
+			Status:   g.Status,
+		}
+		if item.Numbers <= 0 {
+			item.Numbers = 1
+		}
+
+		// 组件指定了规格时，价格和库存以规格为准
+		if combination.Sku != "" {
+			skuValue, err := findGoodsSkuValue(gormDB, g.ID, combination.Sku)
+			if err != nil {
+				log.Printf("查询套装组件规格失败: %v", err)
+				return nil, fmt.Errorf("套装组件%s的规格%s不存在", g.Name, combination.Sku)
+			}
+			item.SkuvID = skuValue.ID
+			item.Price = skuValue.Price
+			item.Quantity = skuValue.Quantity
+			if skuValue.Image != "" {
+				item.Image = skuValue.Image
+			}
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// CheckCombinationStock 检查购买quantity套时每个组件的状态和库存
+func CheckCombinationStock(items []structs.CombinationItem, quantity int) error {
+	if len(items) == 0 {
+		return errors.New("套装商品未设置组件")
+	}
+	for _, item := range items {
+		if item.Status != 1 {
+			return fmt.Errorf("套装组件%s已下架", item.Name)
+		}
+		// 不扣减库存的组件不限制购买数量
+		if item.Subtract != 1 {
+			continue
+		}
+		if item.Quantity < item.Numbers*quantity {
+			return fmt.Errorf("套装组件%s库存不足", item.Name)
+		}
+	}
+	return nil
+}
+
+// CombinationStockItems 把库存项中的套装商品换成组件，组件按每套数量×购买套数计算，套装本身不扣库存
+// combinations为套装商品ID到组件的映射，不在映射中的商品原样保留；结果中同一商品同一规格的数量合并
+func CombinationStockItems(items []structs.StockItem, combinations map[int][]structs.GoodsCombination) ([]structs.StockItem, error) {
+	expanded := make([]structs.StockItem, 0, len(items))
+	for _, item := range items {
+		components, ok := combinations[item.GoodsID]
+		if !ok {
+			expanded = append(expanded, item)
+			continue
+		}
+		if len(components) == 0 {
+			return nil, errors.New("套装商品未设置组件")
+		}
+		for _, component := range components {
+			numbers := component.Numbers
+			if numbers <= 0 {
+				numbers = 1
+			}
+			expanded = append(expanded, structs.StockItem{
+				GoodsID:  component.GoodsID,
+				Sku:      component.Sku,
+				Quantity: numbers * item.Quantity,
+			})
+		}
+	}
+	return mergeStockItems(expanded), nil
+}
+
+// expandCombinationItems 查询库存项中的套装商品和组件，把套装换成组件库存项，须在事务中调用
+func expandCombinationItems(tx *gorm.DB, items []structs.StockItem) ([]structs.StockItem, error) {
+	if len(items) == 0 {
+		return items, nil
+	}
+	goodsIDs := make([]int, 0, len(items))
+	for _, item := range items {
+		goodsIDs = append(goodsIDs, item.GoodsID)
+	}
+	var parentIDs []int
+	if err := tx.Table(goodsTableName).Where("id IN ? AND is_combination = 1", goodsIDs).Pluck("id", &parentIDs).Error; err != nil {
+		log.Printf("查询套装商品失败: %v", err)
+		return nil, err
+	}
+	if len(parentIDs) == 0 {
+		return items, nil
+	}
+
+	var rows []structs.GoodsCombination
+	if err := tx.Table(goodsCombinationTableName).Where("parent_id IN ?", parentIDs).Order("id ASC").Find(&rows).Error; err != nil {
+		log.Printf("查询套装组件失败: %v", err)
+		return nil, err
+	}
+	combinations := make(map[int][]structs.GoodsCombination, len(parentIDs))
+	for _, id := range parentIDs {
+		combinations[id] = nil
+	}
+	for _, row := range rows {
+		combinations[row.ParentID] = append(combinations[row.ParentID], row)
+	}
+	return CombinationStockItems(items, combinations)
+}
+
+// SaveOrderCombinationRevenue 保存订单套装商品的组件收入分摊，同一订单只保存一次
+func SaveOrderCombinationRevenue(orderID int, rows []structs.OrderGoodsCombination) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table(orderGoodsCombinationTableName).Where("order_id = ?", orderID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		now := int(time.Now().Unix())
+		for i := range rows {
+			rows[i].OrderID = orderID
+			rows[i].CreateTime = now
+		}
+		if err := tx.Table(orderGoodsCombinationTableName).Create(&rows).Error; err != nil {
+			log.Printf("保存套装组件收入分摊失败: %v", err)
+			return err
+		}
+		return nil
+	})
+}
+
+// GetOrderCombinationRevenue 获取订单套装商品的组件收入分摊
+func GetOrderCombinationRevenue(orderID int) ([]structs.OrderGoodsCombination, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var list []structs.OrderGoodsCombination
+	if err := gormDB.Table(orderGoodsCombinationTableName).Where("order_id = ?", orderID).Order("id ASC").Find(&list).Error; err != nil {
+		log.Printf("查询套装组件收入分摊失败: %v", err)
+		return nil, err
+	}
+	return list, nil
+}
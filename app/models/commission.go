@@ -0,0 +1,210 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"myapi/app/config"
+	"myapi/app/storage"
+	"myapi/app/structs"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CommissionableAmount 订单可计佣金额：实付金额扣除运费，不小于0
+func CommissionableAmount(total, freight float64) float64 {
+	amount := roundMoney(total - freight)
+	if amount < 0 {
+		return 0
+	}
+	return amount
+}
+
+// CapCommission 按订单佣金上限截断本次佣金，used为订单已分配的佣金，maxPercent为佣金占可计佣金额的最大百分比
+func CapCommission(income, base, used, maxPercent float64) float64 {
+	remaining := roundMoney(base*maxPercent/100 - used)
+	if income > remaining {
+		income = remaining
+	}
+	if income < 0 {
+		return 0
+	}
+	return roundMoney(income)
+}
+
+// reserveOrderCommission 在订单佣金上限内占用佣金额度，返回实际可分配的佣金，须在事务中调用
+// 锁定订单行保证并发结算时各方佣金总额不超过commissionMaxPercent配置的比例
+func reserveOrderCommission(tx *gorm.DB, orderID int, income, base float64) (float64, error) {
+	var order structs.Order
+	if err := tx.Table(orderTableName).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id, commission_total").
+		Where("id = ?", orderID).
+		First(&order).Error; err != nil {
+		return 0, errors.New("订单不存在")
+	}
+
+	income = CapCommission(income, base, order.CommissionTotal, config.GetFloat("commissionMaxPercent", 30))
+	if income <= 0 {
+		return 0, nil
+	}
+	if err := tx.Table(orderTableName).
+		Where("id = ?", orderID).
+		Update("commission_total", gorm.Expr("commission_total + ?", income)).Error; err != nil {
+		return 0, err
+	}
+	return income, nil
+}
+
+// orderIncomeSettleable 订单是否为已付款或已发货状态，退款后的订单不再分配佣金和收入，须在事务中调用
+func orderIncomeSettleable(tx *gorm.DB, orderID int) (bool, error) {
+	var status int
+	if err := tx.Table(orderTableName).Select("order_status_id").Where("id = ?", orderID).Scan(&status).Error; err != nil {
+		return false, err
+	}
+	return status == structs.OrderStatusPaid || status == structs.OrderStatusShipped, nil
+}
+
+// CreateReferralCommissions 写入订单的分销佣金并计入受益会员的冻结金额，同一订单只分配一次
+// 每条佣金受订单佣金总额上限约束，达到上限后剩余佣金不再分配
+func CreateReferralCommissions(order *structs.Order, items []structs.MemberCommission) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table(memberCommissionTableName).Where("order_id = ?", order.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		// 订单在分配前已退款(如拼团失败)时不再分配
+		if ok, err := orderIncomeSettleable(tx, order.ID); err != nil || !ok {
+			return err
+		}
+
+		now := int(time.Now().Unix())
+		base := CommissionableAmount(order.Total, order.Freight)
+		for i := range items {
+			item := &items[i]
+			amount, err := reserveOrderCommission(tx, order.ID, item.Amount, base)
+			if err != nil {
+				return err
+			}
+			if amount <= 0 {
+				log.Printf("订单%d佣金已达上限，剩余分销佣金不再分配", order.ID)
+				return nil
+			}
+
+			item.Weid = order.Weid
+			item.BuyerID = order.UID
+			item.OrderID = order.ID
+			item.Amount = amount
+			item.Status = structs.CommissionFrozen
+			item.CreateTime = now
+			if err := tx.Table(memberCommissionTableName).Create(item).Error; err != nil {
+				log.Printf("写入分销佣金失败: %v", err)
+				return err
+			}
+			if err := postWalletEntry(tx, walletEntry{
+				Weid:    order.Weid,
+				Uid:     item.Uid,
+				BizType: "commission",
+				BizID:   item.ID,
+				Debit:   structs.WalletAccountOrder,
+				Credit:  structs.WalletAccountFreeze,
+				Amount:  amount,
+				Remark:  fmt.Sprintf("订单%s%d级分销佣金", order.OrderNumAlias, item.Level),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// settleOrderCommissions 订单完成后把冻结的分销佣金转入余额，须在事务中调用
+func settleOrderCommissions(tx *gorm.DB, orderID int) error {
+	var items []structs.MemberCommission
+	if err := tx.Table(memberCommissionTableName).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status = ?", orderID, structs.CommissionFrozen).
+		Find(&items).Error; err != nil {
+		return err
+	}
+
+	now := int(time.Now().Unix())
+	for _, item := range items {
+		if err := tx.Table(memberCommissionTableName).
+			Where("id = ?", item.ID).
+			Updates(map[string]interface{}{"status": structs.CommissionSettled, "settle_time": now}).Error; err != nil {
+			return err
+		}
+		if err := postWalletEntry(tx, walletEntry{
+			Weid:    item.Weid,
+			Uid:     item.Uid,
+			BizType: "commission_settle",
+			BizID:   item.ID,
+			Debit:   structs.WalletAccountFreeze,
+			Credit:  structs.WalletAccountBalance,
+			Amount:  item.Amount,
+			Remark:  "分销佣金解冻",
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reverseOrderCommissions 订单退款时冲回分销佣金，冻结中的从冻结金额扣回，已结算的从余额扣回，须在事务中调用
+func reverseOrderCommissions(tx *gorm.DB, orderID int) error {
+	var items []structs.MemberCommission
+	if err := tx.Table(memberCommissionTableName).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status IN ?", orderID, []int{structs.CommissionFrozen, structs.CommissionSettled}).
+		Find(&items).Error; err != nil {
+		return err
+	}
+
+	total := 0.0
+	for _, item := range items {
+		debit := structs.WalletAccountFreeze
+		if item.Status == structs.CommissionSettled {
+			debit = structs.WalletAccountBalance
+		}
+		if err := tx.Table(memberCommissionTableName).
+			Where("id = ?", item.ID).
+			Update("status", structs.CommissionReversed).Error; err != nil {
+			return err
+		}
+		if err := postWalletEntry(tx, walletEntry{
+			Weid:    item.Weid,
+			Uid:     item.Uid,
+			BizType: "commission_reverse",
+			BizID:   item.ID,
+			Debit:   debit,
+			Credit:  structs.WalletAccountOrder,
+			Amount:  item.Amount,
+			Remark:  "订单退款冲回分销佣金",
+		}); err != nil {
+			return err
+		}
+		total += item.Amount
+	}
+	if total <= 0 {
+		return nil
+	}
+	return tx.Table(orderTableName).
+		Where("id = ?", orderID).
+		Update("commission_total", gorm.Expr("GREATEST(commission_total - ?, 0)", roundMoney(total))).Error
+}
@@ -0,0 +1,660 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"myapi/app/config"
+	"myapi/app/storage"
+	"myapi/app/structs"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 扣减库存时的错误
+var (
+	errStockNotEnough = errors.New("库存不足")    // 条件扣减没有命中
+	errSkuNotFound    = errors.New("商品规格不存在") // 没有匹配的SKU
+)
+
+// ReserveStock 立即购买时按购买记录预留商品库存，商品和SKU库存用条件更新扣减，任一商品不足时整体回滚
+// 套装商品预留各组件的库存；同一会员对同一商品规格已有数量相同且未过期的预留时沿用，不重复扣减，数量不同时先释放再预留；不扣减库存(subtract不为1)的商品跳过
+// 购买记录已有预留时直接返回，重复调用不会重复扣减；返回本次扣减写入的库存流水，用于库存提醒
+func ReserveStock(weid, uid, recordID, expireTime int, items []structs.StockItem) ([]structs.GoodsStockLedger, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var entries []structs.GoodsStockLedger
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table(goodsStockReservationTableName).Where("record_id = ?", recordID).Count(&count).Error; err != nil {
+			log.Printf("查询库存预留失败: %v", err)
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		items, err := expandCombinationItems(tx, items)
+		if err != nil {
+			return err
+		}
+		items, err = skipHeldStock(tx, uid, items)
+		if err != nil {
+			return err
+		}
+		if err := releasePendingStock(tx, uid, items, "重新购买释放"); err != nil {
+			return err
+		}
+		entries, err = reserveStock(tx, structs.GoodsStockReservation{
+			Weid:       weid,
+			Uid:        uid,
+			RecordID:   recordID,
+			ExpireTime: expireTime,
+		}, items, "立即购买预留")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// reserveStock 按base的会员、购买记录和订单预留商品库存，返回写入的库存流水，须在事务中调用
+func reserveStock(tx *gorm.DB, base structs.GoodsStockReservation, items []structs.StockItem, remark string) ([]structs.GoodsStockLedger, error) {
+	now := int(time.Now().Unix())
+	var entries []structs.GoodsStockLedger
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return nil, errors.New("购买数量必须大于0")
+		}
+		var goods []structs.Product
+		if err := tx.Table(goodsTableName).
+			Select("id, name, subtract").
+			Where("id = ? AND weid = ? AND delete_time = 0", item.GoodsID, base.Weid).
+			Limit(1).
+			Find(&goods).Error; err != nil {
+			log.Printf("查询商品失败: %v", err)
+			return nil, err
+		}
+		if len(goods) == 0 {
+			return nil, ErrProductNotFound
+		}
+		if goods[0].Subtract != 1 {
+			continue
+		}
+
+		reservation := base
+		reservation.GoodsID = item.GoodsID
+		reservation.Sku = item.Sku
+		reservation.Quantity = item.Quantity
+		reservation.Status = structs.StockReserved
+		reservation.CreateTime = now
+		reservation.UpdateTime = now
+		if err := moveStock(tx, &reservation, -item.Quantity); err != nil {
+			if errors.Is(err, errSkuNotFound) {
+				return nil, fmt.Errorf("商品%s的规格%s不存在", goods[0].Name, item.Sku)
+			}
+			if errors.Is(err, errStockNotEnough) {
+				return nil, fmt.Errorf("商品%s库存不足", goods[0].Name)
+			}
+			return nil, err
+		}
+		if err := tx.Table(goodsStockReservationTableName).Create(&reservation).Error; err != nil {
+			log.Printf("保存库存预留失败: %v", err)
+			return nil, err
+		}
+		entry, err := writeStockLedger(tx, &reservation, structs.StockMoveReserve, -item.Quantity, remark)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// CommitOrderStock 订单支付后确认库存，库存已在立即购买时扣减
+// 会员还未支付的预留按订单商品关联到订单后确认；预留已超时释放的商品按当前库存重新扣减，库存不足时返回错误
+// 订单已有库存预留时只确认，重复调用不会重复关联或扣减；返回重新扣减写入的库存流水，用于库存提醒
+func CommitOrderStock(weid, uid, orderID int, items []structs.StockItem) ([]structs.GoodsStockLedger, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var entries []structs.GoodsStockLedger
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table(goodsStockReservationTableName).Where("order_id = ?", orderID).Count(&count).Error; err != nil {
+			log.Printf("查询订单库存预留失败: %v", err)
+			return err
+		}
+		if count == 0 {
+			items, err := expandCombinationItems(tx, items)
+			if err != nil {
+				return err
+			}
+			missing, err := attachPendingStock(tx, uid, orderID, items)
+			if err != nil {
+				return err
+			}
+			entries, err = reserveStock(tx, structs.GoodsStockReservation{
+				Weid:    weid,
+				Uid:     uid,
+				OrderID: orderID,
+			}, missing, "预留超时支付扣减")
+			if err != nil {
+				return err
+			}
+		}
+
+		reservations, err := lockReservations(tx, orderID, []int{structs.StockReserved})
+		if err != nil {
+			return err
+		}
+		for i := range reservations {
+			if err := setReservationStatus(tx, &reservations[i], structs.StockCommitted); err != nil {
+				return err
+			}
+			if _, err := writeStockLedger(tx, &reservations[i], structs.StockMoveCommit, 0, "支付确认"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// attachPendingStock 把会员还未支付的预留关联到订单，每个订单商品取最近一次数量相同的预留，返回没有预留的商品，须在事务中调用
+func attachPendingStock(tx *gorm.DB, uid, orderID int, items []structs.StockItem) ([]structs.StockItem, error) {
+	var missing []structs.StockItem
+	for _, item := range items {
+		var reservations []structs.GoodsStockReservation
+		if err := pendingReservationQuery(tx, uid, item).
+			Where("quantity = ?", item.Quantity).
+			Order("id DESC").
+			Limit(1).
+			Find(&reservations).Error; err != nil {
+			log.Printf("查询未支付的库存预留失败: %v", err)
+			return nil, err
+		}
+		if len(reservations) == 0 {
+			missing = append(missing, item)
+			continue
+		}
+		if err := tx.Table(goodsStockReservationTableName).
+			Where("id = ?", reservations[0].ID).
+			Updates(map[string]interface{}{"order_id": orderID, "update_time": int(time.Now().Unix())}).Error; err != nil {
+			log.Printf("关联库存预留到订单失败: %v", err)
+			return nil, err
+		}
+	}
+	return missing, nil
+}
+
+// skipHeldStock 去掉会员已持有数量相同且未过期预留的商品，刷新立即购买页面时不重复扣减，须在事务中调用
+func skipHeldStock(tx *gorm.DB, uid int, items []structs.StockItem) ([]structs.StockItem, error) {
+	var rest []structs.StockItem
+	for _, item := range items {
+		var count int64
+		if err := pendingReservationQuery(tx, uid, item).
+			Where("quantity = ? AND expire_time > ?", item.Quantity, time.Now().Unix()).
+			Count(&count).Error; err != nil {
+			log.Printf("查询未支付的库存预留失败: %v", err)
+			return nil, err
+		}
+		if count == 0 {
+			rest = append(rest, item)
+		}
+	}
+	return rest, nil
+}
+
+// pendingReservationQuery 锁定会员对商品规格还未支付的预留
+func pendingReservationQuery(tx *gorm.DB, uid int, item structs.StockItem) *gorm.DB {
+	return tx.Table(goodsStockReservationTableName).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("uid = ? AND order_id = 0 AND status = ? AND goods_id = ? AND sku = ?",
+			uid, structs.StockReserved, item.GoodsID, item.Sku)
+}
+
+// releasePendingStock 释放会员对这些商品规格还未支付的预留，用于重新购买和取消订单，须在事务中调用
+func releasePendingStock(tx *gorm.DB, uid int, items []structs.StockItem, remark string) error {
+	for _, item := range items {
+		var reservations []structs.GoodsStockReservation
+		if err := pendingReservationQuery(tx, uid, item).Find(&reservations).Error; err != nil {
+			log.Printf("查询未支付的库存预留失败: %v", err)
+			return err
+		}
+		for i := range reservations {
+			if err := releaseReservation(tx, &reservations[i], remark); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReleaseOrderStock 订单退款时回补库存，已预留和已确认的都会释放，重复调用不会重复回补
+func ReleaseOrderStock(orderID int, remark string) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		return releaseOrderStock(tx, orderID, remark)
+	})
+}
+
+// releaseOrderStock 回补订单库存，须在事务中调用
+func releaseOrderStock(tx *gorm.DB, orderID int, remark string) error {
+	reservations, err := lockReservations(tx, orderID, []int{structs.StockReserved, structs.StockCommitted})
+	if err != nil {
+		return err
+	}
+	for i := range reservations {
+		if err := releaseReservation(tx, &reservations[i], remark); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetExpiredStockReservations 获取到期还未支付的库存预留，按ID升序
+func GetExpiredStockReservations(before int64, limit int) ([]structs.GoodsStockReservation, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var list []structs.GoodsStockReservation
+	if err := gormDB.Table(goodsStockReservationTableName).
+		Where("status = ? AND order_id = 0 AND expire_time < ?", structs.StockReserved, before).
+		Order("id ASC").
+		Limit(limit).
+		Find(&list).Error; err != nil {
+		log.Printf("查询到期的库存预留失败: %v", err)
+		return nil, err
+	}
+	return list, nil
+}
+
+// ReleaseStockReservation 释放一条还未支付的库存预留，已关联订单或已释放时不处理
+func ReleaseStockReservation(id int, remark string) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		var reservations []structs.GoodsStockReservation
+		if err := tx.Table(goodsStockReservationTableName).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND order_id = 0 AND status = ?", id, structs.StockReserved).
+			Find(&reservations).Error; err != nil {
+			log.Printf("查询库存预留失败: %v", err)
+			return err
+		}
+		for i := range reservations {
+			if err := releaseReservation(tx, &reservations[i], remark); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// releaseReservation 回补一条预留的库存并标记为已释放，须在事务中调用
+func releaseReservation(tx *gorm.DB, reservation *structs.GoodsStockReservation, remark string) error {
+	if err := moveStock(tx, reservation, reservation.Quantity); err != nil {
+		return err
+	}
+	if err := setReservationStatus(tx, reservation, structs.StockReleased); err != nil {
+		return err
+	}
+	_, err := writeStockLedger(tx, reservation, structs.StockMoveRelease, reservation.Quantity, remark)
+	return err
+}
+
+// AdjustStock 后台调整商品库存，Sku不为空时同时调整该规格库存；change为正数补货、负数扣减，扣减时库存不足返回错误
+// 调整写入adjust类型的库存流水，返回写入的流水用于库存提醒
+func AdjustStock(weid, goodsID int, sku string, change int, remark string) (*structs.GoodsStockLedger, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+	if change == 0 {
+		return nil, errors.New("调整数量不能为0")
+	}
+
+	var entry structs.GoodsStockLedger
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table(goodsTableName).
+			Where("id = ? AND weid = ? AND delete_time = 0", goodsID, weid).
+			Count(&count).Error; err != nil {
+			log.Printf("查询商品失败: %v", err)
+			return err
+		}
+		if count == 0 {
+			return ErrProductNotFound
+		}
+		if sku != "" {
+			if err := whereSkuValue(tx.Table(goodsSkuValueTableName), goodsID, sku).Count(&count).Error; err != nil {
+				log.Printf("查询商品SKU失败: %v", err)
+				return err
+			}
+			if count == 0 {
+				return fmt.Errorf("商品规格%s不存在", sku)
+			}
+		}
+
+		reservation := structs.GoodsStockReservation{Weid: weid, GoodsID: goodsID, Sku: sku}
+		if err := moveStock(tx, &reservation, change); err != nil {
+			if errors.Is(err, errStockNotEnough) {
+				return errors.New("库存不足，不能扣减")
+			}
+			return err
+		}
+		var err error
+		entry, err = writeStockLedger(tx, &reservation, structs.StockMoveAdjust, change, remark)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// OrderStockItems 把订单商品转换为库存预留项，同一商品同一规格的数量合并，顺序按首次出现
+func OrderStockItems(goods []structs.OrderGoods) []structs.StockItem {
+	items := make([]structs.StockItem, 0, len(goods))
+	for _, g := range goods {
+		items = append(items, structs.StockItem{GoodsID: g.GoodsID, Sku: g.Sku, Quantity: g.Quantity})
+	}
+	return mergeStockItems(items)
+}
+
+// GetStockGoodsIDs 获取库存项涉及的商品ID，套装商品同时返回组件商品ID，用于库存变动后清除缓存
+func GetStockGoodsIDs(items []structs.StockItem) ([]int, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	expanded, err := expandCombinationItems(gormDB, items)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(items)+len(expanded))
+	seen := make(map[int]bool, cap(ids))
+	for _, list := range [][]structs.StockItem{items, expanded} {
+		for _, item := range list {
+			if !seen[item.GoodsID] {
+				seen[item.GoodsID] = true
+				ids = append(ids, item.GoodsID)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// mergeStockItems 合并同一商品同一规格的库存项，顺序按首次出现
+func mergeStockItems(items []structs.StockItem) []structs.StockItem {
+	merged := make([]structs.StockItem, 0, len(items))
+	index := make(map[string]int, len(items))
+	for _, item := range items {
+		key := strconv.Itoa(item.GoodsID) + "|" + item.Sku
+		if i, ok := index[key]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, item)
+	}
+	return merged
+}
+
+// GetStockLedger 分页查询库存流水，按时间倒序
+func GetStockLedger(f structs.StockLedgerFilter, page, limit int) ([]structs.GoodsStockLedger, int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, 0, errors.New("数据库连接失败")
+	}
+
+	query := gormDB.Table(goodsStockLedgerTableName).Where("weid = ?", f.Weid)
+	if f.GoodsID > 0 {
+		query = query.Where("goods_id = ?", f.GoodsID)
+	}
+	if f.OrderID > 0 {
+		query = query.Where("order_id = ?", f.OrderID)
+	}
+	if f.Type != "" {
+		query = query.Where("type = ?", f.Type)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		log.Printf("查询库存流水总数失败: %v", err)
+		return nil, 0, err
+	}
+	var list []structs.GoodsStockLedger
+	if err := query.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&list).Error; err != nil {
+		log.Printf("查询库存流水失败: %v", err)
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// moveStock 按change增减预留对应的商品库存和SKU库存，扣减时带库存充足条件
+// SKU按商品和规格重新匹配，后台修改商品会重建SKU记录，不能用预留时的SKU ID；匹配到后更新预留的SkuvID供流水记录
+// 回补时规格已被删除的只回补商品库存
+func moveStock(tx *gorm.DB, reservation *structs.GoodsStockReservation, change int) error {
+	if reservation.Sku != "" {
+		var ids []int
+		if err := whereSkuValue(tx.Table(goodsSkuValueTableName), reservation.GoodsID, reservation.Sku).
+			Order("id ASC").
+			Limit(1).
+			Pluck("id", &ids).Error; err != nil {
+			log.Printf("查询商品SKU失败: %v", err)
+			return err
+		}
+		if len(ids) == 0 && change < 0 {
+			return errSkuNotFound
+		}
+		if len(ids) == 0 {
+			log.Printf("商品%d的规格%s已不存在，只回补商品库存", reservation.GoodsID, reservation.Sku)
+			reservation.SkuvID = 0
+		} else {
+			reservation.SkuvID = ids[0]
+			query := tx.Table(goodsSkuValueTableName).Where("id = ?", ids[0])
+			if change < 0 {
+				query = query.Where("quantity >= ?", -change)
+			}
+			result := query.Update("quantity", gorm.Expr("quantity + ?", change))
+			if result.Error != nil {
+				log.Printf("更新SKU库存失败: %v", result.Error)
+				return result.Error
+			}
+			if result.RowsAffected == 0 && change < 0 {
+				return errStockNotEnough
+			}
+		}
+	}
+
+	query := tx.Table(goodsTableName).Where("id = ?", reservation.GoodsID)
+	if change < 0 {
+		query = query.Where("quantity >= ?", -change)
+	}
+	result := query.Update("quantity", gorm.Expr("quantity + ?", change))
+	if result.Error != nil {
+		log.Printf("更新商品库存失败: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 && change < 0 {
+		return errStockNotEnough
+	}
+	return SyncStockStatus(tx, []int{reservation.GoodsID})
+}
+
+// lockReservations 锁定订单指定状态的库存预留
+func lockReservations(tx *gorm.DB, orderID int, statuses []int) ([]structs.GoodsStockReservation, error) {
+	var reservations []structs.GoodsStockReservation
+	if err := tx.Table(goodsStockReservationTableName).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status IN ?", orderID, statuses).
+		Order("id ASC").
+		Find(&reservations).Error; err != nil {
+		log.Printf("查询订单库存预留失败: %v", err)
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// setReservationStatus 变更预留状态，同时保存moveStock重新匹配的SKU ID
+func setReservationStatus(tx *gorm.DB, reservation *structs.GoodsStockReservation, status int) error {
+	now := int(time.Now().Unix())
+	if err := tx.Table(goodsStockReservationTableName).
+		Where("id = ?", reservation.ID).
+		Updates(map[string]interface{}{"status": status, "skuv_id": reservation.SkuvID, "update_time": now}).Error; err != nil {
+		log.Printf("更新库存预留状态失败: %v", err)
+		return err
+	}
+	reservation.Status = status
+	reservation.UpdateTime = now
+	return nil
+}
+
+// writeStockLedger 写入库存流水，记录变动后的商品和SKU库存，返回写入的流水
+func writeStockLedger(tx *gorm.DB, reservation *structs.GoodsStockReservation, moveType string, change int, remark string) (structs.GoodsStockLedger, error) {
+	entry := structs.GoodsStockLedger{
+		Weid:           reservation.Weid,
+		GoodsID:        reservation.GoodsID,
+		SkuvID:         reservation.SkuvID,
+		Sku:            reservation.Sku,
+		RecordID:       reservation.RecordID,
+		OrderID:        reservation.OrderID,
+		Type:           moveType,
+		ChangeQuantity: change,
+		Remark:         remark,
+		CreateTime:     int(time.Now().Unix()),
+	}
+	if err := tx.Table(goodsTableName).Select("quantity").Where("id = ?", reservation.GoodsID).Scan(&entry.QuantityAfter).Error; err != nil {
+		log.Printf("查询商品库存失败: %v", err)
+		return entry, err
+	}
+	if reservation.SkuvID > 0 {
+		if err := tx.Table(goodsSkuValueTableName).Select("quantity").Where("id = ?", reservation.SkuvID).Scan(&entry.SkuQuantityAfter).Error; err != nil {
+			log.Printf("查询SKU库存失败: %v", err)
+			return entry, err
+		}
+	}
+	if err := tx.Table(goodsStockLedgerTableName).Create(&entry).Error; err != nil {
+		log.Printf("保存库存流水失败: %v", err)
+		return entry, err
+	}
+	return entry, nil
+}
+
+// SyncStockStatus 按库存自动切换扣库存商品的库存状态：库存为0时改为缺货，补货后从缺货改回有货
+// 不扣库存的商品不处理，手动设置的其他状态(如预订)在有货时保持不变；可在事务中调用，tx为nil时使用默认连接
+func SyncStockStatus(tx *gorm.DB, goodsIDs []int) error {
+	if len(goodsIDs) == 0 {
+		return nil
+	}
+	if tx == nil {
+		tx = storage.GetGormDB()
+		if tx == nil {
+			log.Println("GORM连接为空")
+			return errors.New("数据库连接失败")
+		}
+	}
+
+	inStock := config.GetInt("stockStatusInStock", 7)
+	outOfStock := config.GetInt("stockStatusOutOfStock", 5)
+	if err := tx.Table(goodsTableName).
+		Where("id IN ? AND subtract = 1 AND quantity <= 0 AND stock_status_id <> ?", goodsIDs, outOfStock).
+		Update("stock_status_id", outOfStock).Error; err != nil {
+		log.Printf("更新商品缺货状态失败: %v", err)
+		return err
+	}
+	if err := tx.Table(goodsTableName).
+		Where("id IN ? AND subtract = 1 AND quantity > 0 AND stock_status_id = ?", goodsIDs, outOfStock).
+		Update("stock_status_id", inStock).Error; err != nil {
+		log.Printf("更新商品有货状态失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetStockAlertProducts 获取商品的库存提醒字段
+func GetStockAlertProducts(ids []int) ([]structs.Product, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var products []structs.Product
+	if err := gormDB.Table(goodsTableName).
+		Select("id, weid, sid, name, quantity, low_stock, stock_status_id").
+		Where("id IN ?", ids).
+		Find(&products).Error; err != nil {
+		log.Printf("查询商品库存失败: %v", err)
+		return nil, err
+	}
+	return products, nil
+}
+
+// GetLowStockProducts 分页查询库存达到提醒阈值的扣库存商品，商品或任一SKU低于阈值都会列出
+// 商品阈值为0时使用defaultThreshold，SKU阈值为0时使用商品阈值
+func GetLowStockProducts(weid, defaultThreshold, page, limit int) ([]structs.Product, int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, 0, errors.New("数据库连接失败")
+	}
+
+	threshold := "IF(g.low_stock > 0, g.low_stock, ?)"
+	query := gormDB.Table(goodsTableName+" AS g").
+		Where("g.weid = ? AND g.delete_time = 0 AND g.subtract = 1", weid).
+		Where("(g.quantity <= "+threshold+" OR EXISTS (SELECT 1 FROM "+goodsSkuValueTableName+
+			" AS v WHERE v.goods_id = g.id AND v.quantity <= IF(v.low_stock > 0, v.low_stock, "+threshold+")))",
+			defaultThreshold, defaultThreshold)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		log.Printf("查询低库存商品总数失败: %v", err)
+		return nil, 0, err
+	}
+	var products []structs.Product
+	if err := query.Select("g.*").
+		Order("g.quantity ASC, g.id ASC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&products).Error; err != nil {
+		log.Printf("查询低库存商品失败: %v", err)
+		return nil, 0, err
+	}
+	return products, total, nil
+}
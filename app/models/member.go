@@ -130,10 +130,16 @@ func CreateMember(member *structs.Member) error {
 		member.Status = 1 // 默认启用
 	}
 
-	result := gormDB.Table(memberTableName).Create(member)
-	if result.Error != nil {
-		log.Printf("创建会员失败: %v", result.Error)
-		return result.Error
+	// 创建会员的同时写入推荐关系
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(memberTableName).Create(member).Error; err != nil {
+			return err
+		}
+		return linkMemberReferral(tx, member.Weid, member.ID, member.Pid)
+	})
+	if err != nil {
+		log.Printf("创建会员失败: %v", err)
+		return err
 	}
 
 	return nil
@@ -193,154 +199,27 @@ func GetPIDName(uid int) (string, error) {
 	return "平台", nil
 }
 
-// GetOneLevel 获取一级会员
+// GetOneLevel 获取一级会员，isData为false时返回人数
 func GetOneLevel(uid int, isData bool) (interface{}, error) {
-	gormDB := storage.GetGormDB()
-	if gormDB == nil {
-		return nil, errors.New("数据库连接失败")
-	}
-
-	if isData {
-		var members []structs.Member
-		result := gormDB.Table(memberTableName).
-			Select("id, nickname, regdate, userpic").
-			Where("pid = ?", uid).
-			Find(&members)
-		if result.Error != nil {
-			log.Printf("查询一级会员数据失败: %v", result.Error)
-			return nil, result.Error
-		}
-		return members, nil
-	} else {
-		var count int64
-		result := gormDB.Table(memberTableName).
-			Where("pid = ?", uid).
-			Count(&count)
-		if result.Error != nil {
-			log.Printf("查询一级会员数量失败: %v", result.Error)
-			return 0, result.Error
-		}
-		return count, nil
-	}
+	return getLevelMembers(uid, 1, isData)
 }
 
-// GetTwoLevel 获取二级会员
+// GetTwoLevel 获取二级会员，isData为false时返回人数
 func GetTwoLevel(uid int, isData bool) (interface{}, error) {
-	gormDB := storage.GetGormDB()
-	if gormDB == nil {
-		return nil, errors.New("数据库连接失败")
-	}
-
-	// 查询一级会员ID
-	var firstLevelIDs []int
-	result := gormDB.Table(memberTableName).
-		Select("id").
-		Where("pid = ?", uid).
-		Pluck("id", &firstLevelIDs)
-	if result.Error != nil {
-		log.Printf("查询一级会员ID失败: %v", result.Error)
-		return 0, result.Error
-	}
-
-	if len(firstLevelIDs) == 0 {
-		if isData {
-			return []structs.Member{}, nil
-		} else {
-			return 0, nil
-		}
-	}
-
-	if isData {
-		var members []structs.Member
-		result := gormDB.Table(memberTableName).
-			Select("id, nickname, regdate, userpic").
-			Where("pid IN ?", firstLevelIDs).
-			Find(&members)
-		if result.Error != nil {
-			log.Printf("查询二级会员数据失败: %v", result.Error)
-			return nil, result.Error
-		}
-		return members, nil
-	} else {
-		var count int64
-		result := gormDB.Table(memberTableName).
-			Where("pid IN ?", firstLevelIDs).
-			Count(&count)
-		if result.Error != nil {
-			log.Printf("查询二级会员数量失败: %v", result.Error)
-			return 0, result.Error
-		}
-		return count, nil
-	}
+	return getLevelMembers(uid, 2, isData)
 }
 
-// GetThreeLevel 获取三级会员
+// GetThreeLevel 获取三级会员，isData为false时返回人数
 func GetThreeLevel(uid int, isData bool) (interface{}, error) {
-	gormDB := storage.GetGormDB()
-	if gormDB == nil {
-		return nil, errors.New("数据库连接失败")
-	}
-
-	// 查询一级会员ID
-	var firstLevelIDs []int
-	result := gormDB.Table(memberTableName).
-		Select("id").
-		Where("pid = ?", uid).
-		Pluck("id", &firstLevelIDs)
-	if result.Error != nil {
-		log.Printf("查询一级会员ID失败: %v", result.Error)
-		return 0, result.Error
-	}
-
-	if len(firstLevelIDs) == 0 {
-		if isData {
-			return []structs.Member{}, nil
-		} else {
-			return 0, nil
-		}
-	}
-
-	// 查询二级会员ID
-	var secondLevelIDs []int
-	result = gormDB.Table(memberTableName).
-		Select("id").
-		Where("pid IN ?", firstLevelIDs).
-		Pluck("id", &secondLevelIDs)
-	if result.Error != nil {
-		log.Printf("查询二级会员ID失败: %v", result.Error)
-		return 0, result.Error
-	}
-
-	if len(secondLevelIDs) == 0 {
-		if isData {
-			return []structs.Member{}, nil
-		} else {
-			return 0, nil
-		}
-	}
+	return getLevelMembers(uid, 3, isData)
+}
 
+// getLevelMembers 从推荐关系闭包表查询第depth级会员，新代码请使用GetReferralTeam和GetReferralDescendantCount
+func getLevelMembers(uid, depth int, isData bool) (interface{}, error) {
 	if isData {
-		var members []structs.Member
-		result := gormDB.Table(memberTableName).
-			Select("id, nickname, regdate, userpic").
-			Where("pid IN ?", secondLevelIDs).
-			Find(&members)
-		if result.Error != nil {
-			log.Printf("查询三级会员数据失败: %v", result.Error)
-			return nil, result.Error
-		}
-		return members, nil
-	} else {
-		var count int64
-		result := gormDB.Table(memberTableName).
-			Where("pid IN ?", secondLevelIDs).
-			Count(&count)
-		if result.Error != nil {
-			log.Printf("查询三级会员数量失败: %v", result.Error)
-			return 0, result.Error
-		}
-		return count, nil
+		return getReferralMembers(uid, depth)
 	}
+	return GetReferralDescendantCount(uid, depth)
 }
 
 // IsTelephoneRegistered 检查手机号是否已注册
@@ -408,3 +287,31 @@ func GetUserPoints(uid int) (int, error) {
 
 	return points, nil
 }
+
+// GetMemberAncestors 沿推荐人(pid)向上获取最多depth级上级会员ID，依次为一级、二级、三级……
+// 遇到无推荐人或推荐关系成环时停止
+func GetMemberAncestors(uid, depth int) ([]int, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	seen := map[int]bool{uid: true}
+	var ancestors []int
+	current := uid
+	for len(ancestors) < depth {
+		var pid int
+		if err := gormDB.Table(memberTableName).Select("pid").Where("id = ?", current).Scan(&pid).Error; err != nil {
+			log.Printf("查询会员推荐人失败: %v", err)
+			return nil, err
+		}
+		if pid <= 0 || seen[pid] {
+			break
+		}
+		seen[pid] = true
+		ancestors = append(ancestors, pid)
+		current = pid
+	}
+	return ancestors, nil
+}
@@ -0,0 +1,335 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"myapi/app/storage"
+	"myapi/app/structs"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrReferralCycle 推荐关系成环，如把自己的下级设为推荐人
+var ErrReferralCycle = errors.New("不能绑定自己或自己团队中的会员为推荐人")
+
+// linkMemberReferral 把会员uid(连同其团队)挂到推荐人pid下，写入闭包表，须在事务中调用
+// pid为0时只写入会员自身的记录
+func linkMemberReferral(tx *gorm.DB, weid, uid, pid int) error {
+	if err := tx.Exec("INSERT IGNORE INTO "+memberReferralTableName+" (weid, ancestor_id, descendant_id, depth) VALUES (?, ?, ?, 0)",
+		weid, uid, uid).Error; err != nil {
+		return err
+	}
+	if pid <= 0 {
+		return nil
+	}
+	if pid == uid {
+		return ErrReferralCycle
+	}
+	if err := tx.Exec("INSERT IGNORE INTO "+memberReferralTableName+" (weid, ancestor_id, descendant_id, depth) VALUES (?, ?, ?, 0)",
+		weid, pid, pid).Error; err != nil {
+		return err
+	}
+
+	var count int64
+	if err := tx.Table(memberReferralTableName).
+		Where("ancestor_id = ? AND descendant_id = ?", uid, pid).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrReferralCycle
+	}
+
+	// 推荐人及其所有上级 x 会员及其所有下级
+	return tx.Exec("INSERT IGNORE INTO "+memberReferralTableName+" (weid, ancestor_id, descendant_id, depth) "+
+		"SELECT ?, a.ancestor_id, d.descendant_id, a.depth + d.depth + 1 FROM "+memberReferralTableName+" AS a "+
+		"JOIN "+memberReferralTableName+" AS d ON d.ancestor_id = ? WHERE a.descendant_id = ?",
+		weid, uid, pid).Error
+}
+
+// referralDepthScope depth大于0时只查该层级，否则查全部下级
+func referralDepthScope(depth int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if depth > 0 {
+			return db.Where("r.depth = ?", depth)
+		}
+		return db.Where("r.depth >= 1")
+	}
+}
+
+// GetReferralDescendantCount 统计会员第depth级下级人数，depth为0时统计整个团队
+func GetReferralDescendantCount(uid, depth int) (int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return 0, errors.New("数据库连接失败")
+	}
+
+	var count int64
+	if err := gormDB.Table(memberReferralTableName+" AS r").
+		Where("r.ancestor_id = ?", uid).
+		Scopes(referralDepthScope(depth)).
+		Count(&count).Error; err != nil {
+		log.Printf("统计团队人数失败: %v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetReferralDepthCounts 按层级统计会员团队人数，maxDepth大于0时只统计前maxDepth级
+func GetReferralDepthCounts(uid, maxDepth int) ([]structs.ReferralDepthCount, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	query := gormDB.Table(memberReferralTableName+" AS r").
+		Select("r.depth, COUNT(*) AS count").
+		Where("r.ancestor_id = ? AND r.depth >= 1", uid)
+	if maxDepth > 0 {
+		query = query.Where("r.depth <= ?", maxDepth)
+	}
+	var list []structs.ReferralDepthCount
+	if err := query.Group("r.depth").Order("r.depth ASC").Find(&list).Error; err != nil {
+		log.Printf("按层级统计团队人数失败: %v", err)
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetReferralTeam 分页获取会员团队成员，depth大于0时只查该层级
+func GetReferralTeam(uid, depth, page, limit int) ([]structs.ReferralMember, int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return nil, 0, errors.New("数据库连接失败")
+	}
+
+	var total int64
+	var list []structs.ReferralMember
+	query := gormDB.Table(memberReferralTableName+" AS r").
+		Joins("JOIN "+memberTableName+" AS m ON m.id = r.descendant_id").
+		Where("r.ancestor_id = ?", uid).
+		Scopes(referralDepthScope(depth))
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("查询团队成员数量失败: %v", err)
+		return nil, 0, err
+	}
+	if err := query.Select("m.id, m.nickname, m.userpic, m.regdate, r.depth").
+		Order("r.depth ASC, m.id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&list).Error; err != nil {
+		log.Printf("查询团队成员失败: %v", err)
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// GetReferralUpline 获取会员的上级链，依次为一级、二级……，maxDepth大于0时最多取maxDepth级
+func GetReferralUpline(uid, maxDepth int) ([]structs.ReferralMember, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	query := gormDB.Table(memberReferralTableName+" AS r").
+		Select("m.id, m.nickname, m.userpic, m.regdate, r.depth").
+		Joins("JOIN "+memberTableName+" AS m ON m.id = r.ancestor_id").
+		Where("r.descendant_id = ? AND r.depth >= 1", uid)
+	if maxDepth > 0 {
+		query = query.Where("r.depth <= ?", maxDepth)
+	}
+	var list []structs.ReferralMember
+	if err := query.Order("r.depth ASC").Find(&list).Error; err != nil {
+		log.Printf("查询上级会员失败: %v", err)
+		return nil, err
+	}
+	return list, nil
+}
+
+// getReferralMembers 获取第depth级下级的会员信息，供旧的分级查询接口使用
+func getReferralMembers(uid, depth int) ([]structs.Member, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var members []structs.Member
+	if err := gormDB.Table(memberTableName).
+		Select("id, nickname, regdate, userpic").
+		Where("id IN (?)", gormDB.Table(memberReferralTableName).
+			Select("descendant_id").
+			Where("ancestor_id = ? AND depth = ?", uid, depth)).
+		Find(&members).Error; err != nil {
+		log.Printf("查询%d级会员数据失败: %v", depth, err)
+		return nil, err
+	}
+	return members, nil
+}
+
+// BackfillMemberReferral 根据会员表的pid重建推荐关系闭包表，weid为0时处理所有站点
+// reset为true时先清空已有记录，否则只补充缺失的记录，可重复执行；返回写入的记录数
+// 按层级逐级写入，最多maxDepth级，pid数据成环时依靠唯一索引停止
+func BackfillMemberReferral(weid int, reset bool, maxDepth int) (int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return 0, errors.New("数据库连接失败")
+	}
+
+	siteSQL, args := "", []interface{}{}
+	if weid > 0 {
+		siteSQL, args = " AND m.weid = ?", []interface{}{weid}
+	}
+
+	if reset {
+		query := gormDB.Table(memberReferralTableName)
+		if weid > 0 {
+			query = query.Where("weid = ?", weid)
+		} else {
+			query = query.Where("1 = 1")
+		}
+		if err := query.Delete(&structs.MemberReferral{}).Error; err != nil {
+			log.Printf("清空推荐关系失败: %v", err)
+			return 0, err
+		}
+	}
+
+	result := gormDB.Exec("INSERT IGNORE INTO "+memberReferralTableName+" (weid, ancestor_id, descendant_id, depth) "+
+		"SELECT m.weid, m.id, m.id, 0 FROM "+memberTableName+" AS m WHERE 1 = 1"+siteSQL, args...)
+	if result.Error != nil {
+		log.Printf("写入会员自身推荐关系失败: %v", result.Error)
+		return 0, result.Error
+	}
+	total := result.RowsAffected
+
+	for depth := 0; depth < maxDepth; depth++ {
+		result := gormDB.Exec("INSERT IGNORE INTO "+memberReferralTableName+" (weid, ancestor_id, descendant_id, depth) "+
+			"SELECT m.weid, r.ancestor_id, m.id, r.depth + 1 FROM "+memberReferralTableName+" AS r "+
+			"JOIN "+memberTableName+" AS m ON m.pid = r.descendant_id "+
+			"WHERE r.depth = ? AND m.pid > 0"+siteSQL, append([]interface{}{depth}, args...)...)
+		if result.Error != nil {
+			log.Printf("写入第%d级推荐关系失败: %v", depth+1, result.Error)
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		log.Printf("已写入第%d级推荐关系%d条", depth+1, result.RowsAffected)
+
+		// 补充模式下本级可能已全部存在，以本级是否有记录判断是否继续下一级
+		var count int64
+		query := gormDB.Table(memberReferralTableName).Where("depth = ?", depth+1)
+		if weid > 0 {
+			query = query.Where("weid = ?", weid)
+		}
+		if err := query.Count(&count).Error; err != nil {
+			return total, err
+		}
+		if count == 0 {
+			break
+		}
+	}
+	return total, nil
+}
+
+// ErrInviteCodeExists 邀请码已被其他会员使用
+var ErrInviteCodeExists = errors.New("邀请码已存在")
+
+// mysqlErrDuplicateEntry MySQL唯一索引冲突的错误码
+const mysqlErrDuplicateEntry = 1062
+
+// GetMemberByInviteCode 根据邀请码获取会员，不存在时返回nil
+func GetMemberByInviteCode(code string) (*structs.Member, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var member structs.Member
+	result := gormDB.Table(memberTableName).Where("invite_code = ?", code).Limit(1).Find(&member)
+	if result.Error != nil {
+		log.Printf("根据邀请码查询会员失败: %v", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &member, nil
+}
+
+// SetMemberInviteCode 给还没有邀请码的会员设置邀请码，invite_code为唯一索引，邀请码重复时返回ErrInviteCodeExists
+func SetMemberInviteCode(uid int, code string) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	err := gormDB.Table(memberTableName).
+		Where("id = ? AND (invite_code IS NULL OR invite_code = '')", uid).
+		Update("invite_code", code).Error
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+			return ErrInviteCodeExists
+		}
+		log.Printf("设置会员邀请码失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// BindMemberReferrer 给会员绑定推荐人并写入推荐关系，已绑定推荐人的会员不能再次绑定
+// window大于0时只允许在注册后window秒内绑定，推荐人必须是同一站点的正常会员
+func BindMemberReferrer(uid, pid int, window int64) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		var member structs.Member
+		if err := tx.Table(memberTableName).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", uid).
+			First(&member).Error; err != nil {
+			return errors.New("会员不存在")
+		}
+		if member.Pid > 0 {
+			return errors.New("已绑定推荐人")
+		}
+		if window > 0 && time.Now().Unix()-member.Regdate > window {
+			return errors.New("已超过绑定推荐人的期限")
+		}
+		if pid == uid {
+			return ErrReferralCycle
+		}
+
+		var referrer structs.Member
+		result := tx.Table(memberTableName).Where("id = ? AND weid = ? AND status = 1", pid, member.Weid).Limit(1).Find(&referrer)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("推荐人不存在")
+		}
+
+		if err := tx.Table(memberTableName).
+			Where("id = ? AND pid = 0", uid).
+			Update("pid", pid).Error; err != nil {
+			return err
+		}
+		return linkMemberReferral(tx, member.Weid, uid, pid)
+	})
+	if err != nil {
+		log.Printf("绑定推荐人失败: %v", err)
+	}
+	return err
+}
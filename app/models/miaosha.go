@@ -3,6 +3,7 @@ package models
 import (
 	"errors"
 	"log"
+	"myapi/app/helper"
 	"myapi/app/storage"
 	"myapi/app/structs"
 	"strings"
@@ -94,7 +95,7 @@ func ValidateCanBuyMiaosha(goodsID int64, msID int64, quantity int64, sku ...str
 
 		// 检查库存是否足够
 		if miaoshaGoodsSkuValue.Quantity < int(quantity) {
-			return nil, errors.New("秒杀商品库存不足")
+			return nil, helper.NewBizError(helper.CodeSeckillSoldOut, "秒杀商品库存不足")
 		}
 	}
 
@@ -122,3 +123,185 @@ func GetMiaoshaGoodsSkuByMsIDAndGoodsID(msID int, goodsID int, sku string) (*str
 
 	return &miaoshaGoodsSkuValue, nil
 }
+
+// GetMiaoshaSkuValues 获取秒杀活动的全部SKU
+func GetMiaoshaSkuValues(msID int) ([]structs.MiaoshaGoodsSkuValue, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var skuValues []structs.MiaoshaGoodsSkuValue
+	result := gormDB.Table(MiaoshaGoodsSkuValueTableName).
+		Where("ms_id = ?", msID).
+		Order("id ASC").
+		Find(&skuValues)
+	if result.Error != nil {
+		log.Printf("查询秒杀SKU列表失败: %v", result.Error)
+		return nil, result.Error
+	}
+	return skuValues, nil
+}
+
+// FindMiaoshaSkuValue 按规格值查找秒杀SKU，规格值以逗号分隔
+func FindMiaoshaSkuValue(msID int, goodsID int, sku string) (*structs.MiaoshaGoodsSkuValue, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	query := gormDB.Table(MiaoshaGoodsSkuValueTableName).Where("goods_id = ? AND ms_id = ?", goodsID, msID)
+	for _, s := range strings.Split(sku, ",") {
+		if s != "" {
+			query = query.Where("FIND_IN_SET( ?,sku)", s)
+		}
+	}
+
+	var skuValue structs.MiaoshaGoodsSkuValue
+	if err := query.First(&skuValue).Error; err != nil {
+		log.Printf("查询秒杀SKU失败: %v", err)
+		return nil, err
+	}
+	return &skuValue, nil
+}
+
+// GetStartingMiaoshaGoods 获取before之前开始且尚未结束的秒杀活动，用于预热库存
+func GetStartingMiaoshaGoods(before int64) ([]structs.MiaoshaGoods, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var list []structs.MiaoshaGoods
+	result := gormDB.Table(miaoshaGoodsTableName).
+		Where("status = ? AND begin_date <= ? AND end_date > ?", 1, before, time.Now().Unix()).
+		Find(&list)
+	if result.Error != nil {
+		log.Printf("查询待预热秒杀活动失败: %v", result.Error)
+		return nil, result.Error
+	}
+	return list, nil
+}
+
+// SyncMiaoshaStock 把Redis中的秒杀库存和销量写回数据库
+// stocks为SKU ID到剩余库存的映射，saleCount为活动累计销量
+func SyncMiaoshaStock(msID int, stocks map[int]int, saleCount int) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		for skuID, quantity := range stocks {
+			if err := tx.Table(MiaoshaGoodsSkuValueTableName).
+				Where("id = ? AND ms_id = ?", skuID, msID).
+				Update("quantity", quantity).Error; err != nil {
+				log.Printf("同步秒杀SKU库存失败: %v", err)
+				return err
+			}
+		}
+		if err := tx.Table(miaoshaGoodsTableName).
+			Where("id = ?", msID).
+			Updates(map[string]interface{}{"sale_count": saleCount, "update_time": time.Now().Unix()}).Error; err != nil {
+			log.Printf("同步秒杀销量失败: %v", err)
+			return err
+		}
+		return nil
+	})
+}
+
+// GetMiaoshaTimes 获取启用的秒杀时间段，weid为0时获取全部站点
+func GetMiaoshaTimes(weid int) ([]structs.MiaoshaTime, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	query := gormDB.Table(miaoshaTimeTableName).Where("status = ?", 1)
+	if weid > 0 {
+		query = query.Where("weid = ?", weid)
+	}
+
+	var times []structs.MiaoshaTime
+	if err := query.Order("begin_time ASC, sort ASC").Find(&times).Error; err != nil {
+		log.Printf("查询秒杀时间段失败: %v", err)
+		return nil, err
+	}
+	return times, nil
+}
+
+// GetMiaoshaTimeByID 根据ID获取秒杀时间段
+func GetMiaoshaTimeByID(id int) (*structs.MiaoshaTime, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var miaoshaTime structs.MiaoshaTime
+	if err := gormDB.Table(miaoshaTimeTableName).Where("id = ? AND status = ?", id, 1).First(&miaoshaTime).Error; err != nil {
+		log.Printf("查询秒杀时间段失败: %v", err)
+		return nil, err
+	}
+	return &miaoshaTime, nil
+}
+
+// GetMiaoshaGoodsInRange 获取活动时间与[start, end)有交集的秒杀商品
+func GetMiaoshaGoodsInRange(weid int, start, end int64) ([]structs.MiaoshaSessionGoods, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var list []structs.MiaoshaSessionGoods
+	result := gormDB.Table(miaoshaGoodsTableName+" AS m").
+		Select("m.*, g.name, g.image, g.original_price").
+		Joins("JOIN "+goodsTableName+" AS g ON g.id = m.goods_id").
+		Where("m.weid = ? AND m.status = ? AND g.status = ?", weid, 1, 1).
+		Where("m.begin_date < ? AND m.end_date > ?", end, start).
+		Order("m.sort ASC, m.id DESC").
+		Find(&list)
+	if result.Error != nil {
+		log.Printf("查询场次秒杀商品失败: %v", result.Error)
+		return nil, result.Error
+	}
+	return list, nil
+}
+
+// GetMiaoshaStockTotals 统计秒杀活动各SKU剩余库存之和，没有SKU的活动不在结果中
+func GetMiaoshaStockTotals(msIDs []int) (map[int]int, error) {
+	totals := make(map[int]int)
+	if len(msIDs) == 0 {
+		return totals, nil
+	}
+
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var rows []struct {
+		MsID  int
+		Total int
+	}
+	result := gormDB.Table(MiaoshaGoodsSkuValueTableName).
+		Select("ms_id, SUM(quantity) AS total").
+		Where("ms_id IN ?", msIDs).
+		Group("ms_id").
+		Scan(&rows)
+	if result.Error != nil {
+		log.Printf("统计秒杀库存失败: %v", result.Error)
+		return nil, result.Error
+	}
+	for _, row := range rows {
+		totals[row.MsID] = row.Total
+	}
+	return totals, nil
+}
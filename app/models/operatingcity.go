@@ -6,10 +6,13 @@ import (
 	"log"
 	"time"
 
+	"myapi/app/config"
+	"myapi/app/helper"
 	"myapi/app/storage"
 	"myapi/app/structs"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GetOperatingcityByID 根据ID获取运营城市信息
@@ -140,6 +143,25 @@ func GetOperatingcityByAreaType(areaType int, areaName string) ([]structs.Operat
 	return cities, nil
 }
 
+// GetOperatingcitiesByUid 获取会员名下的运营城市
+func GetOperatingcitiesByUid(weid, uid int) ([]structs.Operatingcity, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var cities []structs.Operatingcity
+	if err := gormDB.Table(operatingcityTableName).
+		Where("weid = ? AND uid = ?", weid, uid).
+		Order("areatype ASC, id ASC").
+		Find(&cities).Error; err != nil {
+		log.Printf("查询会员运营城市失败: %v", err)
+		return nil, err
+	}
+	return cities, nil
+}
+
 // SetIncome 处理订单的城市代理收入分配
 func SetIncome(orderInfo map[string]interface{}) error {
 	// 处理省级城市代理
@@ -184,7 +206,30 @@ func SetIncome(orderInfo map[string]interface{}) error {
 	return nil
 }
 
+// GetOperatingcityLevelPercent 获取运营城市等级的返点百分比，等级不存在、未启用或未设置时返回0
+func GetOperatingcityLevelPercent(level int) (float64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return 0, errors.New("数据库连接失败")
+	}
+
+	var operatingcityLevel structs.OperatingcityLevel
+	result := gormDB.Table(operatingcityLevelTableName).
+		Where("id = ? AND status = 1", level).
+		Limit(1).
+		Find(&operatingcityLevel)
+	if result.Error != nil {
+		log.Printf("查询运营城市等级失败: %v", result.Error)
+		return 0, result.Error
+	}
+	return operatingcityLevel.ReturnPercent, nil
+}
+
 // Calculate 计算并分配城市代理收入
+// 返点比例取代理等级的比例，未设置时使用operatingcityDefaultPercent配置；
+// 收入按订单可计佣金额计算，并受订单佣金总额上限约束；
+// 订单佣金总额、代理收入和收入日志在同一事务中写入，同一订单同一代理只结算一次
 func Calculate(orderInfo map[string]interface{}, operatingcity structs.Operatingcity) error {
 	// 获取共享的gorm连接实例
 	gormDB := storage.GetGormDB()
@@ -197,51 +242,68 @@ func Calculate(orderInfo map[string]interface{}, operatingcity structs.Operating
 		return nil
 	}
 
-	// 获取返点比例，这里需要调用其他模块的方法，暂时使用默认值
-	percent := 0.0
-	// TODO: 实现OperatingcityLevel::getPercent方法
-
+	// 获取返点比例
+	percent, err := GetOperatingcityLevelPercent(int(operatingcity.Level))
+	if err != nil {
+		return err
+	}
+	if percent <= 0 {
+		percent = config.GetFloat("operatingcityDefaultPercent", 0)
+	}
 	if percent <= 0 {
-		// TODO: 从配置中获取默认返点比例
 		return nil
 	}
 
 	// 计算收入
-	income := 0.0
-	// TODO: 实现佣金计算逻辑，需要调用其他模块的方法
-
-	if income <= 0 {
+	orderID, _ := helper.ToInt(orderInfo["id"])
+	weid, _ := helper.ToInt(orderInfo["weid"])
+	buyerID, _ := helper.ToInt(orderInfo["uid"])
+	total, _ := helper.ToFloat64(orderInfo["total"])
+	freight, _ := helper.ToFloat64(orderInfo["freight"])
+	orderNumAlias, _ := orderInfo["order_num_alias"].(string)
+	payTime, _ := helper.ToInt(orderInfo["pay_time"])
+	base := CommissionableAmount(total, freight)
+	income := roundMoney(base * percent / 100)
+	if orderID <= 0 || income <= 0 {
 		return nil
 	}
 
-	// 检查佣金是否超额
-	// TODO: 实现订单佣金总额检查逻辑
-
-	// 更新订单佣金总额
-	// TODO: 实现订单佣金总额更新逻辑
-
-	// 检查收入日志是否已存在
-	var incomelog structs.OperatingcityIncomelog
-	orderID := 0
-	if id, ok := orderInfo["id"].(int); ok {
-		orderID = id
-	}
-	weid := 0
-	if w, ok := orderInfo["weid"].(int); ok {
-		weid = w
-	}
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		// 检查收入日志是否已存在
+		var count int64
+		if err := tx.Table(operatingcityIncomelogTableName).
+			Where("ocid = ? AND areatype = ? AND weid = ? AND order_id = ?",
+				operatingcity.ID, operatingcity.Areatype, weid, orderID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		// 订单已退款(如拼团失败)时不再分配
+		if ok, err := orderIncomeSettleable(tx, orderID); err != nil || !ok {
+			return err
+		}
 
-	result := gormDB.Table(operatingcityIncomelogTableName).
-		Where("ocid = ? AND areatype = ? AND weid = ? AND order_id = ?",
-			operatingcity.ID, operatingcity.Areatype, weid, orderID).
-		First(&incomelog)
+		// 检查佣金是否超额，并更新订单佣金总额
+		income, err := reserveOrderCommission(tx, orderID, income, base)
+		if err != nil {
+			return err
+		}
+		if income <= 0 {
+			log.Printf("订单%d佣金已达上限，城市代理%d不再分配", orderID, operatingcity.ID)
+			return nil
+		}
 
-	if result.Error != nil {
 		// 更新城市代理收入
-		gormDB.Table(operatingcityTableName).
+		if err := tx.Table(operatingcityTableName).
 			Where("id = ?", operatingcity.ID).
-			Update("income", gorm.Expr("income + ?", income)).
-			Update("total_income", gorm.Expr("total_income + ?", income))
+			Updates(map[string]interface{}{
+				"income":       gorm.Expr("income + ?", income),
+				"total_income": gorm.Expr("total_income + ?", income),
+			}).Error; err != nil {
+			return err
+		}
 
 		// 创建收入日志
 		currentTime := time.Now()
@@ -251,41 +313,27 @@ func Calculate(orderInfo map[string]interface{}, operatingcity structs.Operating
 			Areatype:      operatingcity.Areatype,
 			Weid:          weid,
 			OrderID:       orderID,
-			BuyerID:       0,
+			OrderNumAlias: orderNumAlias,
+			BuyerID:       buyerID,
 			Income:        income,
 			ReturnPercent: percent,
-			PayTime:       int(currentTime.Unix()),
+			PercentRemark: fmt.Sprintf("%.2f x %.2f%%", base, percent),
+			OrderTotal:    total,
+			PayTime:       payTime,
 			CreateTime:    int(currentTime.Unix()),
 			MonthTime:     fmt.Sprintf("%d-%02d", currentTime.Year(), currentTime.Month()),
 			YearTime:      fmt.Sprintf("%d", currentTime.Year()),
-			OrderStatusID: 2, // 已付款
+			OrderStatusID: structs.OrderStatusPaid,
 		}
-
-		// 设置订单号
-		if orderNumAlias, ok := orderInfo["order_num_alias"].(string); ok {
-			incomedata.OrderNumAlias = orderNumAlias
-		}
-
-		// 设置买家ID
-		if uid, ok := orderInfo["uid"].(int); ok {
-			incomedata.BuyerID = uid
+		if incomedata.PayTime == 0 {
+			incomedata.PayTime = incomedata.CreateTime
 		}
-
-		// 设置订单总额
-		if total, ok := orderInfo["total"].(float64); ok {
-			incomedata.OrderTotal = total
-			incomedata.PercentRemark = fmt.Sprintf("%.2f%% x %.2f%%", total, percent)
-		}
-
-		// 创建收入日志
-		result = gormDB.Table(operatingcityIncomelogTableName).Create(&incomedata)
-		if result.Error != nil {
-			log.Printf("创建城市代理收入日志失败: %v", result.Error)
-			return result.Error
+		if err := tx.Table(operatingcityIncomelogTableName).Create(&incomedata).Error; err != nil {
+			log.Printf("创建城市代理收入日志失败: %v", err)
+			return err
 		}
-	}
-
-	return nil
+		return nil
+	})
 }
 
 // Conversion 转换运营城市数据格式
@@ -318,3 +366,53 @@ func Conversion(vo map[string]interface{}) map[string]interface{} {
 
 	return vo
 }
+
+// reverseOperatingcityIncome 订单退款时冲回城市代理收入，扣减代理收入并为每条收入日志写一条负数冲回日志，须在事务中调用
+// 代理已提现的收入不追回，扣减后收入可能为负数，后续收入先抵扣
+func reverseOperatingcityIncome(tx *gorm.DB, orderID int) error {
+	var logs []structs.OperatingcityIncomelog
+	if err := tx.Table(operatingcityIncomelogTableName).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND income > 0 AND order_status_id <> ?", orderID, structs.OrderStatusRefunded).
+		Find(&logs).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	total := 0.0
+	for _, incomeLog := range logs {
+		if err := tx.Table(operatingcityTableName).
+			Where("id = ?", incomeLog.Ocid).
+			Updates(map[string]interface{}{
+				"income":       gorm.Expr("income - ?", incomeLog.Income),
+				"total_income": gorm.Expr("total_income - ?", incomeLog.Income),
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Table(operatingcityIncomelogTableName).
+			Where("id = ?", incomeLog.ID).
+			Update("order_status_id", structs.OrderStatusRefunded).Error; err != nil {
+			return err
+		}
+
+		reversal := incomeLog
+		reversal.ID = 0
+		reversal.Income = -incomeLog.Income
+		reversal.Description = "订单" + incomeLog.OrderNumAlias + "退款冲回"
+		reversal.CreateTime = int(now.Unix())
+		reversal.MonthTime = fmt.Sprintf("%d-%02d", now.Year(), now.Month())
+		reversal.YearTime = fmt.Sprintf("%d", now.Year())
+		reversal.OrderStatusID = structs.OrderStatusRefunded
+		if err := tx.Table(operatingcityIncomelogTableName).Create(&reversal).Error; err != nil {
+			log.Printf("写入城市代理收入冲回日志失败: %v", err)
+			return err
+		}
+		total += incomeLog.Income
+	}
+	if total <= 0 {
+		return nil
+	}
+	return tx.Table(orderTableName).
+		Where("id = ?", orderID).
+		Update("commission_total", gorm.Expr("GREATEST(commission_total - ?, 0)", roundMoney(total))).Error
+}
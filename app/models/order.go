@@ -3,14 +3,37 @@ package models
 import (
 	"errors"
 	"log"
+	"time"
 
 	"myapi/app/storage"
 	"myapi/app/structs"
+
+	"gorm.io/gorm"
 )
 
-// CheckMiaoshaMemberBuyMax 检查用户是否超过秒杀商品的购买限制
-// uid 用户ID，miaosha 秒杀活动信息
-func CheckMiaoshaMemberBuyMax(uid int64, miaosha structs.MiaoshaGoods) (int, error) {
+// CheckMiaoshaMemberBuyMax 检查用户再购买quantity件后是否超过秒杀商品的每人限购数量
+// uid 用户ID，miaosha 秒杀活动信息，quantity 本次购买数量；超过限制返回1
+func CheckMiaoshaMemberBuyMax(uid int64, miaosha structs.MiaoshaGoods, quantity int) (int, error) {
+	if miaosha.MemberBuyMax <= 0 {
+		return 0, nil
+	}
+
+	bought, err := GetMiaoshaMemberBoughtQuantity(int(uid), miaosha.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	// 检查是否超过购买限制
+	if bought+quantity > miaosha.MemberBuyMax {
+		return 1, nil // 超过限制，返回1
+	} else {
+		return 0, nil // 未超过限制，返回0
+	}
+}
+
+// GetMiaoshaMemberBoughtQuantity 统计用户在秒杀活动中已购买的商品数量
+// 包括待付款订单，不含已取消和已退款的订单
+func GetMiaoshaMemberBoughtQuantity(uid int, msID int) (int, error) {
 	// 获取共享的gorm连接实例
 	gormDB := storage.GetGormDB()
 	if gormDB == nil {
@@ -18,22 +41,145 @@ func CheckMiaoshaMemberBuyMax(uid int64, miaosha structs.MiaoshaGoods) (int, err
 		return 0, errors.New("数据库连接失败")
 	}
 
-	// 查询用户购买该秒杀商品的订单数量
-	var orderCount int64
+	var total int64
+	result := gormDB.Table(orderGoodsTableName+" AS og").
+		Select("COALESCE(SUM(og.quantity), 0)").
+		Joins("JOIN "+orderTableName+" AS o ON o.id = og.order_id").
+		Where("o.uid = ? AND o.ms_id = ?", uid, msID).
+		Where("o.order_status_id NOT IN ?", []int{structs.OrderStatusCancelled, structs.OrderStatusRefunded}).
+		Scan(&total)
+	if result.Error != nil {
+		log.Printf("查询用户秒杀购买数量失败: %v", result.Error)
+		return 0, result.Error
+	}
+
+	return int(total), nil
+}
+
+// GetOrderByID 根据ID获取订单
+func GetOrderByID(id int) (*structs.Order, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return nil, errors.New("数据库连接失败")
+	}
+
+	order := &structs.Order{}
 	result := gormDB.Table(orderTableName).
-		Where("uid = ?", uid).
-		Where("ms_id = ?", miaosha.ID).
-		Count(&orderCount)
+		Where("id = ?", id).
+		First(order)
 
 	if result.Error != nil {
-		log.Printf("查询用户秒杀订单数量失败: %v", result.Error)
-		return 0, result.Error
+		log.Printf("根据ID查询订单失败: %v", result.Error)
+		return nil, result.Error
 	}
 
-	// 检查是否超过购买限制
-	if orderCount >= int64(miaosha.MemberBuyMax) {
-		return 1, nil // 超过限制，返回1
-	} else {
-		return 0, nil // 未超过限制，返回0
+	return order, nil
+}
+
+// GetOrderGoodsByOrderID 获取订单的商品明细
+func GetOrderGoodsByOrderID(orderID int) ([]structs.OrderGoods, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var list []structs.OrderGoods
+	if err := gormDB.Table(orderGoodsTableName).Where("order_id = ?", orderID).Find(&list).Error; err != nil {
+		log.Printf("查询订单商品失败: %v", err)
+		return nil, err
+	}
+	return list, nil
+}
+
+// CompleteOrder 会员确认收货，已付款或已发货的订单标记为已完成，并在同一事务中解冻分销佣金
+func CompleteOrder(uid, orderID int) (*structs.Order, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var order structs.Order
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(orderTableName).Where("id = ? AND uid = ?", orderID, uid).First(&order).Error; err != nil {
+			return errors.New("订单不存在")
+		}
+		if order.OrderStatusID != structs.OrderStatusPaid && order.OrderStatusID != structs.OrderStatusShipped {
+			return errors.New("订单当前状态不能确认收货")
+		}
+
+		now := int(time.Now().Unix())
+		result := tx.Table(orderTableName).
+			Where("id = ? AND order_status_id = ?", order.ID, order.OrderStatusID).
+			Updates(map[string]interface{}{
+				"order_status_id": structs.OrderStatusCompleted,
+				"complete_time":   now,
+				"update_time":     now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("订单状态已变更，请刷新后重试")
+		}
+		order.OrderStatusID = structs.OrderStatusCompleted
+		order.CompleteTime = now
+		return settleOrderCommissions(tx, order.ID)
+	})
+	if err != nil {
+		log.Printf("确认收货失败: %v", err)
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CancelOrder 会员取消待付款订单，并在同一事务中释放会员对订单商品还未支付的库存预留
+func CancelOrder(uid, orderID int, remark string) (*structs.Order, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var order structs.Order
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(orderTableName).Where("id = ? AND uid = ?", orderID, uid).First(&order).Error; err != nil {
+			return errors.New("订单不存在")
+		}
+		if order.OrderStatusID != structs.OrderStatusUnpaid {
+			return errors.New("只有待付款订单可以取消")
+		}
+
+		now := int(time.Now().Unix())
+		result := tx.Table(orderTableName).
+			Where("id = ? AND order_status_id = ?", order.ID, structs.OrderStatusUnpaid).
+			Updates(map[string]interface{}{
+				"order_status_id": structs.OrderStatusCancelled,
+				"update_time":     now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("订单状态已变更，请刷新后重试")
+		}
+		order.OrderStatusID = structs.OrderStatusCancelled
+		order.UpdateTime = now
+
+		var goods []structs.OrderGoods
+		if err := tx.Table(orderGoodsTableName).Where("order_id = ?", order.ID).Find(&goods).Error; err != nil {
+			return err
+		}
+		items, err := expandCombinationItems(tx, OrderStockItems(goods))
+		if err != nil {
+			return err
+		}
+		return releasePendingStock(tx, order.UID, items, remark)
+	})
+	if err != nil {
+		log.Printf("取消订单失败: %v", err)
+		return nil, err
 	}
+	return &order, nil
 }
@@ -9,6 +9,8 @@ import (
 	"myapi/app/structs"
 	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // GetProductByID 根据ID获取产品
@@ -55,6 +57,82 @@ func GetAllProducts() []structs.Product {
 	return products
 }
 
+// productSortColumns 商品列表排序字段
+var productSortColumns = map[string]string{
+	structs.ProductSortDefault: "sort",
+	structs.ProductSortPrice:   "price",
+	structs.ProductSortSales:   "(sale_count + sale_count_base)",
+	structs.ProductSortNewest:  "create_time",
+}
+
+// GetProductList 按条件分页获取上架商品，排序值相同时按ID排序，保证游标翻页不重不漏
+func GetProductList(f structs.ProductListFilter, page, limit int) ([]structs.Product, int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, 0, errors.New("数据库连接失败")
+	}
+	column, ok := productSortColumns[f.Sort]
+	if !ok {
+		return nil, 0, errors.New("不支持的排序方式")
+	}
+
+	query := gormDB.Table(goodsTableName).Where("status = 1")
+	if f.Weid > 0 {
+		query = query.Where("weid = ?", f.Weid)
+	}
+	if f.CatID > 0 {
+		sub := gormDB.Table(goodsToCategoryTableName).Select("goods_id").Where("category_id = ?", f.CatID)
+		query = query.Where("(cat_id = ? OR id IN (?))", f.CatID, sub)
+	}
+	if f.Ocid > 0 {
+		query = query.Where("ocid = ?", f.Ocid)
+	}
+	if f.Sid > 0 {
+		query = query.Where("sid = ?", f.Sid)
+	}
+	if f.MinPrice > 0 {
+		query = query.Where("price >= ?", f.MinPrice)
+	}
+	if f.MaxPrice > 0 {
+		query = query.Where("price <= ?", f.MaxPrice)
+	}
+	if f.IsHot == 1 {
+		query = query.Where("is_hot = 1")
+	}
+	if f.IsNew == 1 {
+		query = query.Where("is_new = 1")
+	}
+	if f.IsRecommended == 1 {
+		query = query.Where("is_recommended = 1")
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		log.Printf("统计商品列表数量失败: %v", err)
+		return nil, 0, err
+	}
+
+	direction, op := "ASC", ">"
+	if f.Desc {
+		direction, op = "DESC", "<"
+	}
+	if f.Cursor != nil {
+		query = query.Where("("+column+" "+op+" ? OR ("+column+" = ? AND id "+op+" ?))",
+			f.Cursor.Value, f.Cursor.Value, f.Cursor.ID)
+	} else {
+		query = query.Offset((page - 1) * limit)
+	}
+
+	var products []structs.Product
+	if err := query.Order(column + " " + direction).Order("id " + direction).
+		Limit(limit).Find(&products).Error; err != nil {
+		log.Printf("查询商品列表失败: %v", err)
+		return nil, 0, err
+	}
+	return products, total, nil
+}
+
 // GetCategoryByID 根据ID获取分类信息
 func GetCategoryByID(id int) (*structs.Category, error) {
 	gormDB := storage.GetGormDB()
@@ -99,6 +177,17 @@ func CreateGoodsBuynowinfo(weid int, ip string, data string) (int, error) {
 	return int(buynowinfo.ID), nil
 }
 
+// whereSkuValue 按商品和规格匹配SKU，sku为逗号分隔的规格值，每个规格值都要包含在SKU中，与顺序无关
+func whereSkuValue(query *gorm.DB, goodsID int, sku string) *gorm.DB {
+	query = query.Where("goods_id = ?", goodsID)
+	for _, vo := range strings.Split(sku, ",") {
+		if vo != "" {
+			query = query.Where("FIND_IN_SET(?, sku)", vo)
+		}
+	}
+	return query
+}
+
 // CartGoods 获取购物车商品信息
 func CartGoods(params map[string]interface{}) (map[string]interface{}, error) {
 	log.Println("开始执行CartGoods函数，参数:", params)
@@ -142,17 +231,8 @@ func CartGoods(params map[string]interface{}) (map[string]interface{}, error) {
 	if skuStr != "" && (tuanid == 0 && msid == 0) {
 		// 检查SKU是否存在
 		var SkuValueresult structs.GoodsSkuValue
-		var query = gormDB.Table(goodsSkuValueTableName).
-			Where("goods_id = ? ", params["GoodsID"])
-		// 分割sku字符串并添加条件
-		skuValues := strings.Split(skuStr, ",")
-		for _, vo := range skuValues {
-			if vo != "" {
-				query = query.Where("FIND_IN_SET(?, sku)", vo)
-			}
-		}
-
-		result := query.First(&SkuValueresult)
+		goodsID, _ := helper.ToInt(params["GoodsID"])
+		result := whereSkuValue(gormDB.Table(goodsSkuValueTableName), goodsID, skuStr).First(&SkuValueresult)
 		//如果有就替换价格
 		if result.Error == nil {
 			extraData["price"] = SkuValueresult.Price
@@ -160,18 +240,34 @@ func CartGoods(params map[string]interface{}) (map[string]interface{}, error) {
 			log.Println("SKU存在，价格:", SkuValueresult.Price)
 		}
 	}
+
+	// 套装商品需要检查每个组件的库存
+	if isCombination, _ := helper.ToInt(extraData["is_combination"]); isCombination == 1 {
+		goodsID, _ := helper.ToInt(params["GoodsID"])
+		buyNumber, _ := helper.ToInt(params["quantity"])
+		items, err := GetCombinationItems(goodsID)
+		if err != nil {
+			return nil, err
+		}
+		if err := CheckCombinationStock(items, buyNumber); err != nil {
+			return nil, err
+		}
+		extraData["combination"] = items
+	}
 	log.Println("团购价格处理前的价格: ,", extraData["price"], "数量:", extraData["quantity"])
 
 	// 处理团购信息
 	if tuanid > 0 {
 		// 正确传递字符串给可变参数函数
-		tuanGoods, err := ValidateCanJoinTuan(params["GoodsID"].(int64), int64(tuanid), params["quantity"].(int64), skuStr)
+		joinTuanID, _ := helper.ToInt(params["jointuanid"])
+		tuanGoods, err := ValidateCanJoinTuan(params["GoodsID"].(int64), int64(tuanid), int64(joinTuanID), params["quantity"].(int64), skuStr)
 		if err != nil {
 			log.Printf("团购验证失败: %v，直接返回错误", err)
 			return nil, err
 		}
 		extraData["price"] = tuanGoods.Price
 		extraData["tuanid"] = tuanid
+		extraData["jointuanid"] = joinTuanID
 		log.Println("团购价格处理后的价格: ", extraData["price"])
 		//如果是团购就返回团购相关信息
 		return extraData, nil
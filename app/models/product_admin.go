@@ -0,0 +1,279 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"myapi/app/storage"
+	"myapi/app/structs"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrProductNotFound 商品不存在或已删除
+var ErrProductNotFound = errors.New("商品不存在或已删除")
+
+// productProtectedColumns 后台修改商品时不能覆盖的字段，库存只能通过AdjustStock调整并写入流水
+var productProtectedColumns = []string{"id", "weid", "quantity", "sale_count", "viewed", "create_time", "delete_time"}
+
+// GetProductForm 获取后台编辑用的商品数据，已删除的商品返回ErrProductNotFound
+func GetProductForm(weid, id int) (*structs.ProductForm, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	form := &structs.ProductForm{}
+	var goods []structs.Product
+	if err := gormDB.Table(goodsTableName).
+		Where("id = ? AND weid = ? AND delete_time = 0", id, weid).
+		Limit(1).
+		Find(&goods).Error; err != nil {
+		log.Printf("查询商品失败: %v", err)
+		return nil, err
+	}
+	if len(goods) == 0 {
+		return nil, ErrProductNotFound
+	}
+	form.Goods = goods[0]
+
+	queries := []struct {
+		table string
+		dest  interface{}
+		order string
+	}{
+		{goodsSkuTableName, &form.Skus, "id ASC"},
+		{goodsSkuValueTableName, &form.SkuValues, "id ASC"},
+		{goodsImageTableName, &form.Images, "sort ASC, id ASC"},
+		{goodsDiscountTableName, &form.Discounts, "quantity ASC"},
+	}
+	for _, q := range queries {
+		if err := gormDB.Table(q.table).Where("goods_id = ?", id).Order(q.order).Find(q.dest).Error; err != nil {
+			log.Printf("查询商品关联数据失败: %v", err)
+			return nil, err
+		}
+	}
+
+	description, err := GetGoodsDescription(id)
+	if err != nil {
+		return nil, err
+	}
+	if description != nil {
+		form.Description = *description
+	}
+
+	if err := gormDB.Table(goodsToCategoryTableName).
+		Where("goods_id = ?", id).
+		Order("category_id ASC").
+		Pluck("category_id", &form.CategoryIDs).Error; err != nil {
+		log.Printf("查询商品分类关联失败: %v", err)
+		return nil, err
+	}
+	return form, nil
+}
+
+// CreateProduct 在一个事务中新增商品及其规格、图片、描述、数量折扣和分类关联
+func CreateProduct(form *structs.ProductForm) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		now := int(time.Now().Unix())
+		form.Goods.ID = 0
+		form.Goods.CreateTime = now
+		form.Goods.UpdateTime = now
+		form.Goods.DeleteTime = 0
+		if err := tx.Table(goodsTableName).Create(&form.Goods).Error; err != nil {
+			log.Printf("新增商品失败: %v", err)
+			return err
+		}
+		if err := saveProductRelations(tx, form); err != nil {
+			return err
+		}
+		return SyncStockStatus(tx, []int{form.Goods.ID})
+	})
+}
+
+// UpdateProduct 在一个事务中修改商品，规格、图片、描述、数量折扣和分类关联整体替换
+// 商品库存不修改，已有规格保留当前库存，新增规格库存为0，库存变动通过AdjustStock写入流水
+func UpdateProduct(form *structs.ProductForm) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		var current structs.Product
+		result := tx.Table(goodsTableName).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND weid = ? AND delete_time = 0", form.Goods.ID, form.Goods.Weid).
+			Limit(1).
+			Find(&current)
+		if result.Error != nil {
+			log.Printf("查询商品失败: %v", result.Error)
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrProductNotFound
+		}
+
+		form.Goods.CreateTime = current.CreateTime
+		form.Goods.UpdateTime = int(time.Now().Unix())
+		if err := tx.Table(goodsTableName).
+			Where("id = ?", form.Goods.ID).
+			Select("*").
+			Omit(productProtectedColumns...).
+			Updates(&form.Goods).Error; err != nil {
+			log.Printf("修改商品失败: %v", err)
+			return err
+		}
+
+		var currentValues []structs.GoodsSkuValue
+		if err := tx.Table(goodsSkuValueTableName).Where("goods_id = ?", form.Goods.ID).Find(&currentValues).Error; err != nil {
+			log.Printf("查询商品SKU失败: %v", err)
+			return err
+		}
+		skuQuantity := make(map[string]int, len(currentValues))
+		for _, value := range currentValues {
+			skuQuantity[value.Sku] = value.Quantity
+		}
+		for i := range form.SkuValues {
+			form.SkuValues[i].Quantity = skuQuantity[form.SkuValues[i].Sku]
+		}
+
+		relationTables := []string{
+			goodsSkuTableName, goodsSkuValueTableName, goodsImageTableName,
+			goodsDescriptionTableName, goodsDiscountTableName, goodsToCategoryTableName,
+		}
+		for _, table := range relationTables {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE goods_id = ?", form.Goods.ID).Error; err != nil {
+				log.Printf("清除商品关联数据失败: %v", err)
+				return err
+			}
+		}
+		if err := saveProductRelations(tx, form); err != nil {
+			return err
+		}
+		return SyncStockStatus(tx, []int{form.Goods.ID})
+	})
+}
+
+// saveProductRelations 写入商品的规格、图片、描述、数量折扣和分类关联
+func saveProductRelations(tx *gorm.DB, form *structs.ProductForm) error {
+	goodsID := form.Goods.ID
+
+	for i := range form.Skus {
+		form.Skus[i].ID = 0
+		form.Skus[i].GoodsID = goodsID
+	}
+	for i := range form.SkuValues {
+		form.SkuValues[i].ID = 0
+		form.SkuValues[i].GoodsID = goodsID
+	}
+	for i := range form.Images {
+		form.Images[i].ID = 0
+		form.Images[i].GoodsID = goodsID
+	}
+	for i := range form.Discounts {
+		form.Discounts[i].ID = 0
+		form.Discounts[i].GoodsID = goodsID
+	}
+
+	if len(form.Skus) > 0 {
+		if err := tx.Table(goodsSkuTableName).Create(&form.Skus).Error; err != nil {
+			log.Printf("保存商品规格失败: %v", err)
+			return err
+		}
+	}
+	if len(form.SkuValues) > 0 {
+		if err := tx.Table(goodsSkuValueTableName).Create(&form.SkuValues).Error; err != nil {
+			log.Printf("保存商品SKU失败: %v", err)
+			return err
+		}
+	}
+	if len(form.Images) > 0 {
+		if err := tx.Table(goodsImageTableName).Create(&form.Images).Error; err != nil {
+			log.Printf("保存商品图片失败: %v", err)
+			return err
+		}
+	}
+	if len(form.Discounts) > 0 {
+		if err := tx.Table(goodsDiscountTableName).Create(&form.Discounts).Error; err != nil {
+			log.Printf("保存商品数量折扣失败: %v", err)
+			return err
+		}
+	}
+
+	form.Description.ID = 0
+	form.Description.GoodsID = goodsID
+	if err := tx.Table(goodsDescriptionTableName).Create(&form.Description).Error; err != nil {
+		log.Printf("保存商品描述失败: %v", err)
+		return err
+	}
+
+	if len(form.CategoryIDs) > 0 {
+		links := make([]structs.GoodsToCategory, 0, len(form.CategoryIDs))
+		for _, categoryID := range form.CategoryIDs {
+			links = append(links, structs.GoodsToCategory{GoodsID: goodsID, CategoryID: categoryID})
+		}
+		if err := tx.Table(goodsToCategoryTableName).Create(&links).Error; err != nil {
+			log.Printf("保存商品分类关联失败: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// SoftDeleteProduct 软删除商品，下架并记录删除时间，关联数据保留供历史订单查看
+func SoftDeleteProduct(weid, id int) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	now := time.Now().Unix()
+	result := gormDB.Table(goodsTableName).
+		Where("id = ? AND weid = ? AND delete_time = 0", id, weid).
+		Updates(map[string]interface{}{
+			"status":      0,
+			"delete_time": now,
+			"update_time": now,
+		})
+	if result.Error != nil {
+		log.Printf("删除商品失败: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrProductNotFound
+	}
+	return nil
+}
+
+// CategoriesExist 判断分类是否都属于该站点
+func CategoriesExist(weid int, ids []int) (bool, error) {
+	if len(ids) == 0 {
+		return true, nil
+	}
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return false, errors.New("数据库连接失败")
+	}
+
+	var count int64
+	if err := gormDB.Table(categoryTableName).
+		Where("weid = ? AND id IN ?", weid, ids).
+		Count(&count).Error; err != nil {
+		log.Printf("查询分类失败: %v", err)
+		return false, err
+	}
+	return int(count) == len(ids), nil
+}
@@ -0,0 +1,225 @@
+package models
+
+import (
+	"errors"
+	"log"
+
+	"myapi/app/storage"
+	"myapi/app/structs"
+)
+
+// GetGoodsImages 获取商品图片，按排序值升序
+func GetGoodsImages(goodsID int) ([]structs.GoodsImage, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var images []structs.GoodsImage
+	result := gormDB.Table(goodsImageTableName).
+		Where("goods_id = ?", goodsID).
+		Order("sort ASC, id ASC").
+		Find(&images)
+	if result.Error != nil {
+		log.Printf("查询商品图片失败: %v", result.Error)
+		return nil, result.Error
+	}
+	return images, nil
+}
+
+// GetGoodsDescription 获取商品描述，没有记录时返回nil
+func GetGoodsDescription(goodsID int) (*structs.GoodsDescription, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var descriptions []structs.GoodsDescription
+	result := gormDB.Table(goodsDescriptionTableName).
+		Where("goods_id = ?", goodsID).
+		Limit(1).
+		Find(&descriptions)
+	if result.Error != nil {
+		log.Printf("查询商品描述失败: %v", result.Error)
+		return nil, result.Error
+	}
+	if len(descriptions) == 0 {
+		return nil, nil
+	}
+	return &descriptions[0], nil
+}
+
+// GetGoodsDiscounts 获取商品数量折扣，按起购数量升序
+func GetGoodsDiscounts(goodsID int) ([]structs.GoodsDiscount, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var discounts []structs.GoodsDiscount
+	result := gormDB.Table(goodsDiscountTableName).
+		Where("goods_id = ?", goodsID).
+		Order("quantity ASC").
+		Find(&discounts)
+	if result.Error != nil {
+		log.Printf("查询商品数量折扣失败: %v", result.Error)
+		return nil, result.Error
+	}
+	return discounts, nil
+}
+
+// GetGoodsSkus 获取商品规格定义
+func GetGoodsSkus(goodsID int) ([]structs.GoodsSku, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var skus []structs.GoodsSku
+	result := gormDB.Table(goodsSkuTableName).
+		Where("goods_id = ?", goodsID).
+		Order("id ASC").
+		Find(&skus)
+	if result.Error != nil {
+		log.Printf("查询商品规格失败: %v", result.Error)
+		return nil, result.Error
+	}
+	return skus, nil
+}
+
+// GetGoodsSkuValues 获取商品全部规格组合的价格和库存
+func GetGoodsSkuValues(goodsID int) ([]structs.GoodsSkuValue, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var values []structs.GoodsSkuValue
+	result := gormDB.Table(goodsSkuValueTableName).
+		Where("goods_id = ?", goodsID).
+		Order("id ASC").
+		Find(&values)
+	if result.Error != nil {
+		log.Printf("查询商品SKU列表失败: %v", result.Error)
+		return nil, result.Error
+	}
+	return values, nil
+}
+
+// GetActiveTuanGoodsByGoodsID 获取商品当前进行中的团购活动，没有时返回nil
+func GetActiveTuanGoodsByGoodsID(goodsID int, now int64) (*structs.TuanGoods, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var list []structs.TuanGoods
+	result := gormDB.Table(tuanGoodsTableName).
+		Where("goods_id = ? AND status = 1 AND begin_date <= ? AND end_date >= ?", goodsID, now, now).
+		Order("sort ASC, id DESC").
+		Limit(1).
+		Find(&list)
+	if result.Error != nil {
+		log.Printf("查询商品进行中的团购失败: %v", result.Error)
+		return nil, result.Error
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+// GetTuanSkuValues 获取团购活动的全部SKU
+func GetTuanSkuValues(tuanID int) ([]structs.TuanGoodsSkuValue, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var values []structs.TuanGoodsSkuValue
+	result := gormDB.Table(tuanGoodsSkuValueTableName).
+		Where("tuan_id = ?", tuanID).
+		Order("id ASC").
+		Find(&values)
+	if result.Error != nil {
+		log.Printf("查询团购SKU列表失败: %v", result.Error)
+		return nil, result.Error
+	}
+	return values, nil
+}
+
+// GetActiveMiaoshaGoodsByGoodsID 获取商品当前进行中的秒杀活动，没有时返回nil
+func GetActiveMiaoshaGoodsByGoodsID(goodsID int, now int64) (*structs.MiaoshaGoods, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var list []structs.MiaoshaGoods
+	result := gormDB.Table(miaoshaGoodsTableName).
+		Where("goods_id = ? AND status = 1 AND begin_date <= ? AND end_date >= ?", goodsID, now, now).
+		Order("end_date ASC, id DESC").
+		Limit(1).
+		Find(&list)
+	if result.Error != nil {
+		log.Printf("查询商品进行中的秒杀失败: %v", result.Error)
+		return nil, result.Error
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+// GetSearchableProducts 获取需要建立搜索索引的上架商品，weid为0时获取全部站点
+func GetSearchableProducts(weid int) ([]structs.SearchProduct, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	query := gormDB.Table(goodsTableName).
+		Select("id, weid, name, keyword, image, price, original_price, sale_count, sale_count_base").
+		Where("status = 1")
+	if weid > 0 {
+		query = query.Where("weid = ?", weid)
+	}
+	var products []structs.SearchProduct
+	if err := query.Find(&products).Error; err != nil {
+		log.Printf("查询搜索商品失败: %v", err)
+		return nil, err
+	}
+	return products, nil
+}
+
+// GetSearchableProduct 获取单个上架商品的搜索字段，商品不存在或已下架时返回nil
+func GetSearchableProduct(id int) (*structs.SearchProduct, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var products []structs.SearchProduct
+	if err := gormDB.Table(goodsTableName).
+		Select("id, weid, name, keyword, image, price, original_price, sale_count, sale_count_base").
+		Where("id = ? AND status = 1", id).
+		Limit(1).
+		Find(&products).Error; err != nil {
+		log.Printf("查询搜索商品失败: %v", err)
+		return nil, err
+	}
+	if len(products) == 0 {
+		return nil, nil
+	}
+	return &products[0], nil
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"myapi/app/storage"
+	"myapi/app/structs"
+
+	"gorm.io/gorm"
+)
+
+// productImportColumns 导入文件可以修改的商品字段
+var productImportColumns = []string{
+	"name", "keyword", "cat_id", "model", "price", "original_price",
+	"quantity", "quantity_unit", "image", "sort", "status", "update_time",
+}
+
+// GetProductsForExport 获取站点未删除的商品用于导出，按ID升序，同时返回总数
+func GetProductsForExport(weid, limit int) ([]structs.Product, int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, 0, errors.New("数据库连接失败")
+	}
+
+	query := gormDB.Table(goodsTableName).Where("weid = ? AND delete_time = 0", weid)
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		log.Printf("查询导出商品总数失败: %v", err)
+		return nil, 0, err
+	}
+	var products []structs.Product
+	if err := query.Order("id ASC").Limit(limit).Find(&products).Error; err != nil {
+		log.Printf("查询导出商品失败: %v", err)
+		return nil, 0, err
+	}
+	return products, total, nil
+}
+
+// GetSkuValuesByGoodsIDs 批量获取商品的SKU，按商品ID分组
+func GetSkuValuesByGoodsIDs(goodsIDs []int) (map[int][]structs.GoodsSkuValue, error) {
+	result := make(map[int][]structs.GoodsSkuValue)
+	if len(goodsIDs) == 0 {
+		return result, nil
+	}
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var values []structs.GoodsSkuValue
+	if err := gormDB.Table(goodsSkuValueTableName).
+		Where("goods_id IN ?", goodsIDs).
+		Order("goods_id ASC, id ASC").
+		Find(&values).Error; err != nil {
+		log.Printf("批量查询商品SKU失败: %v", err)
+		return nil, err
+	}
+	for _, value := range values {
+		result[value.GoodsID] = append(result[value.GoodsID], value)
+	}
+	return result, nil
+}
+
+// FindProductsForImport 按商品ID或型号查找站点未删除的商品，型号重复时ID小的在前
+func FindProductsForImport(weid int, ids []int, productModels []string) ([]structs.Product, error) {
+	if len(ids) == 0 && len(productModels) == 0 {
+		return nil, nil
+	}
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	query := gormDB.Table(goodsTableName).Where("weid = ? AND delete_time = 0", weid)
+	switch {
+	case len(ids) > 0 && len(productModels) > 0:
+		query = query.Where("(id IN ? OR model IN ?)", ids, productModels)
+	case len(ids) > 0:
+		query = query.Where("id IN ?", ids)
+	default:
+		query = query.Where("model IN ?", productModels)
+	}
+	var products []structs.Product
+	if err := query.Order("id ASC").Find(&products).Error; err != nil {
+		log.Printf("查询导入商品失败: %v", err)
+		return nil, err
+	}
+	return products, nil
+}
+
+// SaveImportedProduct 在一个事务中保存导入的商品字段和SKU价格库存，SKU只修改已有记录
+func SaveImportedProduct(goods *structs.Product, values []structs.GoodsSkuValue) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		goods.UpdateTime = int(time.Now().Unix())
+		result := tx.Table(goodsTableName).
+			Where("id = ? AND weid = ? AND delete_time = 0", goods.ID, goods.Weid).
+			Select(productImportColumns).
+			Updates(goods)
+		if result.Error != nil {
+			log.Printf("保存导入商品失败: %v", result.Error)
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrProductNotFound
+		}
+
+		for _, value := range values {
+			if err := tx.Table(goodsSkuValueTableName).
+				Where("id = ? AND goods_id = ?", value.ID, goods.ID).
+				Updates(map[string]interface{}{
+					"price":    value.Price,
+					"quantity": value.Quantity,
+				}).Error; err != nil {
+				log.Printf("保存导入商品SKU失败: %v", err)
+				return err
+			}
+		}
+		return SyncStockStatus(tx, []int{goods.ID})
+	})
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"errors"
+	"log"
+
+	"myapi/app/storage"
+	"myapi/app/structs"
+
+	"gorm.io/gorm"
+)
+
+// incomeReportSource 收入报表的数据来源：收入明细表、代理或团长ID字段、代理或团长表
+type incomeReportSource struct {
+	logTable   string
+	idColumn   string
+	ownerTable string
+}
+
+// getIncomeReportSource 根据报表类型获取数据来源
+func getIncomeReportSource(kind string) (incomeReportSource, error) {
+	switch kind {
+	case structs.ReportKindOperatingcity:
+		return incomeReportSource{operatingcityIncomelogTableName, "ocid", operatingcityTableName}, nil
+	case structs.ReportKindTuanzhang:
+		return incomeReportSource{tuanzhangIncomeLogTableName, "tzid", tuanzhangTableName}, nil
+	}
+	return incomeReportSource{}, errors.New("不支持的报表类型")
+}
+
+// incomePeriodColumn 汇总周期对应的字段
+func incomePeriodColumn(period string) string {
+	if period == structs.ReportPeriodYear {
+		return "l.year_time"
+	}
+	return "l.month_time"
+}
+
+// incomeReportQuery 按条件筛选收入明细
+func incomeReportQuery(db *gorm.DB, src incomeReportSource, f structs.IncomeReportFilter) *gorm.DB {
+	query := db.Table(src.logTable+" AS l").Where("l.weid = ?", f.Weid)
+	if len(f.AccountIDs) > 0 {
+		query = query.Where("l."+src.idColumn+" IN ?", f.AccountIDs)
+	}
+	column := incomePeriodColumn(f.Period)
+	if f.From != "" {
+		query = query.Where(column+" >= ?", f.From)
+	}
+	if f.To != "" {
+		query = query.Where(column+" <= ?", f.To)
+	}
+	return query
+}
+
+// GetIncomeReport 按代理或团长和周期汇总收入、订单数和明细条数，limit不大于0时返回全部
+func GetIncomeReport(f structs.IncomeReportFilter, page, limit int) ([]structs.IncomeReport, int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, 0, errors.New("数据库连接失败")
+	}
+	src, err := getIncomeReportSource(f.Kind)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	column := incomePeriodColumn(f.Period)
+	grouped := incomeReportQuery(gormDB, src, f).
+		Select("l." + src.idColumn + " AS account_id, MAX(o.title) AS title, " + column + " AS period, " +
+			"SUM(l.income) AS income, COUNT(DISTINCT l.order_id) AS orders, COUNT(*) AS `lines`").
+		Joins("LEFT JOIN " + src.ownerTable + " AS o ON o.id = l." + src.idColumn).
+		Group("l." + src.idColumn + ", " + column)
+
+	var total int64
+	if err := gormDB.Table("(?) AS t", grouped).Count(&total).Error; err != nil {
+		log.Printf("统计收入报表行数失败: %v", err)
+		return nil, 0, err
+	}
+
+	query := grouped.Order("period DESC, account_id ASC")
+	if limit > 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+	var list []structs.IncomeReport
+	if err := query.Find(&list).Error; err != nil {
+		log.Printf("查询收入报表失败: %v", err)
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// GetIncomeReportTotal 汇总筛选范围内的收入合计
+func GetIncomeReportTotal(f structs.IncomeReportFilter) (*structs.IncomeReportTotal, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+	src, err := getIncomeReportSource(f.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	var total structs.IncomeReportTotal
+	if err := incomeReportQuery(gormDB, src, f).
+		Select("COALESCE(SUM(l.income), 0) AS income, COUNT(DISTINCT l.order_id) AS orders, COUNT(*) AS `lines`").
+		Scan(&total).Error; err != nil {
+		log.Printf("汇总收入报表合计失败: %v", err)
+		return nil, err
+	}
+	return &total, nil
+}
+
+// GetOperatingcityIncomeLines 分页获取城市代理收入明细，limit不大于0时返回全部
+func GetOperatingcityIncomeLines(f structs.IncomeReportFilter, page, limit int) ([]structs.OperatingcityIncomelog, int64, error) {
+	var list []structs.OperatingcityIncomelog
+	total, err := findIncomeLines(f, page, limit, &list)
+	return list, total, err
+}
+
+// GetTuanzhangIncomeLines 分页获取团长收入明细，limit不大于0时返回全部
+func GetTuanzhangIncomeLines(f structs.IncomeReportFilter, page, limit int) ([]structs.TuanzhangIncomeLog, int64, error) {
+	var list []structs.TuanzhangIncomeLog
+	total, err := findIncomeLines(f, page, limit, &list)
+	return list, total, err
+}
+
+// findIncomeLines 按条件查询收入明细
+func findIncomeLines(f structs.IncomeReportFilter, page, limit int, list interface{}) (int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return 0, errors.New("数据库连接失败")
+	}
+	src, err := getIncomeReportSource(f.Kind)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	query := incomeReportQuery(gormDB, src, f)
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("查询收入明细数量失败: %v", err)
+		return 0, err
+	}
+	query = query.Select("l.*").Order("l.id DESC")
+	if limit > 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+	if err := query.Find(list).Error; err != nil {
+		log.Printf("查询收入明细失败: %v", err)
+		return 0, err
+	}
+	return total, nil
+}
@@ -7,22 +7,31 @@ import (
 // 全局变量存储完整表名
 var (
 	// 商品相关表
-	goodsTableName                string
-	goodsBuynowinfoTableName      string
-	goodsSkuTableName             string
-	goodsSkuValueTableName        string
-	goodsImageTableName           string
-	goodsDescriptionTableName     string
-	goodsDiscountTableName        string
-	categoryTableName             string
-	miaoshaGoodsTableName         string
-	tuanGoodsTableName            string
-	MiaoshaGoodsSkuValueTableName string
-	TuanGoodsSkuValueTableName    string
+	goodsTableName                 string
+	goodsBuynowinfoTableName       string
+	goodsSkuTableName              string
+	goodsSkuValueTableName         string
+	goodsImageTableName            string
+	goodsDescriptionTableName      string
+	goodsDiscountTableName         string
+	goodsCombinationTableName      string
+	categoryTableName              string
+	goodsToCategoryTableName       string
+	goodsStockReservationTableName string
+	goodsStockLedgerTableName      string
+	miaoshaGoodsTableName          string
+	miaoshaTimeTableName           string
+	tuanGoodsTableName             string
+	MiaoshaGoodsSkuValueTableName  string
+	TuanGoodsSkuValueTableName     string
 
 	// 团购相关表
-	tuanFoundTableName  string
-	tuanFollowTableName string
+	tuanFoundTableName          string
+	tuanFollowTableName         string
+	tuanLuckyDrawTableName      string
+	tuanzhangTableName          string
+	tuanzhangLevelTableName     string
+	tuanzhangIncomeLogTableName string
 
 	// 其他表
 	adTableName                     string
@@ -30,11 +39,25 @@ var (
 	memberTableName                 string
 	operatingcityTableName          string
 	orderTableName                  string
+	orderGoodsTableName             string
+	orderGoodsCombinationTableName  string
 	userTableName                   string
 	usersRelationTableName          string
 	uuidRelationTableName           string
 	operatingcityIncomelogTableName string
+	operatingcityLevelTableName     string
+	memberCommissionTableName       string
+	memberReferralTableName         string
 	tuanGoodsSkuValueTableName      string
+
+	// 钱包相关表
+	memberWalletLedgerTableName string
+	memberRechargeTableName     string
+	withdrawTableName           string
+
+	// 运费相关表
+	transportTableName       string
+	transportExtendTableName string
 )
 
 // init函数在包初始化时执行，配置表前缀
@@ -50,14 +73,23 @@ func init() {
 	goodsImageTableName = tablePrefix + "goods_image"
 	goodsDescriptionTableName = tablePrefix + "goods_description"
 	goodsDiscountTableName = tablePrefix + "goods_discount"
+	goodsCombinationTableName = tablePrefix + "goods_combination"
 	categoryTableName = tablePrefix + "category"
+	goodsToCategoryTableName = tablePrefix + "goods_to_category"
+	goodsStockReservationTableName = tablePrefix + "goods_stock_reservation"
+	goodsStockLedgerTableName = tablePrefix + "goods_stock_ledger"
 	miaoshaGoodsTableName = tablePrefix + "miaosha_goods"
+	miaoshaTimeTableName = tablePrefix + "miaosha_time"
 	tuanGoodsTableName = tablePrefix + "tuan_goods"
 	MiaoshaGoodsSkuValueTableName = tablePrefix + "miaosha_goods_sku_value"
 
 	// 团购相关表
 	tuanFoundTableName = tablePrefix + "tuan_found"
 	tuanFollowTableName = tablePrefix + "tuan_follow"
+	tuanLuckyDrawTableName = tablePrefix + "tuan_lucky_draw"
+	tuanzhangTableName = tablePrefix + "tuanzhang"
+	tuanzhangLevelTableName = tablePrefix + "tuanzhang_level"
+	tuanzhangIncomeLogTableName = tablePrefix + "tuanzhang_incomelog"
 	tuanGoodsSkuValueTableName = tablePrefix + "tuan_goods_sku_value"
 
 	// 其他表
@@ -66,8 +98,22 @@ func init() {
 	memberTableName = tablePrefix + "member"
 	operatingcityTableName = tablePrefix + "operatingcity"
 	orderTableName = tablePrefix + "order"
+	orderGoodsTableName = tablePrefix + "order_goods"
+	orderGoodsCombinationTableName = tablePrefix + "order_goods_combination"
 	userTableName = tablePrefix + "user"
 	usersRelationTableName = tablePrefix + "users_relation"
 	uuidRelationTableName = tablePrefix + "uuid_relation"
 	operatingcityIncomelogTableName = tablePrefix + "operatingcity_incomelog"
+	operatingcityLevelTableName = tablePrefix + "operatingcity_level"
+	memberCommissionTableName = tablePrefix + "member_commission"
+	memberReferralTableName = tablePrefix + "member_referral"
+
+	// 钱包相关表
+	memberWalletLedgerTableName = tablePrefix + "member_wallet_ledger"
+	memberRechargeTableName = tablePrefix + "member_recharge"
+	withdrawTableName = tablePrefix + "withdraw"
+
+	// 运费相关表
+	transportTableName = tablePrefix + "transport"
+	transportExtendTableName = tablePrefix + "transport_extend"
 }
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"errors"
+	"log"
+
+	"myapi/app/storage"
+	"myapi/app/structs"
+
+	"gorm.io/gorm"
+)
+
+// GetTransportByID 根据ID获取运费模板，不存在时返回nil
+func GetTransportByID(id int) (*structs.Transport, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var transport structs.Transport
+	result := gormDB.Table(transportTableName).Where("id = ? AND status = ?", id, 1).First(&transport)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Printf("查询运费模板失败: %v", result.Error)
+		return nil, result.Error
+	}
+	return &transport, nil
+}
+
+// GetDefaultTransport 获取店铺默认运费模板，不存在时返回nil
+func GetDefaultTransport(weid, sid int) (*structs.Transport, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var transport structs.Transport
+	result := gormDB.Table(transportTableName).
+		Where("weid = ? AND sid = ? AND is_default = ? AND status = ?", weid, sid, 1, 1).
+		Order("sort ASC, id ASC").
+		First(&transport)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Printf("查询店铺默认运费模板失败: %v", result.Error)
+		return nil, result.Error
+	}
+	return &transport, nil
+}
+
+// GetTransportExtends 获取运费模板的地区运费规则
+func GetTransportExtends(transportID int) ([]structs.TransportExtend, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var rules []structs.TransportExtend
+	result := gormDB.Table(transportExtendTableName).
+		Where("transport_id = ? AND status = ?", transportID, 1).
+		Order("sort ASC, id ASC").
+		Find(&rules)
+	if result.Error != nil {
+		log.Printf("查询运费模板地区规则失败: %v", result.Error)
+		return nil, result.Error
+	}
+	return rules, nil
+}
@@ -0,0 +1,584 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"myapi/app/helper"
+	"myapi/app/storage"
+	"myapi/app/structs"
+
+	"gorm.io/gorm"
+)
+
+// ErrTuanFoundClosed 拼团已结束、已满员或已过期
+var ErrTuanFoundClosed = errors.New("该团已结束或已满员")
+
+// CreateTuanFound 开团，同时写入团长的参团记录，团长支付后开团才对其他人可见
+func CreateTuanFound(tuanGoods *structs.TuanGoods, member *structs.Member) (*structs.TuanFound, *structs.TuanFollow, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, nil, errors.New("数据库连接失败")
+	}
+
+	now := int(time.Now().Unix())
+	found := &structs.TuanFound{
+		Weid:         tuanGoods.Weid,
+		Sid:          tuanGoods.Sid,
+		Ocid:         tuanGoods.Ocid,
+		Sn:           helper.DoOrderSn("T"),
+		FoundTime:    now,
+		FoundEndTime: now + tuanGoods.TimeLimit*3600,
+		Uid:          member.ID,
+		TuanID:       tuanGoods.ID,
+		Nickname:     member.Nickname,
+		Avatar:       member.Userpic,
+		Join:         0,
+		Need:         tuanGoods.PeopleNum,
+		Status:       structs.TuanFoundPending,
+	}
+	follow := &structs.TuanFollow{
+		Uid:      member.ID,
+		Nickname: member.Nickname,
+		Avatar:   member.Userpic,
+		JoinTime: now,
+		TuanID:   tuanGoods.ID,
+		IsHead:   1,
+		Status:   structs.TuanFollowUnpaid,
+	}
+
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(tuanFoundTableName).Create(found).Error; err != nil {
+			return err
+		}
+		follow.FoundID = found.ID
+		return tx.Table(tuanFollowTableName).Create(follow).Error
+	})
+	if err != nil {
+		log.Printf("开团失败: %v", err)
+		return nil, nil, err
+	}
+	return found, follow, nil
+}
+
+// GetTuanFollow 获取会员在开团中的参团记录，不存在时返回nil
+func GetTuanFollow(foundID, uid int) (*structs.TuanFollow, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var follow structs.TuanFollow
+	result := gormDB.Table(tuanFollowTableName).
+		Where("found_id = ? AND uid = ?", foundID, uid).
+		Order("id DESC").
+		Limit(1).
+		Find(&follow)
+	if result.Error != nil {
+		log.Printf("查询参团记录失败: %v", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &follow, nil
+}
+
+// CreateTuanFollow 写入参团记录，支付后才占用名额
+func CreateTuanFollow(found *structs.TuanFound, member *structs.Member) (*structs.TuanFollow, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	follow := &structs.TuanFollow{
+		Uid:      member.ID,
+		Nickname: member.Nickname,
+		Avatar:   member.Userpic,
+		JoinTime: int(time.Now().Unix()),
+		FoundID:  found.ID,
+		TuanID:   found.TuanID,
+		Status:   structs.TuanFollowUnpaid,
+	}
+	if err := gormDB.Table(tuanFollowTableName).Create(follow).Error; err != nil {
+		log.Printf("写入参团记录失败: %v", err)
+		return nil, err
+	}
+	return follow, nil
+}
+
+// PayTuanFollow 参团订单支付后占用名额，名额用条件更新原子扣减，最后一个名额支付后拼团成功
+// 团已结束、已过期或已满员时返回ErrTuanFoundClosed，由调用方退款
+func PayTuanFollow(order *structs.Order) (*structs.TuanFound, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var found structs.TuanFound
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(tuanFoundTableName).Where("id = ?", order.TuanFoundID).First(&found).Error; err != nil {
+			return errors.New("开团记录不存在")
+		}
+
+		now := int(time.Now().Unix())
+		updates := map[string]interface{}{
+			"join":   gorm.Expr("`join` + 1"),
+			"need":   gorm.Expr("need - 1"),
+			"status": structs.TuanFoundOngoing,
+		}
+		if order.UID == found.Uid {
+			updates["pay_time"] = now
+		}
+		result := tx.Table(tuanFoundTableName).
+			Where("id = ? AND need > 0 AND found_end_time > ?", found.ID, now).
+			Where("status IN (?,?)", structs.TuanFoundPending, structs.TuanFoundOngoing).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTuanFoundClosed
+		}
+
+		// 没有通过参团接口的订单补写参团记录
+		var follow structs.TuanFollow
+		query := tx.Table(tuanFollowTableName).
+			Where("found_id = ? AND uid = ? AND status = ?", found.ID, order.UID, structs.TuanFollowUnpaid).
+			Order("id DESC").
+			Limit(1).
+			Find(&follow)
+		if query.Error != nil {
+			return query.Error
+		}
+		if query.RowsAffected == 0 {
+			follow = structs.TuanFollow{
+				Uid:      order.UID,
+				JoinTime: now,
+				FoundID:  found.ID,
+				TuanID:   found.TuanID,
+			}
+			if order.UID == found.Uid {
+				follow.IsHead = 1
+			}
+			if err := tx.Table(tuanFollowTableName).Create(&follow).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Table(tuanFollowTableName).Where("id = ?", follow.ID).Updates(map[string]interface{}{
+			"order_id": order.ID,
+			"pay_time": now,
+			"status":   structs.TuanFollowPaid,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Table(tuanFoundTableName).Where("id = ?", found.ID).First(&found).Error; err != nil {
+			return err
+		}
+		if found.Need > 0 {
+			return nil
+		}
+		return completeTuanFound(tx, &found, now)
+	})
+	if err != nil {
+		if err != ErrTuanFoundClosed {
+			log.Printf("参团支付处理失败: %v", err)
+		}
+		return nil, err
+	}
+	return &found, nil
+}
+
+// completeTuanFound 满员后标记拼团成功，已支付的参团记录一起标记成功
+func completeTuanFound(tx *gorm.DB, found *structs.TuanFound, now int) error {
+	if err := tx.Table(tuanFoundTableName).
+		Where("id = ? AND status = ?", found.ID, structs.TuanFoundOngoing).
+		Updates(map[string]interface{}{
+			"status":        structs.TuanFoundSuccess,
+			"tuan_end_time": now,
+		}).Error; err != nil {
+		return err
+	}
+	found.Status = structs.TuanFoundSuccess
+	found.TuanEndTime = now
+
+	if err := tx.Table(tuanFollowTableName).
+		Where("found_id = ? AND status = ?", found.ID, structs.TuanFollowPaid).
+		Updates(map[string]interface{}{
+			"status":        structs.TuanFollowSuccess,
+			"tuan_end_time": now,
+		}).Error; err != nil {
+		return err
+	}
+	return tx.Table(tuanGoodsTableName).
+		Where("id = ?", found.TuanID).
+		Update("sale_count", gorm.Expr("sale_count + ?", found.Join)).Error
+}
+
+// GetExpiredTuanFoundIDs 获取已到结束时间仍未成团的开团ID
+func GetExpiredTuanFoundIDs(limit int) ([]int, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var ids []int
+	err := gormDB.Table(tuanFoundTableName).
+		Where("status IN (?,?) AND found_end_time <= ?", structs.TuanFoundPending, structs.TuanFoundOngoing, time.Now().Unix()).
+		Order("found_end_time ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil {
+		log.Printf("查询过期开团失败: %v", err)
+		return nil, err
+	}
+	return ids, nil
+}
+
+// FailTuanFound 把过期未成团的开团标记为失败，未完成的参团记录一起标记失败，退款由RefundTuanFollow处理
+func FailTuanFound(id int) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	now := int(time.Now().Unix())
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Table(tuanFoundTableName).
+			Where("id = ? AND found_end_time <= ?", id, now).
+			Where("status IN (?,?)", structs.TuanFoundPending, structs.TuanFoundOngoing).
+			Updates(map[string]interface{}{
+				"status":        structs.TuanFoundFailed,
+				"tuan_end_time": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return tx.Table(tuanFollowTableName).
+			Where("found_id = ? AND status IN (?,?)", id, structs.TuanFollowUnpaid, structs.TuanFollowPaid).
+			Updates(map[string]interface{}{
+				"status":        structs.TuanFollowFailed,
+				"tuan_end_time": now,
+			}).Error
+	})
+}
+
+// GetUnrefundedTuanFollows 获取拼团失败或抽奖未中奖、已支付但尚未退款的参团记录，机器人不退款
+func GetUnrefundedTuanFollows(limit int) ([]structs.TuanFollow, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var follows []structs.TuanFollow
+	err := gormDB.Table(tuanFollowTableName).
+		Where("(status = ? OR lucky_status = ?) AND is_refund = 0 AND is_robot = 0", structs.TuanFollowFailed, structs.TuanLuckyLoser).
+		Where("pay_time > 0 AND order_id > 0").
+		Order("id ASC").
+		Limit(limit).
+		Find(&follows).Error
+	if err != nil {
+		log.Printf("查询待退款参团记录失败: %v", err)
+		return nil, err
+	}
+	return follows, nil
+}
+
+// RefundTuanFollow 拼团失败或抽奖未中奖退款，订单金额退回余额并标记参团记录已退款
+func RefundTuanFollow(follow *structs.TuanFollow) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	remark := "拼团失败退款"
+	if follow.LuckyStatus == structs.TuanLuckyLoser {
+		remark = "拼团未中奖退款"
+	}
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := refundOrderToBalance(tx, follow.OrderID, remark); err != nil {
+			return err
+		}
+		return tx.Table(tuanFollowTableName).
+			Where("id = ? AND is_refund = 0", follow.ID).
+			Update("is_refund", 1).Error
+	})
+}
+
+// GetRobotFillTuanFounds 获取即将过期、开启机器人成团且缺少人数不超过机器人数量的开团
+func GetRobotFillTuanFounds(ahead int64, limit int) ([]structs.TuanFound, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	now := time.Now().Unix()
+	var founds []structs.TuanFound
+	err := gormDB.Table(tuanFoundTableName+" AS f").
+		Select("f.*").
+		Joins("JOIN "+tuanGoodsTableName+" AS g ON g.id = f.tuan_id").
+		Where("f.status = ? AND f.need > 0", structs.TuanFoundOngoing).
+		Where("f.found_end_time > ? AND f.found_end_time <= ?", now, now+ahead).
+		Where("g.auto_initiate = 1 AND f.need <= g.robot_num").
+		Order("f.found_end_time ASC").
+		Limit(limit).
+		Find(&founds).Error
+	if err != nil {
+		log.Printf("查询待机器人成团的开团失败: %v", err)
+		return nil, err
+	}
+	return founds, nil
+}
+
+// FillTuanRobots 用机器人补满开团并标记拼团成功，开团人数已变化时不补充
+func FillTuanRobots(found *structs.TuanFound, robots []structs.TuanFollow) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+	if len(robots) == 0 {
+		return nil
+	}
+
+	now := int(time.Now().Unix())
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Table(tuanFoundTableName).
+			Where("id = ? AND status = ? AND need = ? AND found_end_time > ?", found.ID, structs.TuanFoundOngoing, len(robots), now).
+			Updates(map[string]interface{}{
+				"join": gorm.Expr("`join` + ?", len(robots)),
+				"need": 0,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTuanFoundClosed
+		}
+
+		for i := range robots {
+			robots[i].FoundID = found.ID
+			robots[i].TuanID = found.TuanID
+			robots[i].IsRobot = 1
+			robots[i].JoinTime = now
+			robots[i].PayTime = now
+			robots[i].Status = structs.TuanFollowPaid
+		}
+		if err := tx.Table(tuanFollowTableName).Create(&robots).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Table(tuanFoundTableName).Where("id = ?", found.ID).First(found).Error; err != nil {
+			return err
+		}
+		return completeTuanFound(tx, found, now)
+	})
+}
+
+// GetPendingLuckyDrawFounds 获取已成团但尚未开奖的抽奖团
+func GetPendingLuckyDrawFounds(limit int) ([]structs.TuanFound, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var founds []structs.TuanFound
+	err := gormDB.Table(tuanFoundTableName+" AS f").
+		Select("f.*").
+		Joins("JOIN "+tuanGoodsTableName+" AS g ON g.id = f.tuan_id").
+		Joins("LEFT JOIN "+tuanLuckyDrawTableName+" AS d ON d.found_id = f.id").
+		Where("f.status = ? AND g.is_luckydraw = 1 AND d.id IS NULL", structs.TuanFoundSuccess).
+		Order("f.tuan_end_time ASC").
+		Limit(limit).
+		Find(&founds).Error
+	if err != nil {
+		log.Printf("查询待开奖的抽奖团失败: %v", err)
+		return nil, err
+	}
+	return founds, nil
+}
+
+// GetTuanDrawCandidates 获取抽奖团中参与抽奖的真实会员参团记录ID，机器人不参与抽奖
+func GetTuanDrawCandidates(foundID int) ([]int, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var ids []int
+	err := gormDB.Table(tuanFollowTableName).
+		Where("found_id = ? AND status = ? AND is_robot = 0", foundID, structs.TuanFollowSuccess).
+		Order("id ASC").
+		Pluck("id", &ids).Error
+	if err != nil {
+		log.Printf("查询抽奖候选参团记录失败: %v", err)
+		return nil, err
+	}
+	return ids, nil
+}
+
+// SaveTuanLuckyDraw 保存开奖记录并更新参团记录的中奖状态，同一开团只能开奖一次
+func SaveTuanLuckyDraw(draw *structs.TuanLuckyDraw, candidates, winners []int) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table(tuanLuckyDrawTableName).Where("found_id = ?", draw.FoundID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("该团已开奖")
+		}
+		if err := tx.Table(tuanLuckyDrawTableName).Create(draw).Error; err != nil {
+			log.Printf("保存开奖记录失败: %v", err)
+			return err
+		}
+
+		if len(winners) > 0 {
+			if err := tx.Table(tuanFollowTableName).
+				Where("id IN ?", winners).
+				Update("lucky_status", structs.TuanLuckyWin).Error; err != nil {
+				return err
+			}
+		}
+		winnerSet := make(map[int]bool, len(winners))
+		for _, id := range winners {
+			winnerSet[id] = true
+		}
+		losers := make([]int, 0, len(candidates))
+		for _, id := range candidates {
+			if !winnerSet[id] {
+				losers = append(losers, id)
+			}
+		}
+		if len(losers) == 0 {
+			return nil
+		}
+		return tx.Table(tuanFollowTableName).
+			Where("id IN ?", losers).
+			Update("lucky_status", structs.TuanLuckyLoser).Error
+	})
+}
+
+// GetOpenTuanFounds 分页获取团购商品正在拼团中的开团，差人数少、快结束的排在前面
+func GetOpenTuanFounds(tuanID int, page, limit int) ([]structs.TuanFoundBrief, int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, 0, errors.New("数据库连接失败")
+	}
+
+	var total int64
+	var list []structs.TuanFoundBrief
+	query := gormDB.Table(tuanFoundTableName+" AS f").
+		Joins("JOIN "+tuanGoodsTableName+" AS g ON g.id = f.tuan_id").
+		Where("f.tuan_id = ? AND f.status = ? AND f.need > 0 AND f.found_end_time > ?", tuanID, structs.TuanFoundOngoing, time.Now().Unix())
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("查询开团数量失败: %v", err)
+		return nil, 0, err
+	}
+	if err := query.Select("f.*, g.people_num").
+		Order("f.need ASC, f.found_end_time ASC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&list).Error; err != nil {
+		log.Printf("查询开团列表失败: %v", err)
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// GetTuanFoundBrief 获取开团记录和成团人数
+func GetTuanFoundBrief(id int) (*structs.TuanFoundBrief, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var found structs.TuanFoundBrief
+	result := gormDB.Table(tuanFoundTableName+" AS f").
+		Select("f.*, g.people_num").
+		Joins("JOIN "+tuanGoodsTableName+" AS g ON g.id = f.tuan_id").
+		Where("f.id = ?", id).
+		Limit(1).
+		Find(&found)
+	if result.Error != nil {
+		log.Printf("查询开团记录失败: %v", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("开团记录不存在")
+	}
+	return &found, nil
+}
+
+// GetTuanFollowers 获取开团中已支付的参团成员，团长排在最前
+func GetTuanFollowers(foundID int) ([]structs.TuanFollower, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var followers []structs.TuanFollower
+	err := gormDB.Table(tuanFollowTableName).
+		Select("nickname, avatar, is_head, pay_time").
+		Where("found_id = ? AND status IN (?,?)", foundID, structs.TuanFollowPaid, structs.TuanFollowSuccess).
+		Order("is_head DESC, pay_time ASC, id ASC").
+		Find(&followers).Error
+	if err != nil {
+		log.Printf("查询参团成员失败: %v", err)
+		return nil, err
+	}
+	return followers, nil
+}
+
+// GetMemberTuanFounds 分页获取会员参加的拼团，包括自己开的团
+func GetMemberTuanFounds(uid int, page, limit int) ([]structs.TuanMyFound, int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, 0, errors.New("数据库连接失败")
+	}
+
+	var total int64
+	var list []structs.TuanMyFound
+	query := gormDB.Table(tuanFollowTableName+" AS t").
+		Joins("JOIN "+tuanFoundTableName+" AS f ON f.id = t.found_id").
+		Joins("JOIN "+tuanGoodsTableName+" AS g ON g.id = f.tuan_id").
+		Where("t.uid = ? AND t.is_robot = 0", uid)
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("查询我的拼团数量失败: %v", err)
+		return nil, 0, err
+	}
+	if err := query.Select("t.id AS follow_id, t.found_id, f.tuan_id, t.order_id, g.title, g.people_num, " +
+		"t.is_head, t.is_refund, t.lucky_status, t.status AS follow_status, f.status AS found_status, " +
+		"f.`join`, f.need, f.found_end_time").
+		Order("t.id DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&list).Error; err != nil {
+		log.Printf("查询我的拼团失败: %v", err)
+		return nil, 0, err
+	}
+	return list, total, nil
+}
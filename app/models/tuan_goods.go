@@ -100,7 +100,7 @@ func GetTuanFoundByID(id int) *structs.TuanFound {
 	return tuanFound
 }
 
-// GetTuanFollowCountByFoundID 根据开团ID获取参团人数
+// GetTuanFollowCountByFoundID 根据开团ID获取已支付的参团人数
 func GetTuanFollowCountByFoundID(foundID int) (int64, error) {
 	// 获取共享的gorm连接实例
 	gormDB := storage.GetGormDB()
@@ -111,7 +111,7 @@ func GetTuanFollowCountByFoundID(foundID int) (int64, error) {
 	var count int64
 	result := gormDB.Table(tuanFollowTableName).
 		Where("found_id = ? ", foundID).
-		Where("status IN (?,?,?)", 0, 1, 2).
+		Where("status IN (?,?)", structs.TuanFollowPaid, structs.TuanFollowSuccess).
 		Count(&count)
 
 	if result.Error != nil {
@@ -121,8 +121,8 @@ func GetTuanFollowCountByFoundID(foundID int) (int64, error) {
 	return count, nil
 }
 
-// ValidateCanJoinTuan 验证是否可平团
-func ValidateCanJoinTuan(goodsID int64, tuanID int64, quantity int64, sku ...string) (*structs.TuanGoods, error) {
+// ValidateCanJoinTuan 验证是否可平团，foundID大于0时为参加已有的团
+func ValidateCanJoinTuan(goodsID int64, tuanID int64, foundID int64, quantity int64, sku ...string) (*structs.TuanGoods, error) {
 	// 1. 检查团购商品是否存在且有效
 	tuanGoods := GetTuanGoodsByGoodsIDAndTuanID(int(goodsID), int(tuanID))
 	if tuanGoods == nil {
@@ -138,24 +138,11 @@ func ValidateCanJoinTuan(goodsID int64, tuanID int64, quantity int64, sku ...str
 		return nil, errors.New("团购不在有效期内")
 	}
 
-	// 3. 检查开团记录是否存在
-	// tuanFound := GetTuanFoundByID(tuanID)
-	// if tuanFound == nil {
-	// 	return nil, errors.New("开团记录不存在")
-	// }
-
-	// // 4. 检查开团是否有效
-	// if tuanFound.Status != 1 {
-	// 	return nil, errors.New("开团已结束或无效")
-	// }
-
-	// 5. 检查是否已经人满
-	joinCount, err := GetTuanFollowCountByFoundID(int(tuanID))
-	if err != nil {
-		return nil, errors.New("获取参团人数失败")
-	}
-	if joinCount >= int64(tuanGoods.PeopleNum) {
-		return nil, errors.New("该团已经满员")
+	// 3. 参团时检查开团记录是否有效、是否已经满员
+	if foundID > 0 {
+		if _, err := ValidateTuanFound(int(foundID), int(tuanID)); err != nil {
+			return nil, err
+		}
 	}
 
 	// 4. 检查购买数量是否超过限制
@@ -208,3 +195,21 @@ func ValidateCanJoinTuan(goodsID int64, tuanID int64, quantity int64, sku ...str
 	// 所有验证通过
 	return tuanGoods, nil
 }
+
+// ValidateTuanFound 验证开团记录是否可以参加：未结束、未过期且还有名额
+func ValidateTuanFound(foundID int, tuanID int) (*structs.TuanFound, error) {
+	tuanFound := GetTuanFoundByID(foundID)
+	if tuanFound == nil || tuanFound.TuanID != tuanID {
+		return nil, errors.New("开团记录不存在")
+	}
+	if tuanFound.Status != structs.TuanFoundPending && tuanFound.Status != structs.TuanFoundOngoing {
+		return nil, errors.New("开团已结束或无效")
+	}
+	if int64(tuanFound.FoundEndTime) <= time.Now().Unix() {
+		return nil, errors.New("该团已过期")
+	}
+	if tuanFound.Need <= 0 {
+		return nil, errors.New("该团已经满员")
+	}
+	return tuanFound, nil
+}
@@ -0,0 +1,363 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"myapi/app/helper"
+	"myapi/app/storage"
+	"myapi/app/structs"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetTuanzhangByUid 获取会员的团长申请，不存在时返回nil
+func GetTuanzhangByUid(weid, uid int) (*structs.Tuanzhang, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var tuanzhang structs.Tuanzhang
+	result := gormDB.Table(tuanzhangTableName).
+		Where("weid = ? AND uid = ?", weid, uid).
+		Order("id DESC").
+		Limit(1).
+		Find(&tuanzhang)
+	if result.Error != nil {
+		log.Printf("查询团长信息失败: %v", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &tuanzhang, nil
+}
+
+// GetTuanzhangByID 根据ID获取团长
+func GetTuanzhangByID(id int) (*structs.Tuanzhang, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var tuanzhang structs.Tuanzhang
+	if err := gormDB.Table(tuanzhangTableName).Where("id = ?", id).First(&tuanzhang).Error; err != nil {
+		log.Printf("根据ID查询团长失败: %v", err)
+		return nil, errors.New("团长不存在")
+	}
+	return &tuanzhang, nil
+}
+
+// SaveTuanzhangApply 保存团长申请，被驳回的申请修改后重新进入待审核
+func SaveTuanzhangApply(tuanzhang *structs.Tuanzhang) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	tuanzhang.Status = structs.TuanzhangPending
+	if tuanzhang.ID == 0 {
+		tuanzhang.CreateTime = int(time.Now().Unix())
+		if tuanzhang.Uuid == "" {
+			tuanzhang.Uuid = helper.DoOrderSn("TZ")
+		}
+		if err := gormDB.Table(tuanzhangTableName).Create(tuanzhang).Error; err != nil {
+			log.Printf("创建团长申请失败: %v", err)
+			return err
+		}
+		return nil
+	}
+
+	result := gormDB.Table(tuanzhangTableName).
+		Where("id = ? AND status = ?", tuanzhang.ID, structs.TuanzhangRejected).
+		Select("title", "community_title", "tel", "id_cart", "imageurl1", "imageurl2", "imageurl3", "imageurl4",
+			"province_name", "city_name", "district_name", "region_name", "dizhi", "house_number",
+			"longitude", "latitude", "introduction", "status").
+		Updates(tuanzhang)
+	if result.Error != nil {
+		log.Printf("更新团长申请失败: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("申请正在审核或已通过")
+	}
+	return nil
+}
+
+// GetTuanzhangList 分页获取团长列表，status小于0时不按状态筛选
+func GetTuanzhangList(weid, status int, page, limit int) ([]structs.Tuanzhang, int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return nil, 0, errors.New("数据库连接失败")
+	}
+
+	var total int64
+	var list []structs.Tuanzhang
+	query := gormDB.Table(tuanzhangTableName).Where("weid = ?", weid)
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("查询团长数量失败: %v", err)
+		return nil, 0, err
+	}
+	if err := query.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&list).Error; err != nil {
+		log.Printf("查询团长列表失败: %v", err)
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// AuditTuanzhang 审核团长申请，通过时设置团长等级
+func AuditTuanzhang(id int, pass bool, level int, remark string) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return errors.New("数据库连接失败")
+	}
+
+	updates := map[string]interface{}{
+		"status":       structs.TuanzhangRejected,
+		"audit_remark": remark,
+		"audit_time":   time.Now().Unix(),
+	}
+	if pass {
+		updates["status"] = structs.TuanzhangApproved
+		updates["level"] = level
+	}
+	result := gormDB.Table(tuanzhangTableName).
+		Where("id = ? AND status = ?", id, structs.TuanzhangPending).
+		Updates(updates)
+	if result.Error != nil {
+		log.Printf("审核团长申请失败: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("团长申请不存在或已审核")
+	}
+	return nil
+}
+
+// SetTuanzhangLevel 调整团长等级
+func SetTuanzhangLevel(id, level int) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return errors.New("数据库连接失败")
+	}
+
+	result := gormDB.Table(tuanzhangTableName).Where("id = ?", id).Update("level", level)
+	if result.Error != nil {
+		log.Printf("调整团长等级失败: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("团长不存在或等级未变化")
+	}
+	return nil
+}
+
+// GetTuanzhangLevel 获取启用的团长等级，不存在时返回nil
+func GetTuanzhangLevel(id int) (*structs.TuanzhangLevel, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var level structs.TuanzhangLevel
+	result := gormDB.Table(tuanzhangLevelTableName).Where("id = ? AND status = 1", id).Limit(1).Find(&level)
+	if result.Error != nil {
+		log.Printf("查询团长等级失败: %v", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &level, nil
+}
+
+// GetTuanzhangLevels 获取站点启用的团长等级
+func GetTuanzhangLevels(weid int) ([]structs.TuanzhangLevel, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var levels []structs.TuanzhangLevel
+	if err := gormDB.Table(tuanzhangLevelTableName).
+		Where("weid = ? AND status = 1", weid).
+		Order("sort ASC, id ASC").
+		Find(&levels).Error; err != nil {
+		log.Printf("查询团长等级列表失败: %v", err)
+		return nil, err
+	}
+	return levels, nil
+}
+
+// CreateTuanzhangIncome 写入团长订单收入并累加团长收入，同一订单只结算一次
+// 收入受订单佣金总额上限约束，超出部分不再分配；订单不是已付款或已发货状态时不结算
+func CreateTuanzhangIncome(tuanzhang *structs.Tuanzhang, order *structs.Order, income, percent float64) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return errors.New("数据库连接失败")
+	}
+
+	now := time.Now()
+	incomeLog := structs.TuanzhangIncomeLog{
+		Weid:          order.Weid,
+		Tzid:          tuanzhang.ID,
+		Uuid:          tuanzhang.Uuid,
+		Level:         tuanzhang.Level,
+		OrderID:       order.ID,
+		Ptype:         order.Ptype,
+		OrderNumAlias: order.OrderNumAlias,
+		BuyerID:       order.UID,
+		Income:        income,
+		ReturnPercent: percent,
+		Percentremark: fmt.Sprintf("%.2f x %.2f%%", order.Total, percent),
+		Description:   "订单" + order.OrderNumAlias + "团长收入",
+		OrderTotal:    order.Total,
+		PayTime:       order.PayTime,
+		CreateTime:    int(now.Unix()),
+		MonthTime:     now.Format("2006-01"),
+		YearTime:      now.Format("2006"),
+		OrderStatusID: order.OrderStatusID,
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table(tuanzhangIncomeLogTableName).
+			Where("tzid = ? AND order_id = ?", tuanzhang.ID, order.ID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		// 订单已退款(如拼团失败)时不再分配
+		if ok, err := orderIncomeSettleable(tx, order.ID); err != nil || !ok {
+			return err
+		}
+
+		income, err := reserveOrderCommission(tx, order.ID, income, CommissionableAmount(order.Total, order.Freight))
+		if err != nil {
+			return err
+		}
+		if income <= 0 {
+			log.Printf("订单%d佣金已达上限，团长%d不再分配", order.ID, tuanzhang.ID)
+			return nil
+		}
+		incomeLog.Income = income
+
+		if err := tx.Table(tuanzhangIncomeLogTableName).Create(&incomeLog).Error; err != nil {
+			log.Printf("写入团长收入明细失败: %v", err)
+			return err
+		}
+		return tx.Table(tuanzhangTableName).
+			Where("id = ?", tuanzhang.ID).
+			Updates(map[string]interface{}{
+				"income":       gorm.Expr("income + ?", income),
+				"total_income": gorm.Expr("total_income + ?", income),
+			}).Error
+	})
+}
+
+// reverseTuanzhangIncome 订单退款时冲回团长收入，扣减团长收入并为每条收入明细写一条负数冲回明细，须在事务中调用
+// 团长已提现的收入不追回，扣减后收入可能为负数，后续收入先抵扣
+func reverseTuanzhangIncome(tx *gorm.DB, orderID int) error {
+	var logs []structs.TuanzhangIncomeLog
+	if err := tx.Table(tuanzhangIncomeLogTableName).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND income > 0 AND order_status_id <> ?", orderID, structs.OrderStatusRefunded).
+		Find(&logs).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	total := 0.0
+	for _, incomeLog := range logs {
+		if err := tx.Table(tuanzhangTableName).
+			Where("id = ?", incomeLog.Tzid).
+			Updates(map[string]interface{}{
+				"income":       gorm.Expr("income - ?", incomeLog.Income),
+				"total_income": gorm.Expr("total_income - ?", incomeLog.Income),
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Table(tuanzhangIncomeLogTableName).
+			Where("id = ?", incomeLog.ID).
+			Update("order_status_id", structs.OrderStatusRefunded).Error; err != nil {
+			return err
+		}
+
+		reversal := incomeLog
+		reversal.ID = 0
+		reversal.Income = -incomeLog.Income
+		reversal.Description = "订单" + incomeLog.OrderNumAlias + "退款冲回团长收入"
+		reversal.CreateTime = int(now.Unix())
+		reversal.MonthTime = now.Format("2006-01")
+		reversal.YearTime = now.Format("2006")
+		reversal.OrderStatusID = structs.OrderStatusRefunded
+		if err := tx.Table(tuanzhangIncomeLogTableName).Create(&reversal).Error; err != nil {
+			log.Printf("写入团长收入冲回明细失败: %v", err)
+			return err
+		}
+		total += incomeLog.Income
+	}
+	if total <= 0 {
+		return nil
+	}
+	return tx.Table(orderTableName).
+		Where("id = ?", orderID).
+		Update("commission_total", gorm.Expr("GREATEST(commission_total - ?, 0)", roundMoney(total))).Error
+}
+
+// GetTuanzhangIncomeLogs 分页获取团长收入明细
+func GetTuanzhangIncomeLogs(tzid int, page, limit int) ([]structs.TuanzhangIncomeLog, int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return nil, 0, errors.New("数据库连接失败")
+	}
+
+	var total int64
+	var list []structs.TuanzhangIncomeLog
+	query := gormDB.Table(tuanzhangIncomeLogTableName).Where("tzid = ?", tzid)
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("查询团长收入明细数量失败: %v", err)
+		return nil, 0, err
+	}
+	if err := query.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&list).Error; err != nil {
+		log.Printf("查询团长收入明细失败: %v", err)
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// GetTuanzhangMonthIncomes 按月汇总团长从fromMonth(含，格式2006-01)起的收入和订单数
+func GetTuanzhangMonthIncomes(tzid int, fromMonth string) ([]structs.TuanzhangMonthIncome, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var list []structs.TuanzhangMonthIncome
+	if err := gormDB.Table(tuanzhangIncomeLogTableName).
+		Select("month_time, SUM(income) AS income, COUNT(DISTINCT order_id) AS orders").
+		Where("tzid = ? AND month_time >= ?", tzid, fromMonth).
+		Group("month_time").
+		Order("month_time DESC").
+		Find(&list).Error; err != nil {
+		log.Printf("汇总团长月收入失败: %v", err)
+		return nil, err
+	}
+	return list, nil
+}
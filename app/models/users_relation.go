@@ -35,6 +35,32 @@ func GetAdminID(mid int, ptype string) (int, error) {
 	return relation.Uid, nil
 }
 
+// GetActiveAdminID 获取会员绑定的管理员ID，未绑定或管理员账号已禁用时返回0
+func GetActiveAdminID(mid int) (int, error) {
+	uid, err := GetAdminID(mid, "admin")
+	if err != nil || uid == 0 {
+		return 0, err
+	}
+
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		log.Println("GORM连接为空")
+		return 0, errors.New("数据库连接失败")
+	}
+
+	var count int64
+	if err := gormDB.Table(userTableName).
+		Where("id = ? AND weid = ? AND status = 1", uid, helper.GetWeid()).
+		Count(&count).Error; err != nil {
+		log.Printf("查询管理员状态失败: %v", err)
+		return 0, err
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return uid, nil
+}
+
 // GetReID 根据会员ID和类型获取关联ID
 func GetReID(mid int, ptype string) (int, error) {
 	gormDB := storage.GetGormDB()
@@ -0,0 +1,609 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"myapi/app/helper"
+	"myapi/app/storage"
+	"myapi/app/structs"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientBalance 余额不足
+var ErrInsufficientBalance = errors.New("余额不足")
+
+// walletEntry 一笔钱包业务，Debit为借方账户，Credit为贷方账户
+// 涉及代理或团长收入账户时由Ptype和AccountID指定账户
+type walletEntry struct {
+	Weid      int
+	Uid       int
+	BizType   string
+	BizID     int
+	Debit     string
+	Credit    string
+	Amount    float64
+	Remark    string
+	Ptype     string
+	AccountID int
+}
+
+// roundMoney 金额保留两位小数
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// changeMemberWallet 调整会员余额和冻结金额，返回调整后的余额和冻结金额
+// 使用条件更新作为乐观锁，保证并发扣款时余额和冻结金额都不会出现负数
+func changeMemberWallet(tx *gorm.DB, uid int, balanceDelta, freezeDelta float64) (float64, float64, error) {
+	result := tx.Table(memberTableName).
+		Where("id = ?", uid).
+		Where("balance + ? >= 0 AND freeze + ? >= 0", balanceDelta, freezeDelta).
+		Updates(map[string]interface{}{
+			"balance": gorm.Expr("balance + ?", balanceDelta),
+			"freeze":  gorm.Expr("freeze + ?", freezeDelta),
+		})
+	if result.Error != nil {
+		log.Printf("调整会员余额失败: %v", result.Error)
+		return 0, 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, 0, ErrInsufficientBalance
+	}
+
+	var member structs.Member
+	if err := tx.Table(memberTableName).Select("id, balance, freeze").Where("id = ?", uid).First(&member).Error; err != nil {
+		return 0, 0, err
+	}
+	return member.Balance, member.Freeze, nil
+}
+
+// postWalletEntry 按借贷方向调整会员或代理、团长账户并写入两条分录，须在事务中调用
+// 会员余额、代理和团长收入是平台的负债，借方表示减少，贷方表示增加
+func postWalletEntry(tx *gorm.DB, e walletEntry) error {
+	amount := roundMoney(e.Amount)
+	if amount <= 0 {
+		return errors.New("金额必须大于0")
+	}
+
+	deltas := map[string]float64{}
+	deltas[e.Debit] -= amount
+	deltas[e.Credit] += amount
+
+	// 分录上记录会员、代理或团长账户变动后的余额，平台账户不记录
+	after := map[string]float64{}
+	if deltas[structs.WalletAccountBalance] != 0 || deltas[structs.WalletAccountFreeze] != 0 {
+		balance, freeze, err := changeMemberWallet(tx, e.Uid, deltas[structs.WalletAccountBalance], deltas[structs.WalletAccountFreeze])
+		if err != nil {
+			return err
+		}
+		after[structs.WalletAccountBalance] = balance
+		after[structs.WalletAccountFreeze] = freeze
+	}
+	if deltas[structs.WalletAccountIncome] != 0 || deltas[structs.WalletAccountIncomeFreeze] != 0 {
+		income, freeze, err := changeIncomeAccount(tx, e.Ptype, e.AccountID, e.Uid, deltas[structs.WalletAccountIncome], deltas[structs.WalletAccountIncomeFreeze])
+		if err != nil {
+			return err
+		}
+		after[structs.WalletAccountIncome] = income
+		after[structs.WalletAccountIncomeFreeze] = freeze
+	}
+
+	txnNo := helper.DoOrderSn("W")
+	now := int(time.Now().Unix())
+	legs := []structs.MemberWalletLedger{
+		{Weid: e.Weid, Uid: e.Uid, TxnNo: txnNo, BizType: e.BizType, BizID: e.BizID, Account: e.Debit,
+			Direction: structs.LedgerDebit, Amount: amount, BalanceAfter: after[e.Debit], Remark: e.Remark, CreateTime: now},
+		{Weid: e.Weid, Uid: e.Uid, TxnNo: txnNo, BizType: e.BizType, BizID: e.BizID, Account: e.Credit,
+			Direction: structs.LedgerCredit, Amount: amount, BalanceAfter: after[e.Credit], Remark: e.Remark, CreateTime: now},
+	}
+	if err := tx.Table(memberWalletLedgerTableName).Create(&legs).Error; err != nil {
+		log.Printf("写入钱包流水失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetWalletLedger 分页获取会员钱包流水，只返回会员自己账户的分录
+func GetWalletLedger(uid int, page, limit int) ([]structs.MemberWalletLedger, int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return nil, 0, errors.New("数据库连接失败")
+	}
+
+	var total int64
+	var list []structs.MemberWalletLedger
+	query := gormDB.Table(memberWalletLedgerTableName).
+		Where("uid = ? AND account IN ?", uid, []string{structs.WalletAccountBalance, structs.WalletAccountFreeze})
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("查询钱包流水数量失败: %v", err)
+		return nil, 0, err
+	}
+	if err := query.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&list).Error; err != nil {
+		log.Printf("查询钱包流水失败: %v", err)
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// CreateRecharge 创建充值单
+func CreateRecharge(recharge *structs.MemberRecharge) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return errors.New("数据库连接失败")
+	}
+
+	if recharge.CreateTime == 0 {
+		recharge.CreateTime = int(time.Now().Unix())
+	}
+	result := gormDB.Table(memberRechargeTableName).Create(recharge)
+	if result.Error != nil {
+		log.Printf("创建充值单失败: %v", result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// CompleteRecharge 充值到账，支付通知可能重复到达，已到账的充值单直接返回成功
+func CompleteRecharge(rechargeSn, tradeNo string, paidAmount float64) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		var recharge structs.MemberRecharge
+		if err := tx.Table(memberRechargeTableName).Where("recharge_sn = ?", rechargeSn).First(&recharge).Error; err != nil {
+			log.Printf("查询充值单失败: %v", err)
+			return errors.New("充值单不存在")
+		}
+		if recharge.Status == 1 {
+			return nil
+		}
+		if math.Abs(recharge.Amount-paidAmount) > 0.001 {
+			return errors.New("支付金额与充值金额不一致")
+		}
+
+		result := tx.Table(memberRechargeTableName).
+			Where("id = ? AND status = 0", recharge.ID).
+			Updates(map[string]interface{}{
+				"status":   1,
+				"trade_no": tradeNo,
+				"pay_time": time.Now().Unix(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// 并发通知已经处理
+			return nil
+		}
+
+		return postWalletEntry(tx, walletEntry{
+			Weid:    recharge.Weid,
+			Uid:     recharge.Uid,
+			BizType: "recharge",
+			BizID:   recharge.ID,
+			Debit:   structs.WalletAccountCash,
+			Credit:  structs.WalletAccountBalance,
+			Amount:  recharge.Amount,
+			Remark:  "余额充值",
+		})
+	})
+}
+
+// PayOrderByBalance 使用余额支付订单
+func PayOrderByBalance(uid int, orderID int) (*structs.Order, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var order structs.Order
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(orderTableName).Where("id = ? AND uid = ?", orderID, uid).First(&order).Error; err != nil {
+			return errors.New("订单不存在")
+		}
+		if order.OrderStatusID != structs.OrderStatusUnpaid {
+			return errors.New("订单不是待付款状态")
+		}
+		if order.Total <= 0 {
+			return errors.New("订单金额无效")
+		}
+
+		now := int(time.Now().Unix())
+		result := tx.Table(orderTableName).
+			Where("id = ? AND order_status_id = ?", order.ID, structs.OrderStatusUnpaid).
+			Updates(map[string]interface{}{
+				"order_status_id": structs.OrderStatusPaid,
+				"payment_code":    "balance",
+				"pay_time":        now,
+				"update_time":     now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("订单状态已变更，请刷新后重试")
+		}
+
+		order.OrderStatusID = structs.OrderStatusPaid
+		order.PaymentCode = "balance"
+		order.PayTime = now
+		return postWalletEntry(tx, walletEntry{
+			Weid:    order.Weid,
+			Uid:     uid,
+			BizType: "pay",
+			BizID:   order.ID,
+			Debit:   structs.WalletAccountBalance,
+			Credit:  structs.WalletAccountOrder,
+			Amount:  order.Total,
+			Remark:  "余额支付订单" + order.OrderNumAlias,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// RefundOrderToBalance 把已支付订单的金额全额退回会员余额，已退款的订单直接返回
+// 暂未接入支付渠道原路退款，所有支付方式统一退到余额
+func RefundOrderToBalance(orderID int, remark string) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		return refundOrderToBalance(tx, orderID, remark)
+	})
+}
+
+// refundOrderToBalance 退款到余额，须在事务中调用
+func refundOrderToBalance(tx *gorm.DB, orderID int, remark string) error {
+	var order structs.Order
+	if err := tx.Table(orderTableName).Where("id = ?", orderID).First(&order).Error; err != nil {
+		return errors.New("订单不存在")
+	}
+	if order.OrderStatusID == structs.OrderStatusRefunded {
+		return nil
+	}
+	if order.OrderStatusID != structs.OrderStatusPaid && order.OrderStatusID != structs.OrderStatusShipped {
+		return errors.New("订单当前状态不能退款")
+	}
+
+	result := tx.Table(orderTableName).
+		Where("id = ? AND order_status_id = ?", order.ID, order.OrderStatusID).
+		Updates(map[string]interface{}{
+			"order_status_id": structs.OrderStatusRefunded,
+			"update_time":     int(time.Now().Unix()),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("订单状态已变更，请刷新后重试")
+	}
+	if err := reverseOrderCommissions(tx, order.ID); err != nil {
+		return err
+	}
+	if err := reverseOperatingcityIncome(tx, order.ID); err != nil {
+		return err
+	}
+	if err := reverseTuanzhangIncome(tx, order.ID); err != nil {
+		return err
+	}
+	if err := releaseOrderStock(tx, order.ID, "退款回补"); err != nil {
+		return err
+	}
+	if order.Total <= 0 {
+		return nil
+	}
+
+	return postWalletEntry(tx, walletEntry{
+		Weid:    order.Weid,
+		Uid:     order.UID,
+		BizType: "refund",
+		BizID:   order.ID,
+		Debit:   structs.WalletAccountOrder,
+		Credit:  structs.WalletAccountBalance,
+		Amount:  order.Total,
+		Remark:  remark + order.OrderNumAlias,
+	})
+}
+
+// FreezeBalance 冻结会员余额，如售后纠纷期间冻结相应金额
+func FreezeBalance(weid, uid int, amount float64, bizType string, bizID int, remark string) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		return postWalletEntry(tx, walletEntry{
+			Weid:    weid,
+			Uid:     uid,
+			BizType: bizType,
+			BizID:   bizID,
+			Debit:   structs.WalletAccountBalance,
+			Credit:  structs.WalletAccountFreeze,
+			Amount:  amount,
+			Remark:  remark,
+		})
+	})
+}
+
+// UnfreezeBalance 解冻会员余额
+func UnfreezeBalance(weid, uid int, amount float64, bizType string, bizID int, remark string) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		return postWalletEntry(tx, walletEntry{
+			Weid:    weid,
+			Uid:     uid,
+			BizType: bizType,
+			BizID:   bizID,
+			Debit:   structs.WalletAccountFreeze,
+			Credit:  structs.WalletAccountBalance,
+			Amount:  amount,
+			Remark:  remark,
+		})
+	})
+}
+
+// CreateWithdraw 创建提现申请并冻结申请金额
+// 会员余额提现时金额从余额转入冻结，代理和团长提现时从可用收入转入冻结收入，驳回时退回
+func CreateWithdraw(withdraw *structs.Withdraw) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return errors.New("数据库连接失败")
+	}
+
+	now := int(time.Now().Unix())
+	withdraw.CreateTime = now
+	withdraw.UpdateTime = now
+	withdraw.Status = structs.WithdrawPending
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(withdrawTableName).Create(withdraw).Error; err != nil {
+			log.Printf("创建提现申请失败: %v", err)
+			return err
+		}
+		entry := walletEntry{
+			Weid:      withdraw.Weid,
+			Uid:       withdraw.Uid,
+			BizType:   "withdraw",
+			BizID:     withdraw.ID,
+			Debit:     structs.WalletAccountBalance,
+			Credit:    structs.WalletAccountFreeze,
+			Amount:    withdraw.Amount,
+			Remark:    "提现申请冻结",
+			Ptype:     withdraw.Ptype,
+			AccountID: withdraw.AccountID,
+		}
+		if withdraw.Ptype != structs.WithdrawPtypeMember {
+			entry.Debit = structs.WalletAccountIncome
+			entry.Credit = structs.WalletAccountIncomeFreeze
+		}
+		return postWalletEntry(tx, entry)
+	})
+}
+
+// changeIncomeAccount 调整代理或团长的可用收入和冻结收入，返回调整后的可用收入和冻结收入，须在事务中调用
+// 使用条件更新作为乐观锁，扣减后出现负数时返回ErrInsufficientBalance
+func changeIncomeAccount(tx *gorm.DB, ptype string, accountID, uid int, incomeDelta, freezeDelta float64) (float64, float64, error) {
+	var table string
+	switch ptype {
+	case structs.WithdrawPtypeOperatingcity:
+		table = operatingcityTableName
+	case structs.WithdrawPtypeTuanzhang:
+		table = tuanzhangTableName
+	default:
+		return 0, 0, errors.New("不支持的提现账户类型")
+	}
+
+	result := tx.Table(table).
+		Where("id = ? AND uid = ?", accountID, uid).
+		Where("income + ? >= 0 AND income_freeze + ? >= 0", incomeDelta, freezeDelta).
+		Updates(map[string]interface{}{
+			"income":        gorm.Expr("income + ?", incomeDelta),
+			"income_freeze": gorm.Expr("income_freeze + ?", freezeDelta),
+		})
+	if result.Error != nil {
+		log.Printf("调整代理或团长收入失败: %v", result.Error)
+		return 0, 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, 0, ErrInsufficientBalance
+	}
+
+	var account struct {
+		Income       float64 `gorm:"column:income"`
+		IncomeFreeze float64 `gorm:"column:income_freeze"`
+	}
+	if err := tx.Table(table).Select("income, income_freeze").Where("id = ?", accountID).Take(&account).Error; err != nil {
+		return 0, 0, err
+	}
+	return account.Income, account.IncomeFreeze, nil
+}
+
+// GetWithdrawByID 根据ID获取提现申请
+func GetWithdrawByID(id int) (*structs.Withdraw, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return nil, errors.New("数据库连接失败")
+	}
+
+	withdraw := &structs.Withdraw{}
+	if err := gormDB.Table(withdrawTableName).Where("id = ?", id).First(withdraw).Error; err != nil {
+		log.Printf("查询提现申请失败: %v", err)
+		return nil, err
+	}
+	withdraw.StatusText = helper.WithdrawStatus(withdraw.Status)
+	return withdraw, nil
+}
+
+// GetWithdrawList 分页获取提现申请，uid为0时不限会员，ptype为空时不限账户类型，status小于0时不限状态
+func GetWithdrawList(weid, uid int, ptype string, status int, page, limit int) ([]structs.Withdraw, int64, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return nil, 0, errors.New("数据库连接失败")
+	}
+
+	query := gormDB.Table(withdrawTableName).Where("weid = ?", weid)
+	if uid > 0 {
+		query = query.Where("uid = ?", uid)
+	}
+	if ptype != "" {
+		query = query.Where("ptype = ?", ptype)
+	}
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	var list []structs.Withdraw
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("查询提现申请数量失败: %v", err)
+		return nil, 0, err
+	}
+	if err := query.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&list).Error; err != nil {
+		log.Printf("查询提现申请失败: %v", err)
+		return nil, 0, err
+	}
+	for i := range list {
+		list[i].StatusText = helper.WithdrawStatus(list[i].Status)
+	}
+	return list, total, nil
+}
+
+// AuditWithdraw 审核提现申请，通过后等待批量打款，驳回时冻结的金额退回原账户
+func AuditWithdraw(id int, pass bool, remark string) error {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return errors.New("数据库连接失败")
+	}
+
+	return gormDB.Transaction(func(tx *gorm.DB) error {
+		var withdraw structs.Withdraw
+		if err := tx.Table(withdrawTableName).Where("id = ?", id).First(&withdraw).Error; err != nil {
+			return errors.New("提现申请不存在")
+		}
+		if withdraw.Status != structs.WithdrawPending {
+			return errors.New("提现申请已处理")
+		}
+
+		status := structs.WithdrawApproved
+		if !pass {
+			status = structs.WithdrawRejected
+		}
+		now := time.Now().Unix()
+		result := tx.Table(withdrawTableName).
+			Where("id = ? AND status = ?", withdraw.ID, structs.WithdrawPending).
+			Updates(map[string]interface{}{
+				"status":      status,
+				"remark":      remark,
+				"audit_time":  now,
+				"update_time": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("提现申请已处理")
+		}
+
+		entry := walletEntry{
+			Weid:      withdraw.Weid,
+			Uid:       withdraw.Uid,
+			BizID:     withdraw.ID,
+			Debit:     structs.WalletAccountFreeze,
+			Amount:    withdraw.Amount,
+			Ptype:     withdraw.Ptype,
+			AccountID: withdraw.AccountID,
+		}
+		refundAccount := structs.WalletAccountBalance
+		if withdraw.Ptype != structs.WithdrawPtypeMember {
+			entry.Debit = structs.WalletAccountIncomeFreeze
+			refundAccount = structs.WalletAccountIncome
+		}
+		if pass {
+			entry.BizType = "withdraw"
+			entry.Credit = structs.WalletAccountCash
+			entry.Remark = "提现打款"
+		} else {
+			entry.BizType = "withdraw_reject"
+			entry.Credit = refundAccount
+			entry.Remark = "提现驳回：" + remark
+		}
+		return postWalletEntry(tx, entry)
+	})
+}
+
+// CreateWithdrawBatch 把审核通过且未打款的提现申请编入新的打款批次，每批最多limit条
+func CreateWithdrawBatch(weid, limit int) (string, []structs.Withdraw, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return "", nil, errors.New("数据库连接失败")
+	}
+
+	batchNo := helper.BuildOrderNo("PB")
+	var list []structs.Withdraw
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(withdrawTableName).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("weid = ? AND status = ? AND batch_no = ''", weid, structs.WithdrawApproved).
+			Order("id ASC").
+			Limit(limit).
+			Find(&list).Error; err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return errors.New("没有待打款的提现申请")
+		}
+
+		ids := make([]int, len(list))
+		for i := range list {
+			ids[i] = list[i].ID
+			list[i].BatchNo = batchNo
+			list[i].StatusText = helper.WithdrawStatus(list[i].Status)
+		}
+		return tx.Table(withdrawTableName).
+			Where("id IN ? AND batch_no = ''", ids).
+			Updates(map[string]interface{}{"batch_no": batchNo, "update_time": time.Now().Unix()}).Error
+	})
+	if err != nil {
+		log.Printf("生成提现打款批次失败: %v", err)
+		return "", nil, err
+	}
+	return batchNo, list, nil
+}
+
+// GetWithdrawBatch 获取打款批次中的提现申请
+func GetWithdrawBatch(weid int, batchNo string) ([]structs.Withdraw, error) {
+	gormDB := storage.GetGormDB()
+	if gormDB == nil {
+		return nil, errors.New("数据库连接失败")
+	}
+
+	var list []structs.Withdraw
+	if err := gormDB.Table(withdrawTableName).
+		Where("weid = ? AND batch_no = ?", weid, batchNo).
+		Order("id ASC").
+		Find(&list).Error; err != nil {
+		log.Printf("查询提现打款批次失败: %v", err)
+		return nil, err
+	}
+	for i := range list {
+		list[i].StatusText = helper.WithdrawStatus(list[i].Status)
+	}
+	return list, nil
+}
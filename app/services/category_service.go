@@ -0,0 +1,220 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"myapi/app/config"
+	"myapi/app/helper"
+	"myapi/app/models"
+	"myapi/app/storage"
+	"myapi/app/structs"
+)
+
+// categoryTreeCache 分类树缓存内容，缓存站点启用的分类和商品数，筛选和组树在读取后进行
+type categoryTreeCache struct {
+	Categories []structs.Category `json:"categories"`
+	Counts     map[int]int        `json:"counts"`
+}
+
+// CategoryService 商品分类服务接口
+type CategoryService interface {
+	GetTree(weid, ptype, isShowHome int) ([]map[string]interface{}, error)
+	GetAdminTree(weid int) ([]map[string]interface{}, error)
+	GetByID(weid, id int) (*structs.Category, error)
+	Create(category *structs.Category) error
+	Update(category *structs.Category) error
+	Delete(weid, id int) error
+	Invalidate(weid int)
+}
+
+// categoryService 实现CategoryService接口的结构体
+type categoryService struct {
+	prefix string
+}
+
+// NewCategoryService 创建商品分类服务实例
+func NewCategoryService() CategoryService {
+	return &categoryService{prefix: config.GetString("redisPrefix", "")}
+}
+
+// GetTree 获取前台分类树，ptype大于0时只取该类型，isShowHome为0或1时只取对应的顶级分类
+func (s *categoryService) GetTree(weid, ptype, isShowHome int) ([]map[string]interface{}, error) {
+	cached, err := s.getCached(weid)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make([]structs.Category, 0, len(cached.Categories))
+	for _, category := range cached.Categories {
+		if ptype > 0 && category.Ptype != ptype {
+			continue
+		}
+		if category.Pid == 0 && isShowHome >= 0 && category.IsShowHome != isShowHome {
+			continue
+		}
+		categories = append(categories, category)
+	}
+	return BuildCategoryTree(categories, cached.Counts), nil
+}
+
+// GetAdminTree 获取后台分类树，包含停用的分类
+func (s *categoryService) GetAdminTree(weid int) ([]map[string]interface{}, error) {
+	categories, err := models.GetCategories(weid, false)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := models.GetCategoryGoodsCounts(weid)
+	if err != nil {
+		return nil, err
+	}
+	return BuildCategoryTree(categories, counts), nil
+}
+
+// GetByID 获取站点的单个分类
+func (s *categoryService) GetByID(weid, id int) (*structs.Category, error) {
+	category, err := models.GetCategoryByID(id)
+	if err != nil || category.Weid != weid {
+		return nil, models.ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+// Create 新增分类
+func (s *categoryService) Create(category *structs.Category) error {
+	if err := s.validate(category); err != nil {
+		return err
+	}
+	if err := models.CreateCategory(category); err != nil {
+		return err
+	}
+	s.Invalidate(category.Weid)
+	return nil
+}
+
+// Update 修改分类
+func (s *categoryService) Update(category *structs.Category) error {
+	if err := s.validate(category); err != nil {
+		return err
+	}
+	if err := models.UpdateCategory(category); err != nil {
+		return err
+	}
+	s.Invalidate(category.Weid)
+	return nil
+}
+
+// Delete 删除分类
+func (s *categoryService) Delete(weid, id int) error {
+	if err := models.DeleteCategory(weid, id); err != nil {
+		return err
+	}
+	s.Invalidate(weid)
+	return nil
+}
+
+// Invalidate 删除站点的分类树缓存，分类或商品变化后调用
+func (s *categoryService) Invalidate(weid int) {
+	if err := storage.DelCache(s.cacheKey(weid)); err != nil {
+		log.Printf("删除分类树缓存失败: %v", err)
+	}
+}
+
+// validate 校验分类的基本字段
+func (s *categoryService) validate(category *structs.Category) error {
+	category.Title = strings.TrimSpace(category.Title)
+	if category.Title == "" {
+		return errors.New("分类名称不能为空")
+	}
+	if category.Pid < 0 {
+		return errors.New("上级分类无效")
+	}
+	return nil
+}
+
+// cacheKey 分类树缓存键
+func (s *categoryService) cacheKey(weid int) string {
+	return fmt.Sprintf("%s:category:tree:%d", s.prefix, weid)
+}
+
+// getCached 读取站点启用的分类和商品数，未命中时查询数据库并写入缓存
+func (s *categoryService) getCached(weid int) (*categoryTreeCache, error) {
+	cacheKey := s.cacheKey(weid)
+	if cacheData, err := storage.GetCache(cacheKey); err == nil {
+		var cached categoryTreeCache
+		if err := json.Unmarshal([]byte(cacheData), &cached); err == nil {
+			return &cached, nil
+		}
+	}
+
+	categories, err := models.GetCategories(weid, true)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := models.GetCategoryGoodsCounts(weid)
+	if err != nil {
+		return nil, err
+	}
+	cached := &categoryTreeCache{Categories: categories, Counts: counts}
+	if data, err := json.Marshal(cached); err == nil {
+		ttl := time.Duration(config.GetInt("categoryTreeCacheTTL", 600)) * time.Second
+		storage.SetCache(cacheKey, string(data), ttl)
+	}
+	return cached, nil
+}
+
+// BuildCategoryTree 把分类列表组装为带children的树，goods_count为本分类商品数，goods_total为含下级分类的合计
+// 上级分类不在列表中的分类不会出现在树中
+func BuildCategoryTree(categories []structs.Category, counts map[int]int) []map[string]interface{} {
+	data := make([]map[string]interface{}, 0, len(categories))
+	for _, category := range categories {
+		data = append(data, categoryNode(category, counts[category.ID]))
+	}
+	tree := helper.GenerateListTree(data, 0, []string{"id", "pid"})
+	sumCategoryGoods(tree)
+	return tree
+}
+
+// categoryNode 把分类转换为树节点
+func categoryNode(category structs.Category, goodsCount int) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                 category.ID,
+		"weid":               category.Weid,
+		"pid":                category.Pid,
+		"title":              category.Title,
+		"is_binding":         category.IsBinding,
+		"is_ordercount":      category.IsOrdercount,
+		"is_storagelocation": category.IsStoragelocation,
+		"ptype":              category.Ptype,
+		"servicetime_ptype":  category.ServicetimePtype,
+		"deliverymode":       category.Deliverymode,
+		"submit_order_txt":   category.SubmitOrderTxt,
+		"ordergoodsremark":   category.Ordergoodsremark,
+		"order1remark":       category.Order1remark,
+		"image":              helper.ToImg(category.Image),
+		"meta_keyword":       category.MetaKeyword,
+		"meta_description":   category.MetaDescription,
+		"sort":               category.Sort,
+		"create_time":        category.CreateTime,
+		"update_time":        category.UpdateTime,
+		"status":             category.Status,
+		"is_show_home":       category.IsShowHome,
+		"goods_count":        goodsCount,
+	}
+}
+
+// sumCategoryGoods 自下而上累加各节点含下级分类的商品数，返回本层合计
+func sumCategoryGoods(nodes []map[string]interface{}) int {
+	total := 0
+	for _, node := range nodes {
+		children, _ := node["children"].([]map[string]interface{})
+		subtotal := node["goods_count"].(int) + sumCategoryGoods(children)
+		node["goods_total"] = subtotal
+		total += subtotal
+	}
+	return total
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"math"
+
+	"myapi/app/models"
+	"myapi/app/structs"
+)
+
+// AllocateCombinationRevenue 按组件价值（单价×每套数量）把套装收入分摊到各组件
+// 分摊金额保留两位小数，尾差计入最后一个组件，保证分摊合计等于total
+func AllocateCombinationRevenue(total float64, items []structs.CombinationItem) []structs.CombinationItem {
+	if len(items) == 0 {
+		return items
+	}
+
+	weights := make([]float64, len(items))
+	sum := 0.0
+	for i, item := range items {
+		weights[i] = item.Price * float64(item.Numbers)
+		sum += weights[i]
+	}
+	// 组件都没有价格时按数量分摊
+	if sum <= 0 {
+		sum = 0
+		for i, item := range items {
+			weights[i] = float64(item.Numbers)
+			sum += weights[i]
+		}
+	}
+
+	allocated := make([]structs.CombinationItem, len(items))
+	copy(allocated, items)
+	remain := total
+	for i := range allocated {
+		if i == len(allocated)-1 {
+			allocated[i].Amount = math.Round(remain*100) / 100
+			break
+		}
+		amount := math.Round(total*weights[i]/sum*100) / 100
+		allocated[i].Amount = amount
+		remain -= amount
+	}
+	return allocated
+}
+
+// CombinationStock 计算套装可售套数，取各扣减库存组件可组成套数的最小值，所有组件都不扣库存时返回-1
+func CombinationStock(items []structs.CombinationItem) int {
+	stock := -1
+	for _, item := range items {
+		if item.Subtract != 1 {
+			continue
+		}
+		sets := item.Quantity / item.Numbers
+		if stock < 0 || sets < stock {
+			stock = sets
+		}
+	}
+	return stock
+}
+
+// SaveOrderCombinationRevenue 订单支付后把套装订单商品的金额按组件分摊，并保存到订单商品的组件明细
+func SaveOrderCombinationRevenue(order *structs.Order) error {
+	goods, err := models.GetOrderGoodsByOrderID(order.ID)
+	if err != nil {
+		return err
+	}
+
+	var rows []structs.OrderGoodsCombination
+	for _, g := range goods {
+		product := models.GetProductByID(g.GoodsID)
+		if product == nil || product.IsCombination != 1 {
+			continue
+		}
+		items, err := models.GetCombinationItems(g.GoodsID)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, OrderCombinationRows(g, AllocateCombinationRevenue(g.Total, items))...)
+	}
+	return models.SaveOrderCombinationRevenue(order.ID, rows)
+}
+
+// OrderCombinationRows 把订单商品的组件分摊结果转换为组件明细，组件数量为每套数量×购买套数
+func OrderCombinationRows(g structs.OrderGoods, items []structs.CombinationItem) []structs.OrderGoodsCombination {
+	rows := make([]structs.OrderGoodsCombination, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, structs.OrderGoodsCombination{
+			Weid:         g.Weid,
+			OrderID:      g.OrderID,
+			OrderGoodsID: g.ID,
+			ParentID:     g.GoodsID,
+			GoodsID:      item.GoodsID,
+			Sku:          item.Sku,
+			Quantity:     item.Numbers * g.Quantity,
+			Price:        item.Price,
+			Amount:       item.Amount,
+		})
+	}
+	return rows
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"math"
+
+	"myapi/app/config"
+	"myapi/app/models"
+	"myapi/app/structs"
+)
+
+// ReferralRule 分销佣金规则，Rates依次为一级、二级、三级的百分比或每件固定金额
+type ReferralRule struct {
+	Method int
+	Rates  []float64
+}
+
+// CommissionService 分销佣金服务接口，订单支付后按推荐关系给上级会员分配佣金
+type CommissionService interface {
+	Distribute(order *structs.Order) error
+}
+
+// commissionService 实现CommissionService接口的结构体
+type commissionService struct{}
+
+// NewCommissionService 创建一个新的分销佣金服务实例
+func NewCommissionService() CommissionService {
+	return &commissionService{}
+}
+
+// Distribute 按订单商品给下单会员的上级(最多commissionLevels级)计算佣金，佣金先冻结，订单完成后解冻，退款时冲回
+func (s *commissionService) Distribute(order *structs.Order) error {
+	levels := config.GetInt("commissionLevels", 3)
+	if levels <= 0 {
+		return nil
+	}
+	if levels > 3 {
+		levels = 3
+	}
+	ancestors, err := models.GetMemberAncestors(order.UID, levels)
+	if err != nil || len(ancestors) == 0 {
+		return err
+	}
+	lines, err := models.GetOrderGoodsByOrderID(order.ID)
+	if err != nil {
+		return err
+	}
+
+	global := DefaultReferralRule()
+	var items []structs.MemberCommission
+	for _, line := range lines {
+		rule := global
+		if product := models.GetProductByID(line.GoodsID); product != nil {
+			rule = ProductReferralRule(global, product)
+		}
+		for i, uid := range ancestors {
+			level := i + 1
+			amount := CalculateReferralCommission(rule, level, line.Total, line.Quantity)
+			if amount <= 0 {
+				continue
+			}
+			items = append(items, structs.MemberCommission{
+				Uid:        uid,
+				GoodsID:    line.GoodsID,
+				Level:      level,
+				Method:     rule.Method,
+				Rate:       rule.Rates[i],
+				BaseAmount: line.Total,
+				Amount:     amount,
+			})
+		}
+	}
+	return models.CreateReferralCommissions(order, items)
+}
+
+// DefaultReferralRule 读取全局分销佣金规则
+func DefaultReferralRule() ReferralRule {
+	return ReferralRule{
+		Method: config.GetInt("commissionMethod", structs.CommissionMethodPercent),
+		Rates: []float64{
+			config.GetFloat("commissionLevel1", 0),
+			config.GetFloat("commissionLevel2", 0),
+			config.GetFloat("commissionLevel3", 0),
+		},
+	}
+}
+
+// ProductReferralRule 商品开启独立佣金时，以商品佣金作为一级佣金，二、三级按全局规则与一级的比例换算
+// 全局一级佣金为0时无法换算，只分配一级佣金
+func ProductReferralRule(global ReferralRule, product *structs.Product) ReferralRule {
+	if product.IsCommission != 1 {
+		return global
+	}
+	rule := ReferralRule{Method: product.CommissionMethod, Rates: make([]float64, len(global.Rates))}
+	for i := range rule.Rates {
+		switch {
+		case i == 0:
+			rule.Rates[i] = product.CommissionPrice
+		case global.Rates[0] > 0:
+			rule.Rates[i] = product.CommissionPrice * global.Rates[i] / global.Rates[0]
+		}
+	}
+	return rule
+}
+
+// CalculateReferralCommission 计算某一级的佣金，百分比按成交金额计算，固定金额按件数计算，保留两位小数
+func CalculateReferralCommission(rule ReferralRule, level int, total float64, quantity int) float64 {
+	if level <= 0 || level > len(rule.Rates) || rule.Rates[level-1] <= 0 {
+		return 0
+	}
+	rate := rule.Rates[level-1]
+	var amount float64
+	if rule.Method == structs.CommissionMethodFixed {
+		amount = rate * float64(quantity)
+	} else {
+		amount = total * rate / 100
+	}
+	if amount <= 0 {
+		return 0
+	}
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"myapi/app/config"
+	"myapi/app/models"
+	"myapi/app/structs"
+)
+
+// InventoryService 商品库存服务接口，立即购买预留、支付确认、超时或退款回补，每次变动都写入库存流水
+type InventoryService interface {
+	Reserve(weid, uid, recordID int, items []structs.StockItem) error
+	Commit(order *structs.Order) error
+	Release(orderID int, remark string) error
+	ReleaseExpired() (int, error)
+	Adjust(weid, goodsID int, sku string, change int, remark string) error
+	GetLedger(f structs.StockLedgerFilter, page, limit int) ([]structs.GoodsStockLedger, int64, error)
+	GetLowStock(weid, page, limit int) ([]structs.Product, int64, error)
+}
+
+// inventoryService 实现InventoryService接口的结构体
+type inventoryService struct{}
+
+// NewInventoryService 创建商品库存服务实例
+func NewInventoryService() InventoryService {
+	return &inventoryService{}
+}
+
+// Reserve 立即购买时按购买记录预留库存，任一商品库存不足时返回错误且不扣减任何库存
+// 预留在stockReserveTimeout秒内未支付时由后台任务释放；预留成功后检查库存是否降到提醒阈值或售罄，提醒失败只记录日志
+func (s *inventoryService) Reserve(weid, uid, recordID int, items []structs.StockItem) error {
+	expireTime := time.Now().Unix() + int64(config.GetInt("stockReserveTimeout", 1800))
+	entries, err := models.ReserveStock(weid, uid, recordID, int(expireTime), items)
+	if err != nil {
+		return err
+	}
+	// 沿用已有预留时库存没有变动，不清除详情缓存
+	if len(entries) == 0 {
+		return nil
+	}
+	invalidateItemsStockDetail(items)
+	if err := notifyStockAlerts(entries); err != nil {
+		log.Printf("购买记录%d检查库存提醒失败: %v", recordID, err)
+	}
+	return nil
+}
+
+// Commit 订单支付后把会员的库存预留关联到订单并确认，预留已超时重新扣减库存时同样检查库存提醒
+func (s *inventoryService) Commit(order *structs.Order) error {
+	goods, err := models.GetOrderGoodsByOrderID(order.ID)
+	if err != nil {
+		return err
+	}
+	entries, err := models.CommitOrderStock(order.Weid, order.UID, order.ID, models.OrderStockItems(goods))
+	if err != nil {
+		return err
+	}
+	invalidateOrderStockDetail(order.ID)
+	if err := notifyStockAlerts(entries); err != nil {
+		log.Printf("订单%d检查库存提醒失败: %v", order.ID, err)
+	}
+	return nil
+}
+
+// Release 订单退款时回补库存
+func (s *inventoryService) Release(orderID int, remark string) error {
+	if err := models.ReleaseOrderStock(orderID, remark); err != nil {
+		return err
+	}
+	invalidateOrderStockDetail(orderID)
+	return nil
+}
+
+// ReleaseExpired 释放超时未支付的库存预留，返回释放数量
+func (s *inventoryService) ReleaseExpired() (int, error) {
+	list, err := models.GetExpiredStockReservations(time.Now().Unix(), config.GetInt("stockReleaseBatchSize", 100))
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, reservation := range list {
+		if err := models.ReleaseStockReservation(reservation.ID, "超时未支付释放"); err != nil {
+			log.Printf("库存预留%d超时释放失败: %v", reservation.ID, err)
+			continue
+		}
+		invalidateStockDetail(reservation.GoodsID)
+		released++
+	}
+	return released, nil
+}
+
+// Adjust 后台调整商品或规格库存并写入流水，调整后检查库存提醒
+func (s *inventoryService) Adjust(weid, goodsID int, sku string, change int, remark string) error {
+	entry, err := models.AdjustStock(weid, goodsID, sku, change, remark)
+	if err != nil {
+		return err
+	}
+	invalidateStockDetail(goodsID)
+	if err := notifyStockAlerts([]structs.GoodsStockLedger{*entry}); err != nil {
+		log.Printf("商品%d检查库存提醒失败: %v", goodsID, err)
+	}
+	return nil
+}
+
+// GetLedger 分页查询库存流水
+func (s *inventoryService) GetLedger(f structs.StockLedgerFilter, page, limit int) ([]structs.GoodsStockLedger, int64, error) {
+	return models.GetStockLedger(f, page, limit)
+}
+
+// GetLowStock 分页查询库存达到提醒阈值的商品
+func (s *inventoryService) GetLowStock(weid, page, limit int) ([]structs.Product, int64, error) {
+	return models.GetLowStockProducts(weid, config.GetInt("stockLowThreshold", 0), page, limit)
+}
+
+// invalidateStockDetail 库存变动后清除商品详情缓存，详情中的商品和SKU库存才能及时更新
+func invalidateStockDetail(goodsIDs ...int) {
+	products := NewProductService()
+	for _, id := range goodsIDs {
+		products.InvalidateProductDetail(id)
+	}
+}
+
+// invalidateItemsStockDetail 清除库存项涉及的商品详情缓存，套装商品同时清除组件的缓存
+func invalidateItemsStockDetail(items []structs.StockItem) {
+	goodsIDs, err := models.GetStockGoodsIDs(items)
+	if err != nil {
+		log.Printf("清除商品详情缓存失败: %v", err)
+		return
+	}
+	invalidateStockDetail(goodsIDs...)
+}
+
+// invalidateOrderStockDetail 订单库存回补或确认后清除订单商品的详情缓存
+func invalidateOrderStockDetail(orderID int) {
+	goods, err := models.GetOrderGoodsByOrderID(orderID)
+	if err != nil {
+		log.Printf("订单%d清除商品详情缓存失败: %v", orderID, err)
+		return
+	}
+	invalidateItemsStockDetail(models.OrderStockItems(goods))
+}
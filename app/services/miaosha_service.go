@@ -0,0 +1,290 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"myapi/app/config"
+	"myapi/app/helper"
+	"myapi/app/models"
+	"myapi/app/storage"
+	"myapi/app/structs"
+)
+
+// MiaoshaService 秒杀场次服务接口
+type MiaoshaService interface {
+	GetSessions(weid int) ([]structs.MiaoshaSession, error)
+	GetSessionGoods(weid, id, page, limit int) (map[string]interface{}, error)
+	WarmUpcomingSessions() error
+}
+
+// miaoshaService 实现MiaoshaService接口的结构体
+type miaoshaService struct {
+	prefix string
+}
+
+// NewMiaoshaService 创建一个新的秒杀场次服务实例
+func NewMiaoshaService() MiaoshaService {
+	return &miaoshaService{prefix: config.GetString("redisPrefix", "")}
+}
+
+// GetSessions 获取当天的秒杀场次，时间段配置走缓存，状态和倒计时按当前时间实时计算
+func (s *miaoshaService) GetSessions(weid int) ([]structs.MiaoshaSession, error) {
+	slots, err := s.getSlots(weid)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sessions := make([]structs.MiaoshaSession, 0, len(slots))
+	for _, slot := range slots {
+		session, ok := BuildMiaoshaSession(slot, now)
+		if !ok {
+			log.Printf("秒杀时间段%d格式错误: %s-%s", slot.ID, slot.BeginTime, slot.EndTime)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].StartAt < sessions[j].StartAt
+	})
+	return sessions, nil
+}
+
+// GetSessionGoods 获取场次中的秒杀商品，商品列表走缓存，剩余库存和销量实时读取
+func (s *miaoshaService) GetSessionGoods(weid, id, page, limit int) (map[string]interface{}, error) {
+	slot, err := models.GetMiaoshaTimeByID(id)
+	if err != nil || slot.Weid != weid {
+		return nil, errors.New("秒杀场次不存在")
+	}
+	session, ok := BuildMiaoshaSession(*slot, time.Now())
+	if !ok {
+		return nil, errors.New("秒杀场次时间配置错误")
+	}
+
+	goods, err := s.getSessionGoods(weid, session)
+	if err != nil {
+		return nil, err
+	}
+
+	total := len(goods)
+	start := (page - 1) * limit
+	if start > total {
+		start = total
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+	list := goods[start:end]
+	if err := s.fillStock(list); err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Image = helper.ToImg(list[i].Image)
+	}
+
+	return map[string]interface{}{
+		"session": session,
+		"list":    list,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	}, nil
+}
+
+// WarmUpcomingSessions 在场次开始前预热商品列表缓存和秒杀库存
+func (s *miaoshaService) WarmUpcomingSessions() error {
+	slots, err := models.GetMiaoshaTimes(0)
+	if err != nil {
+		return err
+	}
+
+	ahead := int64(config.GetInt("miaoshaPreloadAhead", 300))
+	now := time.Now()
+	seckillService := NewSeckillService()
+	for _, slot := range slots {
+		session, ok := BuildMiaoshaSession(slot, now)
+		if !ok || session.Status == structs.MiaoshaSessionEnded {
+			continue
+		}
+		if session.Status == structs.MiaoshaSessionUpcoming && session.Countdown > ahead {
+			continue
+		}
+
+		goods, err := s.getSessionGoods(slot.Weid, session)
+		if err != nil {
+			log.Printf("预热秒杀场次%d失败: %v", slot.ID, err)
+			continue
+		}
+		for i := range goods {
+			if err := seckillService.Preload(&goods[i].MiaoshaGoods); err != nil {
+				log.Printf("预热秒杀活动%d库存失败: %v", goods[i].ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// getSlots 获取站点的秒杀时间段，缓存1分钟
+func (s *miaoshaService) getSlots(weid int) ([]structs.MiaoshaTime, error) {
+	cacheKey := fmt.Sprintf("%s:miaosha:slots:%d", s.prefix, weid)
+	if cacheData, err := storage.GetCache(cacheKey); err == nil {
+		var slots []structs.MiaoshaTime
+		if err := json.Unmarshal([]byte(cacheData), &slots); err == nil {
+			return slots, nil
+		}
+	}
+
+	slots, err := models.GetMiaoshaTimes(weid)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(slots); err == nil {
+		storage.SetCache(cacheKey, string(data), time.Minute)
+	}
+	return slots, nil
+}
+
+// getSessionGoods 获取场次商品列表，按场次日期缓存
+func (s *miaoshaService) getSessionGoods(weid int, session structs.MiaoshaSession) ([]structs.MiaoshaSessionGoods, error) {
+	day := time.Unix(session.StartAt, 0).Format("20060102")
+	cacheKey := fmt.Sprintf("%s:miaosha:session_goods:%d:%d:%s", s.prefix, weid, session.ID, day)
+	if cacheData, err := storage.GetCache(cacheKey); err == nil {
+		var goods []structs.MiaoshaSessionGoods
+		if err := json.Unmarshal([]byte(cacheData), &goods); err == nil {
+			return goods, nil
+		}
+	}
+
+	goods, err := models.GetMiaoshaGoodsInRange(weid, session.StartAt, session.EndAt)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(goods); err == nil {
+		ttl := time.Duration(config.GetInt("miaoshaSessionCacheTTL", 300)) * time.Second
+		storage.SetCache(cacheKey, string(data), ttl)
+	}
+	return goods, nil
+}
+
+// fillStock 填充剩余库存、销量和已售百分比，已预热的活动读Redis，否则读数据库
+func (s *miaoshaService) fillStock(list []structs.MiaoshaSessionGoods) error {
+	seckillService := NewSeckillService()
+	missing := make([]int, 0)
+	fromDB := make(map[int]bool)
+	for i := range list {
+		stock, sold, loaded := seckillService.GetStock(list[i].ID)
+		if !loaded {
+			missing = append(missing, list[i].ID)
+			fromDB[i] = true
+			continue
+		}
+		list[i].Stock = stock
+		list[i].Sold = sold
+		list[i].SoldPercent = SoldPercent(sold, stock)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	// 未预热的活动按数据库SKU库存统计，没有SKU的活动不限量
+	totals, err := models.GetMiaoshaStockTotals(missing)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		if !fromDB[i] {
+			continue
+		}
+		list[i].Stock = -1
+		if stock, ok := totals[list[i].ID]; ok {
+			list[i].Stock = stock
+		}
+		list[i].Sold = list[i].SaleCount
+		list[i].SoldPercent = SoldPercent(list[i].Sold, list[i].Stock)
+	}
+	return nil
+}
+
+// BuildMiaoshaSession 按时间段计算now所在日期的场次起止时间、状态和倒计时
+// 跨零点的时间段在次日凌晨仍属于前一天的场次
+func BuildMiaoshaSession(slot structs.MiaoshaTime, now time.Time) (structs.MiaoshaSession, bool) {
+	begin, ok1 := parseClock(slot.BeginTime)
+	end, ok2 := parseClock(slot.EndTime)
+	if !ok1 || !ok2 || begin == end {
+		return structs.MiaoshaSession{}, false
+	}
+	if end < begin {
+		end += 24 * 3600
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Unix()
+	startAt := midnight + int64(begin)
+	endAt := midnight + int64(end)
+	if endAt-24*3600 > now.Unix() {
+		startAt -= 24 * 3600
+		endAt -= 24 * 3600
+	}
+
+	session := structs.MiaoshaSession{
+		ID:        slot.ID,
+		BeginTime: slot.BeginTime,
+		EndTime:   slot.EndTime,
+		StartAt:   startAt,
+		EndAt:     endAt,
+	}
+	switch ts := now.Unix(); {
+	case ts >= endAt:
+		session.Status = structs.MiaoshaSessionEnded
+		session.StatusText = "已结束"
+	case ts >= startAt:
+		session.Status = structs.MiaoshaSessionOngoing
+		session.StatusText = "抢购中"
+		session.Countdown = endAt - ts
+	default:
+		session.Status = structs.MiaoshaSessionUpcoming
+		session.StatusText = "即将开始"
+		session.Countdown = startAt - ts
+	}
+	return session, true
+}
+
+// SoldPercent 计算已售百分比，不限量时返回0，售罄时返回100
+func SoldPercent(sold, stock int) int {
+	if stock < 0 {
+		return 0
+	}
+	if stock == 0 {
+		return 100
+	}
+	return sold * 100 / (sold + stock)
+}
+
+// parseClock 把HH、HH:MM或HH:MM:SS格式的时间转换为当天的秒数，允许24:00
+func parseClock(value string) (int, bool) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) == 0 || len(parts) > 3 {
+		return 0, false
+	}
+
+	seconds := 0
+	units := []int{3600, 60, 1}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (i > 0 && n >= 60) {
+			return 0, false
+		}
+		seconds += n * units[i]
+	}
+	if seconds > 24*3600 {
+		return 0, false
+	}
+	return seconds, true
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"log"
+
+	"myapi/app/helper"
+	"myapi/app/models"
+	"myapi/app/structs"
+)
+
+// OrderService 订单服务接口，处理订单状态变化后的后续业务
+type OrderService interface {
+	AfterPaid(order *structs.Order)
+	Complete(uid, orderID int) (*structs.Order, error)
+	Cancel(uid, orderID int) (*structs.Order, error)
+}
+
+// orderService 实现OrderService接口的结构体
+type orderService struct{}
+
+// NewOrderService 创建一个新的订单服务实例
+func NewOrderService() OrderService {
+	return &orderService{}
+}
+
+// AfterPaid 订单支付成功后的处理，各步骤失败只记录日志，不影响支付结果
+func (s *orderService) AfterPaid(order *structs.Order) {
+	// 确认立即购买时预留的库存
+	if err := NewInventoryService().Commit(order); err != nil {
+		log.Printf("订单%d确认库存预留失败: %v", order.ID, err)
+	}
+
+	// 秒杀订单确认库存预留，预留已超时释放且重新扣减时库存不足或超过限购的订单退款
+	if order.MsID > 0 {
+		if err := NewSeckillService().Confirm(order); err != nil {
+			log.Printf("订单%d确认秒杀库存失败: %v", order.ID, err)
+			if helper.ErrorCode(err) != helper.CodeError {
+				if err := s.refund(order, "秒杀库存不足退款"); err != nil {
+					log.Printf("订单%d秒杀库存不足退款失败: %v", order.ID, err)
+				} else {
+					return
+				}
+			}
+		}
+	}
+
+	// 套装订单商品按组件分摊收入
+	if err := SaveOrderCombinationRevenue(order); err != nil {
+		log.Printf("订单%d保存套装组件收入分摊失败: %v", order.ID, err)
+	}
+
+	// 拼团订单占用名额，满员后成团
+	if order.TuanFoundID > 0 {
+		if err := NewTuanService().OnOrderPaid(order); err != nil {
+			log.Printf("订单%d拼团处理失败: %v", order.ID, err)
+		}
+		// 拼团已结束时订单已退款，不再分配收入和佣金
+		if order.OrderStatusID == structs.OrderStatusRefunded {
+			return
+		}
+	}
+
+	// 按收货地区给省、市、区城市代理分配收入
+	if err := models.SetIncome(orderIncomeInfo(order)); err != nil {
+		log.Printf("订单%d结算城市代理收入失败: %v", order.ID, err)
+	}
+
+	// 团长订单按等级结算团长收入
+	if order.Tzid > 0 {
+		if err := NewTuanzhangService().SettleOrderIncome(order); err != nil {
+			log.Printf("订单%d结算团长收入失败: %v", order.ID, err)
+		}
+	}
+
+	// 按推荐关系给上级会员分配分销佣金，订单完成前冻结
+	if err := NewCommissionService().Distribute(order); err != nil {
+		log.Printf("订单%d分配分销佣金失败: %v", order.ID, err)
+	}
+}
+
+// Complete 会员确认收货，订单完成后冻结的分销佣金转入余额
+func (s *orderService) Complete(uid, orderID int) (*structs.Order, error) {
+	return models.CompleteOrder(uid, orderID)
+}
+
+// Cancel 会员取消待付款订单，释放还未支付的库存预留，秒杀订单同时释放秒杀预留
+func (s *orderService) Cancel(uid, orderID int) (*structs.Order, error) {
+	order, err := models.CancelOrder(uid, orderID, "会员取消订单")
+	if err != nil {
+		return nil, err
+	}
+	invalidateOrderStockDetail(order.ID)
+	if order.MsID > 0 {
+		if err := NewSeckillService().Release(order.UID, order.MsID); err != nil {
+			log.Printf("订单%d释放秒杀预留失败: %v", order.ID, err)
+		}
+	}
+	return order, nil
+}
+
+// refund 支付后处理失败时把订单金额退回余额，并把order的状态改为已退款
+func (s *orderService) refund(order *structs.Order, remark string) error {
+	if err := models.RefundOrderToBalance(order.ID, remark); err != nil {
+		return err
+	}
+	invalidateOrderStockDetail(order.ID)
+	order.OrderStatusID = structs.OrderStatusRefunded
+	return nil
+}
+
+// orderIncomeInfo 构建城市代理收入结算需要的订单信息
+func orderIncomeInfo(order *structs.Order) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                     order.ID,
+		"weid":                   order.Weid,
+		"uid":                    order.UID,
+		"total":                  order.Total,
+		"freight":                order.Freight,
+		"order_num_alias":        order.OrderNumAlias,
+		"pay_time":               order.PayTime,
+		"shipping_province_name": order.ShippingProvinceName,
+		"shipping_city_name":     order.ShippingCityName,
+		"shipping_district_name": order.ShippingDistrictName,
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"myapi/app/models"
+)
+
+// 支付单号前缀，支付通知按前缀分发到对应业务
+const (
+	rechargeSnPrefix = "RC"
+)
+
+// PayChannel 支付渠道接口，微信、支付宝等渠道实现后通过RegisterPayChannel注册
+type PayChannel interface {
+	// Prepay 统一下单，返回客户端调起支付需要的参数
+	Prepay(sn string, amount float64, subject string) (map[string]interface{}, error)
+	// VerifyNotify 校验支付结果通知，返回业务单号、渠道交易号和实付金额
+	VerifyNotify(r *http.Request) (sn string, tradeNo string, amount float64, err error)
+}
+
+var (
+	payChannels   = make(map[string]PayChannel)
+	payChannelsMu sync.RWMutex
+)
+
+// RegisterPayChannel 注册支付渠道，code与订单的payment_code一致
+func RegisterPayChannel(code string, channel PayChannel) {
+	payChannelsMu.Lock()
+	defer payChannelsMu.Unlock()
+	payChannels[code] = channel
+}
+
+// GetPayChannel 获取支付渠道
+func GetPayChannel(code string) (PayChannel, error) {
+	payChannelsMu.RLock()
+	defer payChannelsMu.RUnlock()
+	channel, ok := payChannels[code]
+	if !ok {
+		return nil, errors.New("不支持的支付方式")
+	}
+	return channel, nil
+}
+
+// HandlePayNotify 处理支付渠道的异步通知
+func HandlePayNotify(code string, r *http.Request) error {
+	channel, err := GetPayChannel(code)
+	if err != nil {
+		return err
+	}
+
+	sn, tradeNo, amount, err := channel.VerifyNotify(r)
+	if err != nil {
+		log.Printf("支付通知验证失败: %v", err)
+		return err
+	}
+	log.Printf("收到支付通知: code=%s sn=%s tradeNo=%s amount=%.2f", code, sn, tradeNo, amount)
+
+	switch {
+	case strings.HasPrefix(sn, rechargeSnPrefix):
+		return models.CompleteRecharge(sn, tradeNo, amount)
+	}
+	return errors.New("未知的支付单号")
+}
@@ -0,0 +1,563 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"myapi/app/config"
+	"myapi/app/helper"
+	"myapi/app/models"
+	"myapi/app/storage"
+	"myapi/app/structs"
+)
+
+// ProductImportHeader 商品导入导出文件的表头，一行对应一个SKU，没有SKU的商品只有一行且SKU列为空
+var ProductImportHeader = []string{
+	"商品ID", "型号", "商品名称", "关键词", "分类ID", "价格", "原价", "库存", "单位",
+	"主图", "排序", "状态", "SKU规格", "SKU价格", "SKU库存",
+}
+
+// ProductImportService 商品批量导入导出服务接口
+type ProductImportService interface {
+	Export(weid int, format string) (string, []byte, error)
+	Import(weid, adminID int, filename string, data []byte, dryRun bool) (*structs.ProductImportResult, *structs.ProductImportJob, error)
+	GetJob(weid, adminID int, jobID string) (*structs.ProductImportJob, error)
+}
+
+// productImportService 实现ProductImportService接口的结构体
+type productImportService struct {
+	prefix string
+}
+
+// NewProductImportService 创建商品批量导入导出服务实例
+func NewProductImportService() ProductImportService {
+	return &productImportService{prefix: config.GetString("redisPrefix", "")}
+}
+
+// productImportPlan 校验通过、等待写入的商品
+type productImportPlan struct {
+	item   structs.ProductImportItem
+	goods  structs.Product
+	values []structs.GoodsSkuValue
+}
+
+// Export 导出站点未删除的商品及SKU价格库存，format为csv或xlsx
+func (s *productImportService) Export(weid int, format string) (string, []byte, error) {
+	if format != helper.ExportXLSX {
+		format = helper.ExportCSV
+	}
+	maxProducts := config.GetInt("productExportMaxRows", 5000)
+	products, total, err := models.GetProductsForExport(weid, maxProducts)
+	if err != nil {
+		return "", nil, err
+	}
+	if total > int64(maxProducts) {
+		return "", nil, errors.New("导出商品过多，请联系管理员调整导出上限")
+	}
+
+	ids := make([]int, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+	skuMap, err := models.GetSkuValuesByGoodsIDs(ids)
+	if err != nil {
+		return "", nil, err
+	}
+
+	data, err := helper.ExportFile(format, "商品", ProductImportHeader, ProductExportRows(products, skuMap))
+	if err != nil {
+		return "", nil, err
+	}
+	return "products_" + time.Now().Format("20060102150405") + "." + format, data, nil
+}
+
+// Import 导入商品，按商品ID或型号匹配已有商品，匹配不到时新增
+// dryRun为true时只校验并返回预览；商品数超过同步上限且Redis可用时转为后台任务，返回任务进度，任务归属adminID
+func (s *productImportService) Import(weid, adminID int, filename string, data []byte, dryRun bool) (*structs.ProductImportResult, *structs.ProductImportJob, error) {
+	records, err := helper.ReadSpreadsheet(filename, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	maxRows := config.GetInt("productImportMaxRows", 20000)
+	if len(records)-1 > maxRows {
+		return nil, nil, fmt.Errorf("导入文件不能超过%d行", maxRows)
+	}
+	rows, rowErrors, err := ParseProductImportRows(records)
+	if err != nil {
+		return nil, nil, err
+	}
+	groups, groupErrors := GroupProductImportRows(rows)
+
+	result := &structs.ProductImportResult{
+		DryRun:   dryRun,
+		Rows:     len(rows) + len(rowErrors),
+		Products: len(groups),
+		Errors:   append(rowErrors, groupErrors...),
+		Items:    []structs.ProductImportItem{},
+	}
+	plans, planErrors, err := s.plan(weid, groups)
+	if err != nil {
+		return nil, nil, err
+	}
+	result.Errors = append(result.Errors, planErrors...)
+	for _, plan := range plans {
+		result.Items = append(result.Items, plan.item)
+	}
+	if dryRun || len(plans) == 0 {
+		return result, nil, nil
+	}
+
+	if len(plans) <= config.GetInt("productImportSyncRows", 200) || storage.GetRedis() == nil {
+		s.apply(weid, plans, result, nil)
+		return result, nil, nil
+	}
+
+	now := time.Now().Unix()
+	job := &structs.ProductImportJob{
+		JobID:      helper.BuildOrderNo("PI"),
+		Weid:       weid,
+		AdminID:    adminID,
+		Status:     structs.ProductImportRunning,
+		Total:      len(plans),
+		CreateTime: now,
+		UpdateTime: now,
+	}
+	if err := s.saveJob(job); err != nil {
+		return nil, nil, errors.New("创建导入任务失败")
+	}
+	go s.runJob(job, plans, result)
+	return nil, job, nil
+}
+
+// GetJob 获取后台导入任务进度，只能查询本站点本管理员创建的任务
+func (s *productImportService) GetJob(weid, adminID int, jobID string) (*structs.ProductImportJob, error) {
+	cacheData, err := storage.GetCache(s.jobKey(jobID))
+	if err != nil {
+		return nil, errors.New("导入任务不存在或已过期")
+	}
+	var job structs.ProductImportJob
+	if err := json.Unmarshal([]byte(cacheData), &job); err != nil || job.Weid != weid || job.AdminID != adminID {
+		return nil, errors.New("导入任务不存在或已过期")
+	}
+	return &job, nil
+}
+
+// runJob 在后台执行导入并定期保存进度，异常时把任务标记为失败
+func (s *productImportService) runJob(job *structs.ProductImportJob, plans []productImportPlan, result *structs.ProductImportResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("商品导入任务%s异常: %v", job.JobID, r)
+			job.Status = structs.ProductImportFailed
+			job.Msg = "导入异常中断"
+			job.UpdateTime = time.Now().Unix()
+			if err := s.saveJob(job); err != nil {
+				log.Printf("保存商品导入任务失败: %v", err)
+			}
+		}
+	}()
+
+	step := config.GetInt("productImportProgressStep", 50)
+	s.apply(job.Weid, plans, result, func(processed int) {
+		if step > 0 && processed%step == 0 {
+			job.Processed = processed
+			job.UpdateTime = time.Now().Unix()
+			if err := s.saveJob(job); err != nil {
+				log.Printf("保存商品导入进度失败: %v", err)
+			}
+		}
+	})
+
+	job.Status = structs.ProductImportDone
+	job.Processed = len(plans)
+	job.Result = result
+	job.UpdateTime = time.Now().Unix()
+	if err := s.saveJob(job); err != nil {
+		log.Printf("保存商品导入任务失败: %v", err)
+	}
+}
+
+// plan 匹配已有商品并逐个校验，返回可以写入的商品和错误，同一商品有任何错误时整体跳过
+func (s *productImportService) plan(weid int, groups [][]structs.ProductImportRow) ([]productImportPlan, []structs.ProductImportError, error) {
+	var ids []int
+	var productModels []string
+	for _, group := range groups {
+		if group[0].ID > 0 {
+			ids = append(ids, group[0].ID)
+		} else {
+			productModels = append(productModels, group[0].Model)
+		}
+	}
+	products, err := models.FindProductsForImport(weid, ids, productModels)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[int]structs.Product, len(products))
+	byModel := make(map[string]structs.Product, len(products))
+	matchedIDs := make([]int, 0, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+		if _, ok := byModel[product.Model]; !ok && product.Model != "" {
+			byModel[product.Model] = product
+		}
+		matchedIDs = append(matchedIDs, product.ID)
+	}
+	skuMap, err := models.GetSkuValuesByGoodsIDs(matchedIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	categories, err := models.GetCategories(weid, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	categoryIDs := make(map[int]bool, len(categories))
+	for _, category := range categories {
+		categoryIDs[category.ID] = true
+	}
+
+	validator := NewProductValidator()
+	var plans []productImportPlan
+	var errs []structs.ProductImportError
+	for _, group := range groups {
+		first := group[0]
+		plan := productImportPlan{
+			item: structs.ProductImportItem{Model: first.Model, Name: first.Name, Action: structs.ProductImportCreate},
+		}
+		for _, row := range group {
+			plan.item.Rows = append(plan.item.Rows, row.Row)
+		}
+
+		existing, found := byID[first.ID]
+		if first.ID == 0 {
+			existing, found = byModel[first.Model]
+		}
+		if first.ID > 0 && !found {
+			errs = append(errs, structs.ProductImportError{Row: first.Row, Msg: "商品ID不存在或已删除"})
+			continue
+		}
+		if found {
+			plan.goods = existing
+			plan.item.ID = existing.ID
+			plan.item.Action = structs.ProductImportUpdate
+		} else {
+			plan.goods = structs.Product{Weid: weid}
+		}
+		applyProductImportRow(&plan.goods, first)
+
+		if ok, msg := validator.Check(map[string]interface{}{
+			"name":           plan.goods.Name,
+			"cat_id":         plan.goods.CatID,
+			"price":          plan.goods.Price,
+			"original_price": plan.goods.OriginalPrice,
+			"quantity":       plan.goods.Quantity,
+			"minimum":        plan.goods.Minimum,
+		}, "create"); !ok {
+			errs = append(errs, structs.ProductImportError{Row: first.Row, Msg: msg})
+			continue
+		}
+		if !categoryIDs[plan.goods.CatID] {
+			errs = append(errs, structs.ProductImportError{Row: first.Row, Msg: "商品分类不存在"})
+			continue
+		}
+
+		values, skuErrors := matchImportSkus(group, skuMap[plan.goods.ID], found)
+		if len(skuErrors) > 0 {
+			errs = append(errs, skuErrors...)
+			continue
+		}
+		plan.values = values
+		plan.item.Skus = len(values)
+		plans = append(plans, plan)
+	}
+	return plans, errs, nil
+}
+
+// apply 逐个写入商品，每个商品一个事务，失败的商品记入错误；progress在每个商品处理后调用
+func (s *productImportService) apply(weid int, plans []productImportPlan, result *structs.ProductImportResult, progress func(processed int)) {
+	products := NewProductService()
+	changed := make([]int, 0, len(plans))
+	for i := range plans {
+		plan := &plans[i]
+		var err error
+		if plan.item.Action == structs.ProductImportUpdate {
+			err = models.SaveImportedProduct(&plan.goods, plan.values)
+		} else {
+			form := &structs.ProductForm{Goods: plan.goods}
+			err = models.CreateProduct(form)
+			plan.goods.ID = form.Goods.ID
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, structs.ProductImportError{Row: plan.item.Rows[0], Msg: err.Error()})
+		} else {
+			if plan.item.Action == structs.ProductImportUpdate {
+				result.Updated++
+			} else {
+				result.Created++
+			}
+			result.Items[i].ID = plan.goods.ID
+			products.InvalidateProductDetail(plan.goods.ID)
+			changed = append(changed, plan.goods.ID)
+		}
+		if progress != nil {
+			progress(i + 1)
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	NewCategoryService().Invalidate(weid)
+	search := NewSearchService()
+	if len(changed) > config.GetInt("productImportSyncRows", 200) {
+		if _, err := search.Rebuild(); err != nil {
+			log.Printf("重建商品搜索索引失败: %v", err)
+		}
+		return
+	}
+	for _, id := range changed {
+		if err := search.Refresh(id); err != nil {
+			log.Printf("更新商品搜索索引失败: %v", err)
+		}
+	}
+}
+
+// jobKey 导入任务缓存键
+func (s *productImportService) jobKey(jobID string) string {
+	return fmt.Sprintf("%s:product:import:%s", s.prefix, jobID)
+}
+
+// saveJob 保存导入任务进度
+func (s *productImportService) saveJob(job *structs.ProductImportJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	ttl := time.Duration(config.GetInt("productImportJobTTL", 86400)) * time.Second
+	return storage.SetCache(s.jobKey(job.JobID), string(data), ttl)
+}
+
+// applyProductImportRow 把导入行的商品字段写入商品
+func applyProductImportRow(goods *structs.Product, row structs.ProductImportRow) {
+	goods.Model = row.Model
+	goods.Name = row.Name
+	goods.Keyword = row.Keyword
+	goods.CatID = row.CatID
+	goods.Price = row.Price
+	goods.OriginalPrice = row.OriginalPrice
+	goods.Quantity = row.Quantity
+	goods.QuantityUnit = row.QuantityUnit
+	goods.Image = row.Image
+	goods.Sort = row.Sort
+	goods.Status = row.Status
+}
+
+// matchImportSkus 按SKU规格匹配商品已有的SKU并写入价格库存，导入不新增SKU
+func matchImportSkus(group []structs.ProductImportRow, current []structs.GoodsSkuValue, found bool) ([]structs.GoodsSkuValue, []structs.ProductImportError) {
+	bySku := make(map[string]structs.GoodsSkuValue, len(current))
+	for _, value := range current {
+		bySku[value.Sku] = value
+	}
+	var values []structs.GoodsSkuValue
+	var errs []structs.ProductImportError
+	for _, row := range group {
+		if row.Sku == "" {
+			continue
+		}
+		if !found {
+			errs = append(errs, structs.ProductImportError{Row: row.Row, Msg: "新增商品不能导入SKU，请先在后台设置规格"})
+			continue
+		}
+		value, ok := bySku[row.Sku]
+		if !ok {
+			errs = append(errs, structs.ProductImportError{Row: row.Row, Msg: "SKU规格" + row.Sku + "不存在"})
+			continue
+		}
+		if row.SkuPrice != nil {
+			value.Price = *row.SkuPrice
+		}
+		if row.SkuQuantity != nil {
+			value.Quantity = *row.SkuQuantity
+		}
+		values = append(values, value)
+	}
+	return values, errs
+}
+
+// ProductExportRows 生成导出行，每个SKU一行并重复商品字段，没有SKU的商品输出一行
+func ProductExportRows(products []structs.Product, skuMap map[int][]structs.GoodsSkuValue) [][]interface{} {
+	rows := make([][]interface{}, 0, len(products))
+	for _, p := range products {
+		base := []interface{}{p.ID, p.Model, p.Name, p.Keyword, p.CatID, p.Price, p.OriginalPrice,
+			p.Quantity, p.QuantityUnit, p.Image, p.Sort, p.Status}
+		values := skuMap[p.ID]
+		if len(values) == 0 {
+			rows = append(rows, append(base, "", "", ""))
+			continue
+		}
+		for _, value := range values {
+			row := make([]interface{}, len(base), len(base)+3)
+			copy(row, base)
+			rows = append(rows, append(row, value.Sku, value.Price, value.Quantity))
+		}
+	}
+	return rows
+}
+
+// ParseProductImportRows 按表头名称解析导入文件，第一行为表头，列的顺序不限
+// 表头缺少列时返回error；单元格格式错误的行记入错误列表并跳过；状态为空时按上架处理
+func ParseProductImportRows(records [][]string) ([]structs.ProductImportRow, []structs.ProductImportError, error) {
+	if len(records) < 2 {
+		return nil, nil, errors.New("导入文件没有数据")
+	}
+	columns := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	var missing []string
+	for _, name := range ProductImportHeader {
+		if _, ok := columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, errors.New("导入文件缺少列：" + strings.Join(missing, "、"))
+	}
+
+	var rows []structs.ProductImportRow
+	var errs []structs.ProductImportError
+	for i, record := range records[1:] {
+		p := &importRowParser{record: record, columns: columns}
+		row := structs.ProductImportRow{
+			Row:           i + 2,
+			ID:            p.int("商品ID"),
+			Model:         p.text("型号"),
+			Name:          p.text("商品名称"),
+			Keyword:       p.text("关键词"),
+			CatID:         p.int("分类ID"),
+			Price:         p.float("价格"),
+			OriginalPrice: p.float("原价"),
+			Quantity:      p.int("库存"),
+			QuantityUnit:  p.text("单位"),
+			Image:         p.text("主图"),
+			Sort:          p.int("排序"),
+			Status:        1,
+			Sku:           p.text("SKU规格"),
+		}
+		if p.text("状态") != "" {
+			row.Status = p.int("状态")
+		}
+		if p.text("SKU价格") != "" {
+			price := p.float("SKU价格")
+			row.SkuPrice = &price
+		}
+		if p.text("SKU库存") != "" {
+			quantity := p.int("SKU库存")
+			row.SkuQuantity = &quantity
+		}
+		if p.err != "" {
+			errs = append(errs, structs.ProductImportError{Row: row.Row, Msg: p.err})
+			continue
+		}
+		if row.ID < 0 {
+			errs = append(errs, structs.ProductImportError{Row: row.Row, Msg: "商品ID无效"})
+			continue
+		}
+		if row.Status != 0 && row.Status != 1 {
+			errs = append(errs, structs.ProductImportError{Row: row.Row, Msg: "状态只能是0或1"})
+			continue
+		}
+		if (row.SkuPrice != nil && *row.SkuPrice < 0) || (row.SkuQuantity != nil && *row.SkuQuantity < 0) {
+			errs = append(errs, structs.ProductImportError{Row: row.Row, Msg: "SKU价格和库存不能小于0"})
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, errs, nil
+}
+
+// GroupProductImportRows 把同一商品的行归并在一起，有商品ID时按ID归并，否则按型号归并
+// 商品字段取第一行；商品ID和型号都为空或同一商品SKU重复的行记入错误列表
+func GroupProductImportRows(rows []structs.ProductImportRow) ([][]structs.ProductImportRow, []structs.ProductImportError) {
+	var groups [][]structs.ProductImportRow
+	var errs []structs.ProductImportError
+	index := make(map[string]int)
+	skus := make(map[string]bool)
+	for _, row := range rows {
+		var key string
+		switch {
+		case row.ID > 0:
+			key = "id:" + strconv.Itoa(row.ID)
+		case row.Model != "":
+			key = "model:" + row.Model
+		default:
+			errs = append(errs, structs.ProductImportError{Row: row.Row, Msg: "商品ID和型号不能同时为空"})
+			continue
+		}
+		if row.Sku != "" {
+			if skus[key+"|"+row.Sku] {
+				errs = append(errs, structs.ProductImportError{Row: row.Row, Msg: "SKU规格" + row.Sku + "重复"})
+				continue
+			}
+			skus[key+"|"+row.Sku] = true
+		}
+		if i, ok := index[key]; ok {
+			groups[i] = append(groups[i], row)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, []structs.ProductImportRow{row})
+	}
+	return groups, errs
+}
+
+// importRowParser 按列名读取导入行的单元格，记录第一个格式错误
+type importRowParser struct {
+	record  []string
+	columns map[string]int
+	err     string
+}
+
+// text 读取文本单元格，列超出本行长度时为空
+func (p *importRowParser) text(name string) string {
+	i := p.columns[name]
+	if i >= len(p.record) {
+		return ""
+	}
+	return p.record[i]
+}
+
+// float 读取数字单元格，空单元格为0
+func (p *importRowParser) float(name string) float64 {
+	value := p.text(name)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		p.fail(name + "必须是数字")
+		return 0
+	}
+	return n
+}
+
+// int 读取整数单元格，允许表格软件保存的"12.0"这类写法，空单元格为0
+func (p *importRowParser) int(name string) int {
+	n := p.float(name)
+	if n != math.Trunc(n) || math.Abs(n) > math.MaxInt32 {
+		p.fail(name + "必须是整数")
+		return 0
+	}
+	return int(n)
+}
+
+// fail 记录第一个错误
+func (p *importRowParser) fail(msg string) {
+	if p.err == "" {
+		p.err = msg
+	}
+}
@@ -5,27 +5,40 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"myapi/app/config"
 	"myapi/app/helper"
 	"myapi/app/models"
+	"myapi/app/storage"
 	"myapi/app/structs"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // ProductService 定义产品服务接口
 type ProductService interface {
 	GetProductByID(id int) *structs.Product
 	GetAllProducts() []structs.Product
-	CreateProduct(product *structs.Product) error
-	UpdateProduct(product *structs.Product) error
-	DeleteProduct(id int) error
+	GetProductForm(weid, id int) (*structs.ProductForm, error)
+	CreateProduct(form *structs.ProductForm) error
+	UpdateProduct(form *structs.ProductForm) error
+	DeleteProduct(weid, id int) error
 	GetBuyNowInfo(params map[string]interface{}) (map[string]interface{}, error)
+	GetCombinationDetail(id int) (map[string]interface{}, error)
+	GetProductDetail(id int) (*structs.ProductDetail, error)
+	GetProductList(f structs.ProductListFilter, page, limit int) (*structs.ProductList, error)
+	InvalidateProductDetail(id int)
 }
 
 // productService 实现ProductService接口的结构体
-type productService struct{}
+type productService struct {
+	prefix string
+}
 
 // NewProductService 创建一个新的产品服务实例
 func NewProductService() ProductService {
-	return &productService{}
+	return &productService{prefix: config.GetString("redisPrefix", "")}
 }
 
 // GetProductByID 根据ID获取产品
@@ -38,24 +51,148 @@ func (s *productService) GetAllProducts() []structs.Product {
 	return models.GetAllProducts()
 }
 
+// GetProductForm 获取后台编辑用的商品数据，包括规格、图片、描述、数量折扣和分类关联
+func (s *productService) GetProductForm(weid, id int) (*structs.ProductForm, error) {
+	return models.GetProductForm(weid, id)
+}
+
 // CreateProduct 创建产品
-func (s *productService) CreateProduct(product *structs.Product) error {
-	// 实际实现
+func (s *productService) CreateProduct(form *structs.ProductForm) error {
+	if err := s.validateForm(form, "create"); err != nil {
+		return err
+	}
+	if err := models.CreateProduct(form); err != nil {
+		return err
+	}
+	s.afterProductChanged(form.Goods.Weid, form.Goods.ID)
 	return nil
 }
 
 // UpdateProduct 更新产品
-func (s *productService) UpdateProduct(product *structs.Product) error {
-	// 实际实现
+func (s *productService) UpdateProduct(form *structs.ProductForm) error {
+	if err := s.validateForm(form, "update"); err != nil {
+		return err
+	}
+	if err := models.UpdateProduct(form); err != nil {
+		return err
+	}
+	s.afterProductChanged(form.Goods.Weid, form.Goods.ID)
 	return nil
 }
 
-// DeleteProduct 删除产品
-func (s *productService) DeleteProduct(id int) error {
-	// 实际实现
+// DeleteProduct 删除产品，软删除后前台不再展示
+func (s *productService) DeleteProduct(weid, id int) error {
+	if err := models.SoftDeleteProduct(weid, id); err != nil {
+		return err
+	}
+	s.afterProductChanged(weid, id)
+	return nil
+}
+
+// afterProductChanged 商品变化后清除详情和分类树缓存，并更新搜索索引
+func (s *productService) afterProductChanged(weid, id int) {
+	s.InvalidateProductDetail(id)
+	NewCategoryService().Invalidate(weid)
+	if err := NewSearchService().Refresh(id); err != nil {
+		log.Printf("更新商品搜索索引失败: %v", err)
+	}
+}
+
+// validateForm 按场景校验商品，并检查规格、SKU、数量折扣和分类关联
+func (s *productService) validateForm(form *structs.ProductForm, scene string) error {
+	goods := &form.Goods
+	goods.Name = strings.TrimSpace(goods.Name)
+	if ok, msg := NewProductValidator().Check(map[string]interface{}{
+		"id":             goods.ID,
+		"name":           goods.Name,
+		"cat_id":         goods.CatID,
+		"price":          goods.Price,
+		"original_price": goods.OriginalPrice,
+		"quantity":       goods.Quantity,
+		"minimum":        goods.Minimum,
+	}, scene); !ok {
+		return errors.New(msg)
+	}
+
+	for _, sku := range form.Skus {
+		if strings.TrimSpace(sku.Name) == "" || strings.TrimSpace(sku.Item) == "" {
+			return errors.New("规格名称和规格项不能为空")
+		}
+	}
+	seen := make(map[string]bool)
+	for _, value := range form.SkuValues {
+		if value.Sku == "" {
+			return errors.New("SKU规格值不能为空")
+		}
+		if value.Price < 0 || value.Quantity < 0 {
+			return errors.New("SKU价格和库存不能小于0")
+		}
+		if seen[value.Sku] {
+			return fmt.Errorf("SKU规格值%s重复", value.Sku)
+		}
+		seen[value.Sku] = true
+	}
+	for _, discount := range form.Discounts {
+		// 数量折扣按百分比保存，与下单时的计算方式一致
+		if discount.Quantity <= 0 || discount.Price <= 0 || discount.Price > 100 {
+			return errors.New("数量折扣的数量必须大于0，折扣必须在0到100之间")
+		}
+	}
+	if goods.Image == "" && len(form.Images) > 0 {
+		goods.Image = form.Images[0].Image
+	}
+
+	categoryIDs := make([]int, 0, len(form.CategoryIDs))
+	linked := map[int]bool{goods.CatID: true}
+	for _, id := range form.CategoryIDs {
+		if id > 0 && !linked[id] {
+			linked[id] = true
+			categoryIDs = append(categoryIDs, id)
+		}
+	}
+	form.CategoryIDs = categoryIDs
+	exists, err := models.CategoriesExist(goods.Weid, append([]int{goods.CatID}, categoryIDs...))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("商品分类不存在")
+	}
 	return nil
 }
 
+// NewProductValidator 商品校验规则，create场景校验新增字段，update场景额外校验商品ID
+func NewProductValidator() *helper.Validator {
+	v := helper.NewValidator()
+	positive := func(value interface{}) bool {
+		n, _ := helper.ToInt(value)
+		return n > 0
+	}
+	notNegative := func(value interface{}) bool {
+		n, err := helper.ToFloat64(value)
+		return err == nil && n >= 0
+	}
+	v.AddRule("id", "required", "商品ID不能为空", positive)
+	v.AddRule("name", "required", "商品名称不能为空", func(value interface{}) bool {
+		name, _ := value.(string)
+		return name != ""
+	})
+	v.AddRule("name", "max", "商品名称不能超过100个字", func(value interface{}) bool {
+		name, _ := value.(string)
+		return utf8.RuneCountInString(name) <= 100
+	})
+	v.AddRule("cat_id", "required", "请选择商品分类", positive)
+	v.AddRule("price", "min", "商品价格不能小于0", notNegative)
+	v.AddRule("original_price", "min", "商品原价不能小于0", notNegative)
+	v.AddRule("quantity", "min", "商品库存不能小于0", notNegative)
+	v.AddRule("minimum", "min", "最小起订数量不能小于0", notNegative)
+
+	createFields := []string{"name", "cat_id", "price", "original_price", "quantity", "minimum"}
+	v.AddScene("create", createFields)
+	v.AddScene("update", append([]string{"id"}, createFields...))
+	return v
+}
+
 // GetBuyNowInfo 获取立即购买信息
 func (s *productService) GetBuyNowInfo(params map[string]interface{}) (map[string]interface{}, error) {
 	// 参数验证
@@ -79,7 +216,7 @@ func (s *productService) GetBuyNowInfo(params map[string]interface{}) (map[strin
 	product, err := models.CartGoods(cartParams)
 	log.Println("cart 产品", product)
 	if err != nil {
-		return nil, fmt.Errorf("获取商品信息失败: %v", err)
+		return nil, fmt.Errorf("获取商品信息失败: %w", err)
 	}
 	status, _ := helper.ToInt(product["status"])
 	if status != 1 {
@@ -105,11 +242,50 @@ func (s *productService) GetBuyNowInfo(params map[string]interface{}) (map[strin
 		}
 	}
 
+	// 套装商品把本次购买金额分摊到各组件，供报表和佣金按组件统计
+	if items, ok := product["combination"].([]structs.CombinationItem); ok && len(items) > 0 {
+		price, _ := helper.ToFloat64(product["price"])
+		quantity, _ := helper.ToFloat64(product["quantity"])
+		product["combination"] = AllocateCombinationRevenue(price*quantity, items)
+	}
+
 	infodata := make(map[string]interface{})
 	infodata["shopList"] = product
 	infodata["sid"] = product["sid"]
 	infodata["ptype"] = 1
 
+	// 计算运费，按店铺返回
+	freight, err := s.getBuyNowFreight(params, product)
+	if err != nil {
+		return nil, err
+	}
+	freightTotal := 0.0
+	for _, fee := range freight {
+		freightTotal += fee
+	}
+
+	// 秒杀商品预留库存，未支付的预留超时后释放
+	var reservation *SeckillReservation
+	if msid, _ := helper.ToInt(params["Msid"]); msid > 0 {
+		miaoshaGoods := models.GetMiaoshaGoodsByID(msid)
+		if miaoshaGoods == nil {
+			return nil, errors.New("该秒杀商品不存在")
+		}
+		uid, _ := helper.ToInt(params["Uid"])
+		quantity, _ := helper.ToInt(params["BuyNumber"])
+		sku, _ := params["Sku"].(string)
+		reservation, err = NewSeckillService().Reserve(uid, miaoshaGoods, sku, quantity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	infodata["freight"] = freight
+	infodata["freightTotal"] = freightTotal
+	if reservation != nil {
+		infodata["reservation"] = reservation
+	}
+
 	// 转换为JSON字符串
 	dataJSON, err := json.Marshal(product)
 	if err != nil {
@@ -118,12 +294,322 @@ func (s *productService) GetBuyNowInfo(params map[string]interface{}) (map[strin
 
 	// 记录购买信息
 	recordId, err := models.CreateGoodsBuynowinfo(params["Weid"].(int), params["Ip"].(string), string(dataJSON))
+	if err != nil {
+		s.releaseSeckillReservation(params, reservation)
+		return nil, errors.New("记录购买信息失败")
+	}
+
+	// 按购买记录预留商品库存，未支付的预留超时后释放；刷新页面时沿用会员已有的相同预留
+	uid, _ := helper.ToInt(params["Uid"])
+	goodsIDInt, _ := helper.ToInt(goodsID)
+	quantity, _ := helper.ToInt(params["BuyNumber"])
+	sku, _ := params["Sku"].(string)
+	items := []structs.StockItem{{GoodsID: goodsIDInt, Sku: sku, Quantity: quantity}}
+	if err := NewInventoryService().Reserve(params["Weid"].(int), uid, recordId, items); err != nil {
+		s.releaseSeckillReservation(params, reservation)
+		return nil, err
+	}
 
 	// 构建返回数据
+	infodata["recordId"] = recordId
 
-	if err == nil {
-		infodata["recordId"] = recordId
+	return infodata, nil
+}
+
+// releaseSeckillReservation 立即购买失败时释放本次的秒杀预留
+func (s *productService) releaseSeckillReservation(params map[string]interface{}, reservation *SeckillReservation) {
+	if reservation == nil {
+		return
+	}
+	uid, _ := helper.ToInt(params["Uid"])
+	if err := NewSeckillService().Release(uid, reservation.MsID); err != nil {
+		log.Printf("释放秒杀预留失败: %v", err)
 	}
+}
 
-	return infodata, nil
+// GetCombinationDetail 获取套装商品详情，包括组件列表、按套装售价分摊的组件金额和可售套数
+func (s *productService) GetCombinationDetail(id int) (map[string]interface{}, error) {
+	product := models.GetProductByID(id)
+	if product == nil {
+		return nil, errors.New("商品不存在")
+	}
+	if product.IsCombination != 1 {
+		return nil, errors.New("该商品不是套装商品")
+	}
+
+	items, err := models.GetCombinationItems(id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].Image = helper.ToImg(items[i].Image)
+	}
+	items = AllocateCombinationRevenue(product.Price, items)
+
+	product.Image = helper.ToImg(product.Image)
+	return map[string]interface{}{
+		"goods": product,
+		"items": items,
+		"stock": CombinationStock(items),
+	}, nil
+}
+
+// GetProductList 分页获取商品列表，按游标翻页时返回下一页游标
+func (s *productService) GetProductList(f structs.ProductListFilter, page, limit int) (*structs.ProductList, error) {
+	if f.Sort == "" {
+		f.Sort = structs.ProductSortDefault
+	}
+	if f.MaxPrice > 0 && f.MinPrice > f.MaxPrice {
+		f.MinPrice, f.MaxPrice = f.MaxPrice, f.MinPrice
+	}
+
+	products, total, err := models.GetProductList(f, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		products[i].Image = helper.ToImg(products[i].Image)
+	}
+
+	result := &structs.ProductList{
+		List:  products,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}
+	if len(products) == limit {
+		last := products[len(products)-1]
+		result.NextCursor = EncodeProductCursor(ProductSortValue(last, f.Sort), last.ID)
+	}
+	return result, nil
+}
+
+// ProductSortValue 获取商品在指定排序方式下的排序值
+func ProductSortValue(product structs.Product, sort string) float64 {
+	switch sort {
+	case structs.ProductSortPrice:
+		return product.Price
+	case structs.ProductSortSales:
+		return float64(product.SaleCount + product.SaleCountBase)
+	case structs.ProductSortNewest:
+		return float64(product.CreateTime)
+	default:
+		return float64(product.Sort)
+	}
+}
+
+// EncodeProductCursor 生成商品列表游标，格式为"排序值_ID"
+func EncodeProductCursor(value float64, id int) string {
+	return strconv.FormatFloat(value, 'f', -1, 64) + "_" + strconv.Itoa(id)
+}
+
+// ParseProductCursor 解析商品列表游标
+func ParseProductCursor(cursor string) (*structs.ProductCursor, error) {
+	idx := strings.LastIndex(cursor, "_")
+	if idx <= 0 {
+		return nil, errors.New("游标无效")
+	}
+	value, err := strconv.ParseFloat(cursor[:idx], 64)
+	if err != nil {
+		return nil, errors.New("游标无效")
+	}
+	id, err := strconv.Atoi(cursor[idx+1:])
+	if err != nil || id <= 0 {
+		return nil, errors.New("游标无效")
+	}
+	return &structs.ProductCursor{Value: value, ID: id}, nil
+}
+
+// GetProductDetail 获取商品详情，基础信息按商品缓存，进行中的团购和秒杀活动每次实时查询
+func (s *productService) GetProductDetail(id int) (*structs.ProductDetail, error) {
+	detail, err := s.getCachedDetail(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	if tuan, err := models.GetActiveTuanGoodsByGoodsID(id, now); err != nil {
+		log.Printf("获取商品团购活动失败: %v", err)
+	} else if tuan != nil {
+		detail.Tuan = tuan
+		if skus, err := models.GetTuanSkuValues(tuan.ID); err == nil {
+			for i := range skus {
+				skus[i].Image = helper.ToImg(skus[i].Image)
+			}
+			detail.TuanSkus = skus
+		}
+	}
+	if miaosha, err := models.GetActiveMiaoshaGoodsByGoodsID(id, now); err != nil {
+		log.Printf("获取商品秒杀活动失败: %v", err)
+	} else if miaosha != nil {
+		detail.Miaosha = miaosha
+		if skus, err := models.GetMiaoshaSkuValues(miaosha.ID); err == nil {
+			for i := range skus {
+				skus[i].Image = helper.ToImg(skus[i].Image)
+			}
+			detail.MiaoshaSkus = skus
+		}
+	}
+	return detail, nil
+}
+
+// InvalidateProductDetail 删除商品详情缓存，后台修改商品后调用
+func (s *productService) InvalidateProductDetail(id int) {
+	if id <= 0 {
+		return
+	}
+	if err := storage.DelCache(s.detailCacheKey(id)); err != nil {
+		log.Printf("删除商品详情缓存失败: %v", err)
+	}
+}
+
+// detailCacheKey 商品详情缓存键
+func (s *productService) detailCacheKey(id int) string {
+	return fmt.Sprintf("%s:product:detail:%d", s.prefix, id)
+}
+
+// getCachedDetail 读取商品详情缓存，未命中时从数据库组装并写入缓存
+func (s *productService) getCachedDetail(id int) (*structs.ProductDetail, error) {
+	cacheKey := s.detailCacheKey(id)
+	if cacheData, err := storage.GetCache(cacheKey); err == nil {
+		var detail structs.ProductDetail
+		if err := json.Unmarshal([]byte(cacheData), &detail); err == nil && detail.Goods != nil {
+			return &detail, nil
+		}
+	}
+
+	detail, err := s.loadDetail(id)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(detail); err == nil {
+		ttl := time.Duration(config.GetInt("productDetailCacheTTL", 600)) * time.Second
+		storage.SetCache(cacheKey, string(data), ttl)
+	}
+	return detail, nil
+}
+
+// loadDetail 从数据库组装商品详情，不含活动信息
+func (s *productService) loadDetail(id int) (*structs.ProductDetail, error) {
+	product := models.GetProductByID(id)
+	if product == nil || product.Status != 1 {
+		return nil, errors.New("商品不存在或已下架")
+	}
+	product.Image = helper.ToImg(product.Image)
+
+	images, err := models.GetGoodsImages(id)
+	if err != nil {
+		return nil, err
+	}
+	description, err := models.GetGoodsDescription(id)
+	if err != nil {
+		return nil, err
+	}
+	skus, err := models.GetGoodsSkus(id)
+	if err != nil {
+		return nil, err
+	}
+	skuValues, err := models.GetGoodsSkuValues(id)
+	if err != nil {
+		return nil, err
+	}
+	discounts, err := models.GetGoodsDiscounts(id)
+	if err != nil {
+		return nil, err
+	}
+
+	pics := make([]string, 0, len(images))
+	for _, image := range images {
+		pics = append(pics, image.Image)
+	}
+	for i := range skuValues {
+		skuValues[i].Image = helper.ToImg(skuValues[i].Image)
+	}
+	if discounts == nil {
+		discounts = []structs.GoodsDiscount{}
+	}
+
+	return &structs.ProductDetail{
+		Goods:       product,
+		Images:      helper.SetPicsView(strings.Join(pics, ",")),
+		Description: description,
+		Specs:       BuildProductSpecs(skus),
+		SkuValues:   skuValues,
+		Discounts:   discounts,
+	}, nil
+}
+
+// BuildProductSpecs 把商品规格定义转换为规格矩阵，规格项以逗号分隔，去掉空项和重复项
+func BuildProductSpecs(skus []structs.GoodsSku) []structs.ProductSpec {
+	specs := make([]structs.ProductSpec, 0, len(skus))
+	for _, sku := range skus {
+		name := strings.TrimSpace(sku.Name)
+		if name == "" {
+			continue
+		}
+		items := make([]string, 0)
+		seen := make(map[string]bool)
+		for _, item := range strings.Split(sku.Item, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" || seen[item] {
+				continue
+			}
+			seen[item] = true
+			items = append(items, item)
+		}
+		if len(items) == 0 {
+			continue
+		}
+		specs = append(specs, structs.ProductSpec{
+			Name:     name,
+			Ptype:    sku.Ptype,
+			Required: sku.Required,
+			Items:    items,
+		})
+	}
+	return specs
+}
+
+// getBuyNowFreight 计算立即购买的运费，未指定收货地址时使用默认地址，没有地址时运费为0
+func (s *productService) getBuyNowFreight(params map[string]interface{}, product map[string]interface{}) (map[int]float64, error) {
+	sid, _ := helper.ToInt(product["sid"])
+	freight := map[int]float64{sid: 0}
+
+	// 不需要配送的商品不计算运费
+	if shipping, _ := helper.ToInt(product["shipping"]); shipping != 1 {
+		return freight, nil
+	}
+
+	weid, _ := helper.ToInt(params["Weid"])
+	uid, _ := helper.ToInt(params["Uid"])
+	addressID, _ := helper.ToInt(params["AddressID"])
+	var address *structs.Address
+	if addressID > 0 {
+		a, err := models.GetAddressDetail(addressID, uid)
+		if err != nil {
+			return nil, errors.New("收货地址不存在")
+		}
+		address = a
+	} else if uid > 0 {
+		address, _ = models.GetDefaultAddress(weid, uid)
+	}
+	if address == nil {
+		return freight, nil
+	}
+
+	goodsID, _ := helper.ToInt(product["id"])
+	transportID, _ := helper.ToInt(product["transport_id"])
+	quantity, _ := helper.ToInt(product["quantity"])
+	weight, _ := helper.ToFloat64(product["weight"])
+	price, _ := helper.ToFloat64(product["price"])
+
+	shippingService := NewShippingService()
+	return shippingService.CalculateFreight(weid, address, []structs.FreightItem{{
+		Sid:         sid,
+		GoodsID:     goodsID,
+		TransportID: transportID,
+		Quantity:    quantity,
+		Weight:      weight,
+		Amount:      price * float64(quantity),
+	}})
 }
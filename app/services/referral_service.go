@@ -0,0 +1,242 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"myapi/app/config"
+	"myapi/app/helper"
+	"myapi/app/models"
+	"myapi/app/structs"
+)
+
+// ReferralService 推荐关系服务接口，基于推荐关系闭包表查询团队和上级
+type ReferralService interface {
+	GetTeamStat(uid, maxDepth int) (map[string]interface{}, error)
+	GetTeam(uid, depth, page, limit int) ([]structs.ReferralMember, int64, error)
+	GetUpline(uid int) ([]structs.ReferralMember, error)
+	Backfill(weid int, reset bool) (int64, error)
+	GetInviteInfo(uid int) (map[string]interface{}, error)
+	Bind(uid int, invite string) error
+}
+
+// inviteCodeCharset 邀请码字符集，去掉了容易混淆的0、O、1、I
+const inviteCodeCharset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// referralService 实现ReferralService接口的结构体
+type referralService struct{}
+
+// NewReferralService 创建一个新的推荐关系服务实例
+func NewReferralService() ReferralService {
+	return &referralService{}
+}
+
+// GetTeamStat 获取团队总人数和各层级人数，maxDepth大于0时各层级人数只统计前maxDepth级
+func (s *referralService) GetTeamStat(uid, maxDepth int) (map[string]interface{}, error) {
+	teamSize, err := models.GetReferralDescendantCount(uid, 0)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := models.GetReferralDepthCounts(uid, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"team_size": teamSize,
+		"levels":    levels,
+	}, nil
+}
+
+// GetTeam 分页获取团队成员，depth大于0时只查该层级
+func (s *referralService) GetTeam(uid, depth, page, limit int) ([]structs.ReferralMember, int64, error) {
+	list, total, err := models.GetReferralTeam(uid, depth, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range list {
+		list[i].Userpic = helper.ToImg(list[i].Userpic)
+	}
+	return list, total, nil
+}
+
+// GetUpline 获取会员的上级链，最多referralUplineDepth级
+func (s *referralService) GetUpline(uid int) ([]structs.ReferralMember, error) {
+	list, err := models.GetReferralUpline(uid, config.GetInt("referralUplineDepth", 3))
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Userpic = helper.ToImg(list[i].Userpic)
+	}
+	return list, nil
+}
+
+// Backfill 根据会员表的pid重建推荐关系闭包表，最多referralMaxDepth级
+func (s *referralService) Backfill(weid int, reset bool) (int64, error) {
+	return models.BackfillMemberReferral(weid, reset, config.GetInt("referralMaxDepth", 100))
+}
+
+// GetInviteInfo 获取会员的邀请码、分享令牌、推荐人和直推人数，会员没有邀请码时生成一个
+func (s *referralService) GetInviteInfo(uid int) (map[string]interface{}, error) {
+	member, err := models.GetMemberByID(uid)
+	if err != nil || member == nil {
+		return nil, errors.New("会员不存在")
+	}
+	code, err := s.ensureInviteCode(member)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"invite_code": code,
+		"inviter":     nil,
+	}
+	if secret := shareSecret(); secret != "" {
+		expire := time.Now().Add(time.Duration(config.GetInt("referralShareTTL", 168)) * time.Hour).Unix()
+		result["share_token"] = SignShareToken(secret, uid, expire)
+		result["share_expire"] = expire
+	}
+	upline, err := models.GetReferralUpline(uid, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(upline) > 0 {
+		upline[0].Userpic = helper.ToImg(upline[0].Userpic)
+		result["inviter"] = upline[0]
+	}
+	inviteeCount, err := models.GetReferralDescendantCount(uid, 1)
+	if err != nil {
+		return nil, err
+	}
+	result["invitee_count"] = inviteeCount
+	return result, nil
+}
+
+// Bind 通过邀请码或分享令牌绑定推荐人，只能在注册后referralBindWindow小时内绑定，0为不限
+func (s *referralService) Bind(uid int, invite string) error {
+	pid, err := s.resolveInviter(strings.TrimSpace(invite))
+	if err != nil {
+		return err
+	}
+	window := int64(config.GetInt("referralBindWindow", 24)) * 3600
+	return models.BindMemberReferrer(uid, pid, window)
+}
+
+// resolveInviter 解析邀请码或分享令牌，返回邀请人ID
+func (s *referralService) resolveInviter(invite string) (int, error) {
+	if invite == "" {
+		return 0, errors.New("请输入邀请码")
+	}
+	if strings.Contains(invite, ".") {
+		uid, err := ParseShareToken(shareSecret(), invite, time.Now().Unix())
+		if err != nil {
+			return 0, err
+		}
+		return uid, nil
+	}
+
+	member, err := models.GetMemberByInviteCode(strings.ToUpper(invite))
+	if err != nil {
+		return 0, err
+	}
+	if member == nil {
+		return 0, errors.New("邀请码无效")
+	}
+	return member.ID, nil
+}
+
+// ensureInviteCode 返回会员的邀请码，没有时生成，邀请码重复时重试
+func (s *referralService) ensureInviteCode(member *structs.Member) (string, error) {
+	if member.InviteCode != "" {
+		return member.InviteCode, nil
+	}
+	length := config.GetInt("referralInviteCodeLength", 8)
+	for i := 0; i < 5; i++ {
+		code, err := newInviteCode(length)
+		if err != nil {
+			return "", err
+		}
+		err = models.SetMemberInviteCode(member.ID, code)
+		if err == models.ErrInviteCodeExists {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		// 并发生成时以数据库中的邀请码为准
+		saved, err := models.GetMemberByID(member.ID)
+		if err != nil || saved == nil {
+			return "", errors.New("会员不存在")
+		}
+		member.InviteCode = saved.InviteCode
+		return saved.InviteCode, nil
+	}
+	return "", errors.New("生成邀请码失败，请稍后重试")
+}
+
+// SignShareToken 生成分享令牌，格式为 会员ID.过期时间.签名，签名为HMAC-SHA256的前16字节
+func SignShareToken(secret string, uid int, expire int64) string {
+	payload := fmt.Sprintf("%d.%d", uid, expire)
+	return payload + "." + shareSignature(secret, payload)
+}
+
+// ParseShareToken 校验分享令牌的签名和有效期，返回邀请人ID
+func ParseShareToken(secret, token string, now int64) (int, error) {
+	if secret == "" {
+		return 0, errors.New("分享链接未启用")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, errors.New("分享链接无效")
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(shareSignature(secret, payload))) {
+		return 0, errors.New("分享链接无效")
+	}
+	uid, err := strconv.Atoi(parts[0])
+	if err != nil || uid <= 0 {
+		return 0, errors.New("分享链接无效")
+	}
+	expire, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || expire < now {
+		return 0, errors.New("分享链接已过期")
+	}
+	return uid, nil
+}
+
+// shareSignature 计算分享令牌签名
+func shareSignature(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// shareSecret 分享令牌签名密钥，未配置referralShareSecret时使用jwtSecret
+func shareSecret() string {
+	return config.GetString("referralShareSecret", config.GetString("jwtSecret", ""))
+}
+
+// newInviteCode 生成指定长度的随机邀请码
+func newInviteCode(length int) (string, error) {
+	if length < 6 {
+		length = 6
+	}
+	buf := make([]byte, length)
+	max := big.NewInt(int64(len(inviteCodeCharset)))
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		buf[i] = inviteCodeCharset[n.Int64()]
+	}
+	return string(buf), nil
+}
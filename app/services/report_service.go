@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"myapi/app/config"
+	"myapi/app/helper"
+	"myapi/app/models"
+	"myapi/app/structs"
+)
+
+// ReportService 收入报表服务接口，按月或按年汇总城市代理和团长的收入
+type ReportService interface {
+	MemberFilter(f *structs.IncomeReportFilter, uid, accountID int) error
+	GetIncomeSummary(f structs.IncomeReportFilter, page, limit int) (map[string]interface{}, error)
+	GetIncomeLines(f structs.IncomeReportFilter, page, limit int) (map[string]interface{}, error)
+	ExportIncome(f structs.IncomeReportFilter, format string, detail bool) (string, []byte, error)
+}
+
+// reportService 实现ReportService接口的结构体
+type reportService struct{}
+
+// NewReportService 创建一个新的收入报表服务实例
+func NewReportService() ReportService {
+	return &reportService{}
+}
+
+// MemberFilter 把报表范围限制在会员自己名下的代理或团长，accountID大于0时只查该代理
+func (s *reportService) MemberFilter(f *structs.IncomeReportFilter, uid, accountID int) error {
+	var ids []int
+	switch f.Kind {
+	case structs.ReportKindOperatingcity:
+		cities, err := models.GetOperatingcitiesByUid(f.Weid, uid)
+		if err != nil {
+			return err
+		}
+		for _, city := range cities {
+			if accountID <= 0 || city.ID == accountID {
+				ids = append(ids, city.ID)
+			}
+		}
+	case structs.ReportKindTuanzhang:
+		tuanzhang, err := models.GetTuanzhangByUid(f.Weid, uid)
+		if err != nil {
+			return err
+		}
+		if tuanzhang != nil && tuanzhang.Status == structs.TuanzhangApproved {
+			ids = append(ids, tuanzhang.ID)
+		}
+	default:
+		return errors.New("不支持的报表类型")
+	}
+	if len(ids) == 0 {
+		return errors.New("没有可查看的收入账户")
+	}
+	f.AccountIDs = ids
+	return nil
+}
+
+// GetIncomeSummary 获取按周期汇总的收入报表和合计
+func (s *reportService) GetIncomeSummary(f structs.IncomeReportFilter, page, limit int) (map[string]interface{}, error) {
+	if err := normalizeIncomeFilter(&f); err != nil {
+		return nil, err
+	}
+	list, total, err := models.GetIncomeReport(f, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := models.GetIncomeReportTotal(f)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"list":    list,
+		"total":   total,
+		"summary": summary,
+		"page":    page,
+		"limit":   limit,
+	}, nil
+}
+
+// GetIncomeLines 获取收入明细，用于从汇总行下钻
+func (s *reportService) GetIncomeLines(f structs.IncomeReportFilter, page, limit int) (map[string]interface{}, error) {
+	if err := normalizeIncomeFilter(&f); err != nil {
+		return nil, err
+	}
+
+	var list interface{}
+	var total int64
+	var err error
+	if f.Kind == structs.ReportKindOperatingcity {
+		list, total, err = models.GetOperatingcityIncomeLines(f, page, limit)
+	} else {
+		list, total, err = models.GetTuanzhangIncomeLines(f, page, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"list":  list,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	}, nil
+}
+
+// ExportIncome 导出收入报表，detail为true时导出明细，否则导出汇总，返回文件名和文件内容
+// 导出行数超过reportExportMaxRows时提示缩小范围
+func (s *reportService) ExportIncome(f structs.IncomeReportFilter, format string, detail bool) (string, []byte, error) {
+	if err := normalizeIncomeFilter(&f); err != nil {
+		return "", nil, err
+	}
+	if format != helper.ExportXLSX {
+		format = helper.ExportCSV
+	}
+	maxRows := int64(config.GetInt("reportExportMaxRows", 50000))
+
+	var header []string
+	var rows [][]interface{}
+	if detail {
+		header = []string{"ID", "账户ID", "订单号", "下单会员", "订单金额", "返点比例", "收入", "月份", "支付时间"}
+		if f.Kind == structs.ReportKindOperatingcity {
+			list, total, err := models.GetOperatingcityIncomeLines(f, 1, int(maxRows))
+			if err != nil {
+				return "", nil, err
+			}
+			if total > maxRows {
+				return "", nil, errors.New("导出数据过多，请缩小查询范围")
+			}
+			for _, item := range list {
+				rows = append(rows, []interface{}{item.ID, item.Ocid, item.OrderNumAlias, item.BuyerID, item.OrderTotal,
+					item.ReturnPercent, item.Income, item.MonthTime, helper.TimeFormat(item.PayTime, "2006-01-02 15:04:05")})
+			}
+		} else {
+			list, total, err := models.GetTuanzhangIncomeLines(f, 1, int(maxRows))
+			if err != nil {
+				return "", nil, err
+			}
+			if total > maxRows {
+				return "", nil, errors.New("导出数据过多，请缩小查询范围")
+			}
+			for _, item := range list {
+				rows = append(rows, []interface{}{item.ID, item.Tzid, item.OrderNumAlias, item.BuyerID, item.OrderTotal,
+					item.ReturnPercent, item.Income, item.MonthTime, helper.TimeFormat(item.PayTime, "2006-01-02 15:04:05")})
+			}
+		}
+	} else {
+		list, total, err := models.GetIncomeReport(f, 1, int(maxRows))
+		if err != nil {
+			return "", nil, err
+		}
+		if total > maxRows {
+			return "", nil, errors.New("导出数据过多，请缩小查询范围")
+		}
+		header = []string{"账户ID", "名称", "周期", "收入", "订单数", "明细条数"}
+		for _, item := range list {
+			rows = append(rows, []interface{}{item.AccountID, item.Title, item.Period, item.Income, item.Orders, item.Lines})
+		}
+	}
+
+	data, err := helper.ExportFile(format, "收入报表", header, rows)
+	if err != nil {
+		return "", nil, err
+	}
+	name := "income_" + f.Kind + "_" + f.Period
+	if detail {
+		name += "_detail"
+	}
+	return name + "_" + time.Now().Format("20060102150405") + "." + format, data, nil
+}
+
+// normalizeIncomeFilter 检查报表类型，补全汇总周期并校验起止周期格式
+func normalizeIncomeFilter(f *structs.IncomeReportFilter) error {
+	if f.Kind != structs.ReportKindOperatingcity && f.Kind != structs.ReportKindTuanzhang {
+		return errors.New("不支持的报表类型")
+	}
+	layout := "2006-01"
+	switch f.Period {
+	case "", structs.ReportPeriodMonth:
+		f.Period = structs.ReportPeriodMonth
+	case structs.ReportPeriodYear:
+		layout = "2006"
+	default:
+		return errors.New("汇总周期只能是month或year")
+	}
+	for _, v := range []string{f.From, f.To} {
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse(layout, v); err != nil {
+			return errors.New("起止周期格式错误，应为" + layout)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,422 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"myapi/app/config"
+	"myapi/app/helper"
+	"myapi/app/models"
+	"myapi/app/structs"
+)
+
+// 商品搜索各字段的权重
+const (
+	searchNameWeight    = 3.0 // 商品名称
+	searchKeywordWeight = 2.0 // 商品关键词
+	searchPrefixFactor  = 0.8 // 英文按前缀命中时的折扣
+	searchPinyinWeight  = 1.0 // 拼音首字母命中
+)
+
+// searchDoc 索引中的商品文档
+type searchDoc struct {
+	product  structs.SearchProduct
+	initials string
+	terms    map[string]float64
+}
+
+// productSearchIndex 内存中的商品倒排索引，postings为检索词到商品ID和权重的映射
+type productSearchIndex struct {
+	mu       sync.RWMutex
+	docs     map[int]*searchDoc
+	postings map[string]map[int]float64
+	builtAt  time.Time
+}
+
+var (
+	searchIndex      = newProductSearchIndex()
+	searchRebuilding int32
+	searchBuildMu    sync.Mutex
+)
+
+// newProductSearchIndex 创建空索引
+func newProductSearchIndex() *productSearchIndex {
+	return &productSearchIndex{
+		docs:     make(map[int]*searchDoc),
+		postings: make(map[string]map[int]float64),
+	}
+}
+
+// newSearchDoc 对商品名称和关键词分词，生成索引文档
+func newSearchDoc(product structs.SearchProduct) *searchDoc {
+	doc := &searchDoc{
+		product:  product,
+		initials: helper.PinyinInitials(product.Name),
+		terms:    make(map[string]float64),
+	}
+	for _, term := range helper.SegmentText(product.Name) {
+		doc.terms[term] += searchNameWeight
+	}
+	for _, term := range helper.SegmentText(product.Keyword) {
+		doc.terms[term] += searchKeywordWeight
+	}
+	return doc
+}
+
+// addLocked 把文档写入倒排表，调用方需持有写锁
+func (idx *productSearchIndex) addLocked(doc *searchDoc) {
+	idx.docs[doc.product.ID] = doc
+	for term, weight := range doc.terms {
+		posting, ok := idx.postings[term]
+		if !ok {
+			posting = make(map[int]float64)
+			idx.postings[term] = posting
+		}
+		posting[doc.product.ID] = weight
+	}
+}
+
+// removeLocked 从倒排表删除文档，调用方需持有写锁
+func (idx *productSearchIndex) removeLocked(id int) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		if posting, ok := idx.postings[term]; ok {
+			delete(posting, id)
+			if len(posting) == 0 {
+				delete(idx.postings, term)
+			}
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// put 新增或替换单个商品
+func (idx *productSearchIndex) put(product structs.SearchProduct) {
+	doc := newSearchDoc(product)
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(product.ID)
+	idx.addLocked(doc)
+}
+
+// remove 删除单个商品
+func (idx *productSearchIndex) remove(id int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(id)
+}
+
+// replace 用全量商品重建索引，先在锁外构建再整体替换，不阻塞搜索
+func (idx *productSearchIndex) replace(products []structs.SearchProduct) {
+	fresh := newProductSearchIndex()
+	for _, product := range products {
+		fresh.addLocked(newSearchDoc(product))
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.docs = fresh.docs
+	idx.postings = fresh.postings
+	idx.builtAt = time.Now()
+}
+
+// age 距上次全量重建的时间，未建立时返回false
+func (idx *productSearchIndex) age() (time.Duration, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if idx.builtAt.IsZero() {
+		return 0, false
+	}
+	return time.Since(idx.builtAt), true
+}
+
+// matchTerm 查找命中检索词的商品，英文和数字按前缀匹配
+func (idx *productSearchIndex) matchTerm(term string) map[int]float64 {
+	if !isASCIIWord(term) {
+		return idx.postings[term]
+	}
+	hits := make(map[int]float64)
+	for indexed, posting := range idx.postings {
+		if !strings.HasPrefix(indexed, term) {
+			continue
+		}
+		factor := 1.0
+		if indexed != term {
+			factor = searchPrefixFactor
+		}
+		for id, weight := range posting {
+			if score := weight * factor; score > hits[id] {
+				hits[id] = score
+			}
+		}
+	}
+	return hits
+}
+
+// search 检索站点内的商品，所有检索词都命中才算匹配，纯字母搜索词同时按拼音首字母匹配
+func (idx *productSearchIndex) search(weid int, terms []string, initials string, salesWeight float64) []structs.SearchHit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var scores map[int]float64
+	for i, term := range terms {
+		hits := idx.matchTerm(term)
+		if i == 0 {
+			scores = make(map[int]float64, len(hits))
+			for id, weight := range hits {
+				scores[id] = weight
+			}
+			continue
+		}
+		for id := range scores {
+			if weight, ok := hits[id]; ok {
+				scores[id] += weight
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	if scores == nil {
+		scores = make(map[int]float64)
+	}
+
+	if initials != "" {
+		for id, doc := range idx.docs {
+			if pos := strings.Index(doc.initials, initials); pos == 0 {
+				scores[id] += searchPinyinWeight * 1.5
+			} else if pos > 0 {
+				scores[id] += searchPinyinWeight
+			}
+		}
+	}
+
+	hits := make([]structs.SearchHit, 0, len(scores))
+	for id, relevance := range scores {
+		doc := idx.docs[id]
+		if doc == nil || (weid > 0 && doc.product.Weid != weid) {
+			continue
+		}
+		hits = append(hits, structs.SearchHit{
+			SearchProduct: doc.product,
+			Score:         SearchScore(relevance, doc.product.SaleCount+doc.product.SaleCountBase, salesWeight),
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	return hits
+}
+
+// suggest 返回名称或关键词包含输入内容、或名称拼音首字母以输入内容开头的词，按销量排序
+func (idx *productSearchIndex) suggest(weid int, prefix string, limit int) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	lowerPrefix := strings.ToLower(prefix)
+	weights := make(map[string]int)
+	for _, doc := range idx.docs {
+		if weid > 0 && doc.product.Weid != weid {
+			continue
+		}
+		sales := doc.product.SaleCount + doc.product.SaleCountBase + 1
+		name := strings.TrimSpace(doc.product.Name)
+		if strings.Contains(strings.ToLower(name), lowerPrefix) || strings.HasPrefix(doc.initials, lowerPrefix) {
+			weights[name] += sales
+		}
+		for _, keyword := range strings.FieldsFunc(doc.product.Keyword, isKeywordSeparator) {
+			if strings.Contains(strings.ToLower(keyword), lowerPrefix) {
+				weights[keyword] += sales
+			}
+		}
+	}
+
+	words := make([]string, 0, len(weights))
+	for word := range weights {
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if weights[words[i]] != weights[words[j]] {
+			return weights[words[i]] > weights[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	if len(words) > limit {
+		words = words[:limit]
+	}
+	return words
+}
+
+// isASCIIWord 判断检索词是否只包含英文字母和数字
+func isASCIIWord(term string) bool {
+	for _, r := range term {
+		if r >= unicode.MaxASCII {
+			return false
+		}
+	}
+	return term != ""
+}
+
+// isKeywordSeparator 商品关键词的分隔符
+func isKeywordSeparator(r rune) bool {
+	return r == ',' || r == '，' || r == '、' || r == '|' || unicode.IsSpace(r)
+}
+
+// SearchScore 计算搜索排序分，在相关度基础上按销量的对数加权
+func SearchScore(relevance float64, sales int, salesWeight float64) float64 {
+	if sales < 0 {
+		sales = 0
+	}
+	return relevance * (1 + salesWeight*math.Log1p(float64(sales)))
+}
+
+// SearchService 商品搜索服务接口
+type SearchService interface {
+	Search(weid int, keyword string, page, limit int) (*structs.SearchResult, error)
+	Suggest(weid int, keyword string, limit int) ([]string, error)
+	Refresh(id int) error
+	Remove(id int)
+	Rebuild() (int, error)
+}
+
+// searchService 实现SearchService接口的结构体
+type searchService struct{}
+
+// NewSearchService 创建商品搜索服务实例
+func NewSearchService() SearchService {
+	return &searchService{}
+}
+
+// Search 搜索商品，按相关度和销量排序并高亮商品名称
+func (s *searchService) Search(weid int, keyword string, page, limit int) (*structs.SearchResult, error) {
+	terms := helper.SegmentQuery(keyword)
+	if len(terms) == 0 {
+		return nil, errors.New("请输入搜索关键词")
+	}
+	if err := s.ensureIndex(); err != nil {
+		return nil, err
+	}
+
+	// 只有字母时按拼音首字母匹配，如"pg"匹配"苹果"
+	initials := ""
+	if compact := strings.Join(terms, ""); len(terms) == 1 && isASCIIWord(compact) && len(compact) >= 2 {
+		initials = compact
+	}
+	salesWeight := config.GetFloat("searchSalesWeight", 0.1)
+	hits := searchIndex.search(weid, terms, initials, salesWeight)
+
+	result := &structs.SearchResult{Total: len(hits), Page: page, Limit: limit, List: []structs.SearchHit{}}
+	start := (page - 1) * limit
+	if start >= len(hits) {
+		return result, nil
+	}
+	end := start + limit
+	if end > len(hits) {
+		end = len(hits)
+	}
+	result.List = hits[start:end]
+	for i := range result.List {
+		result.List[i].Highlight = helper.HighlightText(result.List[i].Name, terms)
+		result.List[i].Image = helper.ToImg(result.List[i].Image)
+	}
+	return result, nil
+}
+
+// Suggest 搜索联想词
+func (s *searchService) Suggest(weid int, keyword string, limit int) ([]string, error) {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return []string{}, nil
+	}
+	if err := s.ensureIndex(); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = config.GetInt("searchSuggestLimit", 10)
+	}
+	return searchIndex.suggest(weid, keyword, limit), nil
+}
+
+// Refresh 商品修改后更新索引，已下架或删除的商品从索引中移除，索引未建立时跳过
+func (s *searchService) Refresh(id int) error {
+	if id <= 0 {
+		return nil
+	}
+	if _, built := searchIndex.age(); !built {
+		return nil
+	}
+	product, err := models.GetSearchableProduct(id)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		searchIndex.remove(id)
+		return nil
+	}
+	searchIndex.put(*product)
+	return nil
+}
+
+// Remove 从索引中删除商品
+func (s *searchService) Remove(id int) {
+	searchIndex.remove(id)
+}
+
+// Rebuild 从数据库全量重建索引，返回索引的商品数
+func (s *searchService) Rebuild() (int, error) {
+	searchBuildMu.Lock()
+	defer searchBuildMu.Unlock()
+	return s.rebuildLocked()
+}
+
+// rebuildLocked 全量重建索引，调用方需持有searchBuildMu
+func (s *searchService) rebuildLocked() (int, error) {
+	products, err := models.GetSearchableProducts(0)
+	if err != nil {
+		return 0, err
+	}
+	searchIndex.replace(products)
+	log.Printf("商品搜索索引重建完成，共%d个商品", len(products))
+	return len(products), nil
+}
+
+// ensureIndex 首次搜索时同步建立索引，超过重建间隔后在后台重建，期间继续使用旧索引
+func (s *searchService) ensureIndex() error {
+	age, built := searchIndex.age()
+	if !built {
+		searchBuildMu.Lock()
+		defer searchBuildMu.Unlock()
+		// 等锁期间可能已由其他请求建好
+		if _, built := searchIndex.age(); built {
+			return nil
+		}
+		_, err := s.rebuildLocked()
+		return err
+	}
+	interval := time.Duration(config.GetInt("searchRebuildInterval", 600)) * time.Second
+	if interval <= 0 || age < interval {
+		return nil
+	}
+	if atomic.CompareAndSwapInt32(&searchRebuilding, 0, 1) {
+		go func() {
+			defer atomic.StoreInt32(&searchRebuilding, 0)
+			if _, err := s.Rebuild(); err != nil {
+				log.Printf("后台重建商品搜索索引失败: %v", err)
+			}
+		}()
+	}
+	return nil
+}
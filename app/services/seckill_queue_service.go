@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"myapi/app/config"
+	"myapi/app/helper"
+	"myapi/app/storage"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 秒杀排队状态
+const (
+	SeckillTicketQueued     = "queued"     // 排队中
+	SeckillTicketProcessing = "processing" // 处理中
+	SeckillTicketSuccess    = "success"    // 抢购成功
+	SeckillTicketFailed     = "failed"     // 抢购失败
+)
+
+// seckillQueueGroup 秒杀排队stream的消费组
+const seckillQueueGroup = "seckill"
+
+// SeckillQueueRequest 秒杀排队请求，字段与立即购买参数一致
+type SeckillQueueRequest struct {
+	Weid      int    `json:"weid"`
+	Uid       int    `json:"uid"`
+	GoodsID   int64  `json:"goods_id"`
+	Msid      int64  `json:"msid"`
+	Sku       string `json:"sku"`
+	BuyNumber int64  `json:"buy_number"`
+	AddressID int    `json:"address_id"`
+	Ip        string `json:"ip"`
+}
+
+// SeckillTicket 秒杀排队凭证状态
+type SeckillTicket struct {
+	Ticket string      `json:"ticket"`
+	Status string      `json:"status"`
+	Code   int         `json:"code"`
+	Msg    string      `json:"msg"`
+	Result interface{} `json:"result,omitempty"`
+}
+
+// SeckillQueueService 秒杀排队服务接口
+type SeckillQueueService interface {
+	Enqueue(req *SeckillQueueRequest) (*SeckillTicket, error)
+	GetTicket(uid int, ticket string) (*SeckillTicket, error)
+	Admitted(uid int, msid int64, ticket string) bool
+	Consume(ctx context.Context) error
+}
+
+// seckillQueueService 实现SeckillQueueService接口的结构体
+type seckillQueueService struct {
+	prefix string
+	ctx    context.Context
+}
+
+// NewSeckillQueueService 创建一个新的秒杀排队服务实例
+func NewSeckillQueueService() SeckillQueueService {
+	return &seckillQueueService{
+		prefix: config.GetString("redisPrefix", ""),
+		ctx:    context.Background(),
+	}
+}
+
+// key 构建秒杀排队相关的Redis键
+func (s *seckillQueueService) key(name string, args ...interface{}) string {
+	return s.prefix + ":miaosha:" + fmt.Sprintf(name, args...)
+}
+
+// Enqueue 秒杀请求进入排队，返回排队凭证
+// 活动已抢光或排队人数已满时直接拒绝，同一会员在同一活动中只保留一个排队凭证
+// Redis未启用时直接处理请求
+func (s *seckillQueueService) Enqueue(req *SeckillQueueRequest) (*SeckillTicket, error) {
+	if req.Uid <= 0 {
+		return nil, errors.New("请先登录")
+	}
+	if req.Msid <= 0 || req.GoodsID <= 0 || req.BuyNumber <= 0 {
+		return nil, errors.New("参数无效")
+	}
+
+	rdb := storage.GetRedis()
+	if rdb == nil {
+		ticket := &SeckillTicket{}
+		s.handle(ticket, req)
+		return ticket, nil
+	}
+
+	if stock, _, loaded := NewSeckillService().GetStock(int(req.Msid)); loaded && stock == 0 {
+		return nil, ErrSeckillSoldOut
+	}
+	// 只在入队前按长度拒绝，不裁剪stream，避免已排队未消费的请求被静默丢弃
+	maxLen := int64(config.GetInt("miaoshaQueueMaxLen", 10000))
+	if size, err := rdb.XLen(s.ctx, s.key("queue")).Result(); err == nil && size >= maxLen {
+		return nil, helper.NewBizError(helper.CodeSeckillQueueFull, "排队人数过多，请稍后再试")
+	}
+
+	ticketID, err := newSeckillToken()
+	if err != nil {
+		return nil, err
+	}
+	ttl := time.Duration(config.GetInt("miaoshaTicketTTL", 600)) * time.Second
+
+	// 已在排队中时返回原凭证
+	queuingKey := s.key("queuing:%d:%d", req.Msid, req.Uid)
+	ok, err := rdb.SetNX(s.ctx, queuingKey, ticketID, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		existing, err := rdb.Get(s.ctx, queuingKey).Result()
+		if err == nil {
+			return s.GetTicket(req.Uid, existing)
+		}
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	ticketKey := s.key("ticket:%s", ticketID)
+	pipe := rdb.TxPipeline()
+	pipe.HSet(s.ctx, ticketKey, "uid", req.Uid, "msid", req.Msid, "status", SeckillTicketQueued)
+	pipe.Expire(s.ctx, ticketKey, ttl)
+	pipe.XAdd(s.ctx, &redis.XAddArgs{
+		Stream: s.key("queue"),
+		Values: map[string]interface{}{"ticket": ticketID, "request": string(data)},
+	})
+	if _, err := pipe.Exec(s.ctx); err != nil {
+		rdb.Del(s.ctx, queuingKey)
+		log.Printf("秒杀请求排队失败: %v", err)
+		return nil, errors.New("秒杀繁忙，请稍后再试")
+	}
+
+	return &SeckillTicket{Ticket: ticketID, Status: SeckillTicketQueued, Msg: "排队中"}, nil
+}
+
+// GetTicket 查询排队凭证的处理结果
+func (s *seckillQueueService) GetTicket(uid int, ticket string) (*SeckillTicket, error) {
+	rdb := storage.GetRedis()
+	if rdb == nil {
+		return nil, errors.New("排队凭证不存在")
+	}
+
+	values, err := rdb.HGetAll(s.ctx, s.key("ticket:%s", ticket)).Result()
+	if err != nil || len(values) == 0 || values["uid"] != fmt.Sprint(uid) {
+		return nil, errors.New("排队凭证不存在或已过期")
+	}
+
+	result := &SeckillTicket{Ticket: ticket, Status: values["status"], Msg: values["msg"]}
+	fmt.Sscan(values["code"], &result.Code)
+	if values["result"] != "" {
+		var data interface{}
+		if err := json.Unmarshal([]byte(values["result"]), &data); err == nil {
+			result.Result = data
+		}
+	}
+	if result.Msg == "" && result.Status == SeckillTicketQueued {
+		result.Msg = "排队中"
+	}
+	return result, nil
+}
+
+// Admitted 判断会员的排队凭证是否已被消费者放行抢购该秒杀活动
+// Redis未启用时没有排队，直接放行
+func (s *seckillQueueService) Admitted(uid int, msid int64, ticket string) bool {
+	rdb := storage.GetRedis()
+	if rdb == nil {
+		return true
+	}
+	if ticket == "" {
+		return false
+	}
+
+	values, err := rdb.HGetAll(s.ctx, s.key("ticket:%s", ticket)).Result()
+	if err != nil || len(values) == 0 {
+		return false
+	}
+	return values["uid"] == fmt.Sprint(uid) && values["msid"] == fmt.Sprint(msid) && values["status"] == SeckillTicketSuccess
+}
+
+// Consume 按配置的速率消费排队请求，直到ctx结束
+// 启动时先处理本消费者未确认的消息，避免重启丢失请求
+func (s *seckillQueueService) Consume(ctx context.Context) error {
+	rdb := storage.GetRedis()
+	if rdb == nil {
+		return errors.New("redis not initialized")
+	}
+
+	stream := s.key("queue")
+	if err := rdb.XGroupCreateMkStream(ctx, stream, seckillQueueGroup, "0").Err(); err != nil && !isBusyGroupErr(err) {
+		return err
+	}
+	consumer, _ := os.Hostname()
+	if consumer == "" {
+		consumer = "default"
+	}
+
+	rate := config.GetInt("miaoshaQueueRate", 50)
+	if rate <= 0 {
+		rate = 50
+	}
+	limiter := time.NewTicker(time.Second / time.Duration(rate))
+	defer limiter.Stop()
+
+	lastID := "0"
+	for ctx.Err() == nil {
+		streams, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    seckillQueueGroup,
+			Consumer: consumer,
+			Streams:  []string{stream, lastID},
+			Count:    int64(rate),
+			Block:    2 * time.Second,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("读取秒杀排队请求失败: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		messages := 0
+		for _, st := range streams {
+			for _, message := range st.Messages {
+				messages++
+				<-limiter.C
+				s.process(message)
+				rdb.XAck(s.ctx, stream, seckillQueueGroup, message.ID)
+				rdb.XDel(s.ctx, stream, message.ID)
+			}
+		}
+		// 未确认的消息处理完后开始读取新消息
+		if lastID == "0" && messages == 0 {
+			lastID = ">"
+		}
+	}
+	return nil
+}
+
+// process 处理一条排队消息，把结果写入排队凭证
+func (s *seckillQueueService) process(message redis.XMessage) {
+	rdb := storage.GetRedis()
+	ticketID, _ := message.Values["ticket"].(string)
+	data, _ := message.Values["request"].(string)
+	if ticketID == "" {
+		return
+	}
+	ticketKey := s.key("ticket:%s", ticketID)
+
+	var req SeckillQueueRequest
+	if err := json.Unmarshal([]byte(data), &req); err != nil {
+		log.Printf("解析秒杀排队请求失败: %v", err)
+		rdb.HSet(s.ctx, ticketKey, "status", SeckillTicketFailed, "code", helper.CodeError, "msg", "请求无效")
+		return
+	}
+	defer rdb.Del(s.ctx, s.key("queuing:%d:%d", req.Msid, req.Uid))
+
+	// 凭证已过期说明客户端已放弃，不再处理
+	if exists, _ := rdb.Exists(s.ctx, ticketKey).Result(); exists == 0 {
+		return
+	}
+	rdb.HSet(s.ctx, ticketKey, "status", SeckillTicketProcessing)
+
+	// 排队期间已抢光的请求直接失败，不再访问数据库
+	ticket := &SeckillTicket{Ticket: ticketID}
+	if stock, _, loaded := NewSeckillService().GetStock(int(req.Msid)); loaded && stock == 0 {
+		ticket.Status = SeckillTicketFailed
+		ticket.Code = helper.CodeSeckillSoldOut
+		ticket.Msg = ErrSeckillSoldOut.Error()
+	} else {
+		s.handle(ticket, &req)
+	}
+
+	fields := []interface{}{"status", ticket.Status, "code", ticket.Code, "msg", ticket.Msg}
+	if ticket.Result != nil {
+		if result, err := json.Marshal(ticket.Result); err == nil {
+			fields = append(fields, "result", string(result))
+		}
+	}
+	rdb.HSet(s.ctx, ticketKey, fields...)
+}
+
+// handle 执行立即购买流程，流程中会原子预留秒杀库存
+func (s *seckillQueueService) handle(ticket *SeckillTicket, req *SeckillQueueRequest) {
+	params := map[string]interface{}{
+		"Msid":       req.Msid,
+		"Tuanid":     int64(0),
+		"Tecid":      int64(0),
+		"Jointuanid": int64(0),
+		"Sku":        req.Sku,
+		"IsSkumore":  0,
+		"Skumore":    0,
+		"BuyNumber":  req.BuyNumber,
+		"GoodsID":    req.GoodsID,
+		"AddressID":  req.AddressID,
+		"Uid":        req.Uid,
+		"Weid":       req.Weid,
+		"Ip":         req.Ip,
+	}
+
+	result, err := NewProductService().GetBuyNowInfo(params)
+	if err != nil {
+		ticket.Status = SeckillTicketFailed
+		ticket.Code = helper.ErrorCode(err)
+		ticket.Msg = err.Error()
+		return
+	}
+	ticket.Status = SeckillTicketSuccess
+	ticket.Msg = "抢购成功，请尽快支付"
+	ticket.Result = result
+}
+
+// isBusyGroupErr 判断是否为消费组已存在的错误
+func isBusyGroupErr(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP")
+}
@@ -0,0 +1,554 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"myapi/app/config"
+	"myapi/app/helper"
+	"myapi/app/models"
+	"myapi/app/storage"
+	"myapi/app/structs"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 秒杀库存预留错误
+var (
+	ErrSeckillSoldOut     = helper.NewBizError(helper.CodeSeckillSoldOut, "秒杀商品库存不足")
+	ErrSeckillSkuNotFound = helper.NewBizError(helper.CodeSeckillSkuNotFound, "未找到匹配的秒杀SKU")
+)
+
+// seckillMemberLimitError 超过每人限购数量的错误，提示中带上限购数量
+func seckillMemberLimitError(limit int) error {
+	return helper.NewBizError(helper.CodeSeckillMemberLimit, fmt.Sprintf("该秒杀商品每人限购%d件", limit))
+}
+
+// seckillNoSkuField 没有SKU的秒杀活动在库存hash中使用的字段，库存-1表示不限量
+const seckillNoSkuField = "0"
+
+// seckillReserveScript 原子地检查并扣减SKU库存和会员购买数量，同一会员重复预留时先归还上一次的预留
+// KEYS: 库存hash, 会员购买数hash, 预留hash, 预留到期zset, 待同步set
+// ARGV: SKU字段, 数量, 每人限购(0不限), 预留令牌, 到期时间戳, 会员ID, zset成员, 秒杀ID
+var seckillReserveScript = redis.NewScript(`
+local stock = redis.call('HGET', KEYS[1], ARGV[1])
+if not stock then return -3 end
+stock = tonumber(stock)
+local qty = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local bought = tonumber(redis.call('HGET', KEYS[2], ARGV[6]) or '0')
+local oldField = redis.call('HGET', KEYS[3], 'field')
+local oldQty = tonumber(redis.call('HGET', KEYS[3], 'qty') or '0')
+local available = stock
+if oldField == ARGV[1] then available = stock + oldQty end
+if stock >= 0 and available < qty then return -1 end
+if limit > 0 and bought - oldQty + qty > limit then return -2 end
+if oldField then
+	local oldStock = tonumber(redis.call('HGET', KEYS[1], oldField) or '-1')
+	if oldStock >= 0 then redis.call('HINCRBY', KEYS[1], oldField, oldQty) end
+	redis.call('HINCRBY', KEYS[2], ARGV[6], -oldQty)
+end
+if stock >= 0 then redis.call('HINCRBY', KEYS[1], ARGV[1], -qty) end
+redis.call('HINCRBY', KEYS[2], ARGV[6], qty)
+redis.call('DEL', KEYS[3])
+redis.call('HSET', KEYS[3], 'field', ARGV[1], 'qty', qty, 'token', ARGV[4])
+redis.call('EXPIREAT', KEYS[3], tonumber(ARGV[5]) + 86400)
+redis.call('ZADD', KEYS[4], ARGV[5], ARGV[7])
+redis.call('SADD', KEYS[5], ARGV[8])
+return 1
+`)
+
+// seckillReleaseScript 归还预留的库存和会员购买数量
+// KEYS: 库存hash, 会员购买数hash, 预留hash, 预留到期zset, 待同步set
+// ARGV: 会员ID, zset成员, 秒杀ID, 到期截止时间(空表示不检查到期)
+var seckillReleaseScript = redis.NewScript(`
+if ARGV[4] ~= '' then
+	local score = redis.call('ZSCORE', KEYS[4], ARGV[2])
+	if score and tonumber(score) > tonumber(ARGV[4]) then return 0 end
+end
+redis.call('ZREM', KEYS[4], ARGV[2])
+local field = redis.call('HGET', KEYS[3], 'field')
+if not field then return 0 end
+local qty = tonumber(redis.call('HGET', KEYS[3], 'qty'))
+local stock = tonumber(redis.call('HGET', KEYS[1], field) or '-1')
+if stock >= 0 then redis.call('HINCRBY', KEYS[1], field, qty) end
+redis.call('HINCRBY', KEYS[2], ARGV[1], -qty)
+redis.call('DEL', KEYS[3])
+redis.call('SADD', KEYS[5], ARGV[3])
+return qty
+`)
+
+// seckillConfirmScript 支付成功后把预留转为销量；预留已超时释放时按订单重新检查并扣减库存和会员购买数量
+// KEYS: 预留hash, 预留到期zset, 销量hash, 待同步set, 库存hash, 会员购买数hash
+// ARGV: zset成员, 秒杀ID, 订单SKU字段, 订单数量, 每人限购(0不限), 会员ID
+var seckillConfirmScript = redis.NewScript(`
+local field = redis.call('HGET', KEYS[1], 'field')
+if field then
+	local qty = tonumber(redis.call('HGET', KEYS[1], 'qty'))
+	redis.call('HINCRBY', KEYS[3], field, qty)
+	redis.call('DEL', KEYS[1])
+	redis.call('ZREM', KEYS[2], ARGV[1])
+	redis.call('SADD', KEYS[4], ARGV[2])
+	return qty
+end
+local qty = tonumber(ARGV[4])
+if qty <= 0 then return 0 end
+local stock = redis.call('HGET', KEYS[5], ARGV[3])
+if not stock then return -3 end
+stock = tonumber(stock)
+local limit = tonumber(ARGV[5])
+local bought = tonumber(redis.call('HGET', KEYS[6], ARGV[6]) or '0')
+if stock >= 0 and stock < qty then return -1 end
+if limit > 0 and bought + qty > limit then return -2 end
+if stock >= 0 then redis.call('HINCRBY', KEYS[5], ARGV[3], -qty) end
+redis.call('HINCRBY', KEYS[6], ARGV[6], qty)
+redis.call('HINCRBY', KEYS[3], ARGV[3], qty)
+redis.call('SADD', KEYS[4], ARGV[2])
+return qty
+`)
+
+// SeckillReservation 秒杀库存预留结果
+type SeckillReservation struct {
+	Token    string `json:"token"`
+	MsID     int    `json:"ms_id"`
+	Quantity int    `json:"quantity"`
+	ExpireAt int64  `json:"expire_at"`
+}
+
+// SeckillService 秒杀库存服务接口
+type SeckillService interface {
+	Preload(miaosha *structs.MiaoshaGoods) error
+	PreloadUpcoming() error
+	Reserve(uid int, miaosha *structs.MiaoshaGoods, sku string, quantity int) (*SeckillReservation, error)
+	CheckReservation(uid, msID int, token string) error
+	Confirm(order *structs.Order) error
+	Release(uid, msID int) error
+	ReleaseExpired() (int, error)
+	GetStock(msID int) (stock int, sold int, loaded bool)
+	Reconcile() error
+}
+
+// seckillService 实现SeckillService接口的结构体
+type seckillService struct {
+	prefix string
+	ctx    context.Context
+}
+
+// NewSeckillService 创建一个新的秒杀库存服务实例
+func NewSeckillService() SeckillService {
+	return &seckillService{
+		prefix: config.GetString("redisPrefix", ""),
+		ctx:    context.Background(),
+	}
+}
+
+// key 构建秒杀相关的Redis键
+func (s *seckillService) key(parts ...interface{}) string {
+	items := make([]string, 0, len(parts))
+	for _, part := range parts {
+		items = append(items, fmt.Sprint(part))
+	}
+	return s.prefix + ":miaosha:" + strings.Join(items, ":")
+}
+
+// Preload 把秒杀活动库存加载到Redis，已加载的活动不会重复加载
+func (s *seckillService) Preload(miaosha *structs.MiaoshaGoods) error {
+	rdb := storage.GetRedis()
+	if rdb == nil {
+		return errors.New("redis not initialized")
+	}
+
+	loadedKey := s.key("loaded", miaosha.ID)
+	expireAt := time.Unix(int64(miaosha.EndDate), 0).Add(24 * time.Hour)
+	ok, err := rdb.SetNX(s.ctx, loadedKey, 1, time.Until(expireAt)).Result()
+	if err != nil || !ok {
+		return err
+	}
+
+	skuValues, err := models.GetMiaoshaSkuValues(miaosha.ID)
+	if err != nil {
+		rdb.Del(s.ctx, loadedKey)
+		return err
+	}
+	stocks := make(map[string]interface{}, len(skuValues))
+	for _, skuValue := range skuValues {
+		stocks[strconv.Itoa(skuValue.ID)] = skuValue.Quantity
+	}
+	if len(stocks) == 0 {
+		stocks[seckillNoSkuField] = -1
+	}
+
+	// 销量以数据库已有销量为基数，会员购买数hash先写入占位字段以便设置过期时间
+	stockKey := s.key("stock", miaosha.ID)
+	soldKey := s.key("sold", miaosha.ID)
+	memberKey := s.key("member", miaosha.ID)
+	pipe := rdb.TxPipeline()
+	pipe.Del(s.ctx, stockKey, soldKey)
+	pipe.HSet(s.ctx, stockKey, stocks)
+	pipe.HSet(s.ctx, soldKey, "base", miaosha.SaleCount)
+	pipe.HSetNX(s.ctx, memberKey, "0", 0)
+	for _, key := range []string{stockKey, soldKey, memberKey} {
+		pipe.ExpireAt(s.ctx, key, expireAt)
+	}
+	if _, err := pipe.Exec(s.ctx); err != nil {
+		rdb.Del(s.ctx, loadedKey)
+		return err
+	}
+	log.Printf("秒杀活动%d库存已预热: %v", miaosha.ID, stocks)
+	return nil
+}
+
+// PreloadUpcoming 预热即将开始和进行中的秒杀活动
+func (s *seckillService) PreloadUpcoming() error {
+	ahead := config.GetInt("miaoshaPreloadAhead", 300)
+	list, err := models.GetStartingMiaoshaGoods(time.Now().Unix() + int64(ahead))
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		if err := s.Preload(&list[i]); err != nil {
+			log.Printf("预热秒杀活动%d库存失败: %v", list[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// Reserve 预留秒杀库存并占用会员限购数量，预留在支付超时后自动释放
+// Redis未启用时只按数据库订单检查限购，不做预留，返回nil
+func (s *seckillService) Reserve(uid int, miaosha *structs.MiaoshaGoods, sku string, quantity int) (*SeckillReservation, error) {
+	if uid <= 0 {
+		return nil, errors.New("请先登录")
+	}
+	if quantity <= 0 {
+		return nil, errors.New("购买数量无效")
+	}
+
+	rdb := storage.GetRedis()
+	if rdb == nil {
+		log.Println("Redis未启用，秒杀库存不做预留")
+		return nil, s.checkMemberLimitByOrders(uid, miaosha, quantity)
+	}
+
+	if err := s.Preload(miaosha); err != nil {
+		return nil, err
+	}
+	if err := s.seedMemberBought(uid, miaosha); err != nil {
+		return nil, err
+	}
+
+	field := seckillNoSkuField
+	if sku != "" {
+		skuValue, err := models.FindMiaoshaSkuValue(miaosha.ID, miaosha.GoodsID, sku)
+		if err != nil {
+			return nil, ErrSeckillSkuNotFound
+		}
+		field = strconv.Itoa(skuValue.ID)
+	}
+
+	token, err := newSeckillToken()
+	if err != nil {
+		return nil, err
+	}
+	expireAt := time.Now().Unix() + int64(config.GetInt("miaoshaPayTimeout", 900))
+	member := fmt.Sprintf("%d:%d", miaosha.ID, uid)
+
+	keys := []string{
+		s.key("stock", miaosha.ID),
+		s.key("member", miaosha.ID),
+		s.key("reserve", miaosha.ID, uid),
+		s.key("reserves"),
+		s.key("dirty"),
+	}
+	code, err := seckillReserveScript.Run(s.ctx, rdb, keys,
+		field, quantity, miaosha.MemberBuyMax, token, expireAt, uid, member, miaosha.ID).Int()
+	if err != nil {
+		log.Printf("预留秒杀库存失败: %v", err)
+		return nil, errors.New("秒杀繁忙，请稍后再试")
+	}
+
+	switch code {
+	case -1:
+		return nil, ErrSeckillSoldOut
+	case -2:
+		return nil, seckillMemberLimitError(miaosha.MemberBuyMax)
+	case -3:
+		return nil, ErrSeckillSkuNotFound
+	}
+
+	return &SeckillReservation{
+		Token:    token,
+		MsID:     miaosha.ID,
+		Quantity: quantity,
+		ExpireAt: expireAt,
+	}, nil
+}
+
+// seedMemberBought 首次预留前用数据库订单中的购买数量初始化会员已购数，包括待付款订单
+func (s *seckillService) seedMemberBought(uid int, miaosha *structs.MiaoshaGoods) error {
+	if miaosha.MemberBuyMax <= 0 {
+		return nil
+	}
+
+	rdb := storage.GetRedis()
+	memberKey := s.key("member", miaosha.ID)
+	field := strconv.Itoa(uid)
+	exists, err := rdb.HExists(s.ctx, memberKey, field).Result()
+	if err != nil || exists {
+		return err
+	}
+
+	bought, err := models.GetMiaoshaMemberBoughtQuantity(uid, miaosha.ID)
+	if err != nil {
+		return err
+	}
+	return rdb.HSetNX(s.ctx, memberKey, field, bought).Err()
+}
+
+// checkMemberLimitByOrders 按数据库订单检查会员限购
+func (s *seckillService) checkMemberLimitByOrders(uid int, miaosha *structs.MiaoshaGoods, quantity int) error {
+	over, err := models.CheckMiaoshaMemberBuyMax(int64(uid), *miaosha, quantity)
+	if err != nil {
+		return err
+	}
+	if over == 1 {
+		return seckillMemberLimitError(miaosha.MemberBuyMax)
+	}
+	return nil
+}
+
+// CheckReservation 支付前检查会员持有该秒杀活动未到期的预留且令牌与立即购买时返回的一致
+// 限购和库存已在预留时原子占用，没有预留、预留已到期或令牌不符时不能支付；Redis未启用时不做预留，不检查
+func (s *seckillService) CheckReservation(uid, msID int, token string) error {
+	rdb := storage.GetRedis()
+	if rdb == nil {
+		return nil
+	}
+
+	reserved, err := rdb.HGet(s.ctx, s.key("reserve", msID, uid), "token").Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	expireAt, err := rdb.ZScore(s.ctx, s.key("reserves"), fmt.Sprintf("%d:%d", msID, uid)).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if token == "" || reserved != token || int64(expireAt) <= time.Now().Unix() {
+		return helper.NewBizError(helper.CodeSeckillSoldOut, "秒杀名额已失效，请重新下单")
+	}
+	return nil
+}
+
+// Confirm 订单支付成功后确认预留，计入销量
+// 预留已因支付超时释放时按订单商品原子地重新扣减库存和会员限购，库存不足或超过限购时返回业务错误，由调用方退款
+func (s *seckillService) Confirm(order *structs.Order) error {
+	rdb := storage.GetRedis()
+	if rdb == nil {
+		return nil
+	}
+
+	msID, uid := order.MsID, order.UID
+	miaosha := models.GetMiaoshaGoodsByID(msID)
+	if miaosha == nil {
+		return errors.New("该秒杀商品不存在")
+	}
+	goods, err := models.GetOrderGoodsByOrderID(order.ID)
+	if err != nil {
+		return err
+	}
+	field, quantity := seckillNoSkuField, 0
+	for _, g := range goods {
+		if g.GoodsID != miaosha.GoodsID {
+			continue
+		}
+		quantity += g.Quantity
+		if g.Sku != "" {
+			field = ""
+			if skuValue, err := models.FindMiaoshaSkuValue(msID, miaosha.GoodsID, g.Sku); err == nil {
+				field = strconv.Itoa(skuValue.ID)
+			}
+		}
+	}
+	if err := s.Preload(miaosha); err != nil {
+		return err
+	}
+
+	keys := []string{
+		s.key("reserve", msID, uid),
+		s.key("reserves"),
+		s.key("sold", msID),
+		s.key("dirty"),
+		s.key("stock", msID),
+		s.key("member", msID),
+	}
+	code, err := seckillConfirmScript.Run(s.ctx, rdb, keys,
+		fmt.Sprintf("%d:%d", msID, uid), msID, field, quantity, miaosha.MemberBuyMax, uid).Int()
+	if err != nil {
+		return err
+	}
+	switch code {
+	case -1:
+		return ErrSeckillSoldOut
+	case -2:
+		return seckillMemberLimitError(miaosha.MemberBuyMax)
+	case -3:
+		return ErrSeckillSkuNotFound
+	case 0:
+		log.Printf("秒杀活动%d会员%d没有待确认的库存预留", msID, uid)
+	}
+	return nil
+}
+
+// Release 取消订单时释放预留
+func (s *seckillService) Release(uid, msID int) error {
+	return s.release(uid, msID, "")
+}
+
+// release 执行释放脚本，deadline非空时只释放在该时间之前到期的预留
+func (s *seckillService) release(uid, msID int, deadline string) error {
+	rdb := storage.GetRedis()
+	if rdb == nil {
+		return nil
+	}
+
+	keys := []string{
+		s.key("stock", msID),
+		s.key("member", msID),
+		s.key("reserve", msID, uid),
+		s.key("reserves"),
+		s.key("dirty"),
+	}
+	return seckillReleaseScript.Run(s.ctx, rdb, keys, uid, fmt.Sprintf("%d:%d", msID, uid), msID, deadline).Err()
+}
+
+// ReleaseExpired 释放支付超时的预留，返回释放数量
+func (s *seckillService) ReleaseExpired() (int, error) {
+	rdb := storage.GetRedis()
+	if rdb == nil {
+		return 0, nil
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	members, err := rdb.ZRangeByScore(s.ctx, s.key("reserves"), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   now,
+		Count: 500,
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, member := range members {
+		var msID, uid int
+		if _, err := fmt.Sscanf(member, "%d:%d", &msID, &uid); err != nil {
+			rdb.ZRem(s.ctx, s.key("reserves"), member)
+			continue
+		}
+		if err := s.release(uid, msID, now); err != nil {
+			log.Printf("释放秒杀预留%s失败: %v", member, err)
+			continue
+		}
+		released++
+	}
+	return released, nil
+}
+
+// GetStock 从Redis读取秒杀活动的剩余库存和销量，活动未预热时loaded为false
+// 剩余库存为各SKU库存之和，不限量时为-1
+func (s *seckillService) GetStock(msID int) (stock int, sold int, loaded bool) {
+	rdb := storage.GetRedis()
+	if rdb == nil {
+		return 0, 0, false
+	}
+
+	stockMap, err := rdb.HGetAll(s.ctx, s.key("stock", msID)).Result()
+	if err != nil || len(stockMap) == 0 {
+		return 0, 0, false
+	}
+	for _, value := range stockMap {
+		quantity, _ := strconv.Atoi(value)
+		if quantity < 0 {
+			stock = -1
+			break
+		}
+		stock += quantity
+	}
+
+	soldMap, _ := rdb.HGetAll(s.ctx, s.key("sold", msID)).Result()
+	for _, value := range soldMap {
+		n, _ := strconv.Atoi(value)
+		sold += n
+	}
+	return stock, sold, true
+}
+
+// Reconcile 把库存有变化的秒杀活动写回数据库
+func (s *seckillService) Reconcile() error {
+	rdb := storage.GetRedis()
+	if rdb == nil {
+		return nil
+	}
+
+	dirtyKey := s.key("dirty")
+	msIDs, err := rdb.SPopN(s.ctx, dirtyKey, 100).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range msIDs {
+		msID, err := strconv.Atoi(id)
+		if err != nil {
+			continue
+		}
+		if err := s.reconcileOne(msID); err != nil {
+			log.Printf("同步秒杀活动%d库存失败: %v", msID, err)
+			rdb.SAdd(s.ctx, dirtyKey, msID)
+		}
+	}
+	return nil
+}
+
+// reconcileOne 同步单个秒杀活动的库存和销量
+func (s *seckillService) reconcileOne(msID int) error {
+	rdb := storage.GetRedis()
+	stockMap, err := rdb.HGetAll(s.ctx, s.key("stock", msID)).Result()
+	if err != nil {
+		return err
+	}
+	soldMap, err := rdb.HGetAll(s.ctx, s.key("sold", msID)).Result()
+	if err != nil {
+		return err
+	}
+
+	stocks := make(map[int]int, len(stockMap))
+	for field, value := range stockMap {
+		skuID, _ := strconv.Atoi(field)
+		quantity, _ := strconv.Atoi(value)
+		if skuID > 0 && quantity >= 0 {
+			stocks[skuID] = quantity
+		}
+	}
+	saleCount := 0
+	for _, value := range soldMap {
+		sold, _ := strconv.Atoi(value)
+		saleCount += sold
+	}
+
+	return models.SyncMiaoshaStock(msID, stocks, saleCount)
+}
+
+// newSeckillToken 生成随机的预留令牌
+func newSeckillToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
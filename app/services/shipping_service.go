@@ -0,0 +1,166 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"myapi/app/models"
+	"myapi/app/structs"
+)
+
+// ShippingService 运费计算服务接口
+type ShippingService interface {
+	CalculateFreight(weid int, address *structs.Address, items []structs.FreightItem) (map[int]float64, error)
+}
+
+// shippingService 实现ShippingService接口的结构体
+type shippingService struct{}
+
+// NewShippingService 创建一个新的运费计算服务实例
+func NewShippingService() ShippingService {
+	return &shippingService{}
+}
+
+// freightGroup 使用同一运费模板的商品汇总
+type freightGroup struct {
+	sid       int
+	transport *structs.Transport
+	quantity  int
+	weight    float64
+	amount    float64
+}
+
+// CalculateFreight 按店铺计算运费，返回店铺ID到运费的映射
+// 同一店铺使用同一模板的商品合并计费，商品未设置模板时使用店铺默认模板，店铺没有模板时包邮
+func (s *shippingService) CalculateFreight(weid int, address *structs.Address, items []structs.FreightItem) (map[int]float64, error) {
+	freight := make(map[int]float64)
+	groups := make(map[string]*freightGroup)
+	groupKeys := make([]string, 0)
+	defaults := make(map[int]*structs.Transport)
+
+	for _, item := range items {
+		if _, ok := freight[item.Sid]; !ok {
+			freight[item.Sid] = 0
+		}
+
+		transportID := item.TransportID
+		var transport *structs.Transport
+		if transportID > 0 {
+			t, err := models.GetTransportByID(transportID)
+			if err != nil {
+				return nil, err
+			}
+			transport = t
+		}
+		if transport == nil {
+			t, ok := defaults[item.Sid]
+			if !ok {
+				var err error
+				t, err = models.GetDefaultTransport(weid, item.Sid)
+				if err != nil {
+					return nil, err
+				}
+				defaults[item.Sid] = t
+			}
+			transport = t
+		}
+		if transport == nil {
+			continue
+		}
+
+		key := fmt.Sprintf("%d_%d", item.Sid, transport.ID)
+		group, ok := groups[key]
+		if !ok {
+			group = &freightGroup{sid: item.Sid, transport: transport}
+			groups[key] = group
+			groupKeys = append(groupKeys, key)
+		}
+		group.quantity += item.Quantity
+		group.weight += item.Weight * float64(item.Quantity)
+		group.amount += item.Amount
+	}
+
+	for _, key := range groupKeys {
+		group := groups[key]
+		if group.transport.FreeAmount > 0 && group.amount >= group.transport.FreeAmount {
+			continue
+		}
+
+		rules, err := models.GetTransportExtends(group.transport.ID)
+		if err != nil {
+			return nil, err
+		}
+		rule := MatchTransportArea(rules, address)
+		if rule == nil {
+			return nil, fmt.Errorf("收货地址不在%s的配送范围内", group.transport.Title)
+		}
+
+		measure := float64(group.quantity)
+		if group.transport.ChargeType == structs.TransportChargeByWeight {
+			measure = group.weight
+		}
+		freight[group.sid] = math.Round((freight[group.sid]+CalculateTransportFee(rule, measure))*100) / 100
+	}
+
+	return freight, nil
+}
+
+// MatchTransportArea 按收货地址匹配地区运费规则
+// 优先匹配城市，其次匹配省份，都不匹配时使用默认地区；地址没有地区ID时按地区名称匹配
+func MatchTransportArea(rules []structs.TransportExtend, address *structs.Address) *structs.TransportExtend {
+	var provinceRule, defaultRule *structs.TransportExtend
+	for i := range rules {
+		rule := &rules[i]
+		if rule.IsDefault == 1 {
+			if defaultRule == nil {
+				defaultRule = rule
+			}
+			continue
+		}
+		if address == nil {
+			continue
+		}
+
+		if matchArea(rule.AreaID, rule.AreaName, address.CityId, address.CityName) {
+			return rule
+		}
+		if provinceRule == nil && matchArea(rule.TopAreaID, rule.AreaName, address.ProvinceId, address.ProvinceName) {
+			provinceRule = rule
+		}
+	}
+
+	if provinceRule != nil {
+		return provinceRule
+	}
+	return defaultRule
+}
+
+// matchArea 判断地区ID是否在逗号分隔的ID列表中，地区ID为0时按名称匹配
+func matchArea(areaIDs, areaName string, id int, name string) bool {
+	if id > 0 {
+		target := strconv.Itoa(id)
+		for _, areaID := range strings.Split(areaIDs, ",") {
+			if strings.TrimSpace(areaID) == target {
+				return true
+			}
+		}
+		return false
+	}
+	return name != "" && strings.Contains(areaName, name)
+}
+
+// CalculateTransportFee 按地区规则计算运费，measure为件数或重量
+// 不超过首件(首重)收取首费，超出部分按续件(续重)向上取整计费
+func CalculateTransportFee(rule *structs.TransportExtend, measure float64) float64 {
+	if rule == nil || measure <= 0 {
+		return 0
+	}
+
+	fee := rule.Sprice
+	if measure > float64(rule.Snum) && rule.Xnum > 0 {
+		fee += math.Ceil((measure-float64(rule.Snum))/float64(rule.Xnum)) * rule.Xprice
+	}
+	return math.Round(fee*100) / 100
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"myapi/app/config"
+	"myapi/app/models"
+	"myapi/app/structs"
+)
+
+// StockNotifier 库存提醒通知接口，短信、公众号模板消息等实现后通过RegisterStockNotifier注册
+// Notify在下单流程中同步调用，耗时的通知应自行异步发送
+type StockNotifier interface {
+	Notify(alert structs.StockAlert) error
+}
+
+var (
+	stockNotifiers   = make(map[string]StockNotifier)
+	stockNotifiersMu sync.RWMutex
+)
+
+func init() {
+	RegisterStockNotifier("log", logStockNotifier{})
+}
+
+// RegisterStockNotifier 注册库存提醒通知方式，code与配置stockNotifier一致
+func RegisterStockNotifier(code string, notifier StockNotifier) {
+	stockNotifiersMu.Lock()
+	defer stockNotifiersMu.Unlock()
+	stockNotifiers[code] = notifier
+}
+
+// getStockNotifier 获取配置的通知方式，未注册时使用日志通知
+func getStockNotifier() StockNotifier {
+	code := config.GetString("stockNotifier", "log")
+	stockNotifiersMu.RLock()
+	defer stockNotifiersMu.RUnlock()
+	if notifier, ok := stockNotifiers[code]; ok {
+		return notifier
+	}
+	log.Printf("库存提醒通知方式%s未注册，使用日志通知", code)
+	return stockNotifiers["log"]
+}
+
+// logStockNotifier 只记录日志的默认通知方式
+type logStockNotifier struct{}
+
+// Notify 把库存提醒写入日志
+func (logStockNotifier) Notify(alert structs.StockAlert) error {
+	what := "商品"
+	if alert.SkuvID > 0 {
+		what = "规格" + alert.Sku
+	}
+	source := fmt.Sprintf("购买记录%d", alert.RecordID)
+	if alert.OrderID > 0 {
+		source = fmt.Sprintf("订单%d", alert.OrderID)
+	}
+	if alert.Type == structs.StockAlertOut {
+		log.Printf("库存提醒: 站点%d店铺%d商品%d(%s)%s已售罄，%s", alert.Weid, alert.Sid, alert.GoodsID, alert.Name, what, source)
+		return nil
+	}
+	log.Printf("库存提醒: 站点%d店铺%d商品%d(%s)%s库存%d，低于提醒阈值%d，%s",
+		alert.Weid, alert.Sid, alert.GoodsID, alert.Name, what, alert.Quantity, alert.Threshold, source)
+	return nil
+}
+
+// notifyStockAlerts 按立即购买预留或支付重新扣减写入的库存流水检查库存是否降到阈值或售罄，并发送提醒
+func notifyStockAlerts(entries []structs.GoodsStockLedger) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(entries))
+	seen := make(map[int]bool, len(entries))
+	for _, entry := range entries {
+		if !seen[entry.GoodsID] {
+			seen[entry.GoodsID] = true
+			ids = append(ids, entry.GoodsID)
+		}
+	}
+	products, err := models.GetStockAlertProducts(ids)
+	if err != nil {
+		return err
+	}
+	skuMap, err := models.GetSkuValuesByGoodsIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	notifier := getStockNotifier()
+	for _, alert := range BuildStockAlerts(entries, products, skuMap, config.GetInt("stockLowThreshold", 0)) {
+		if err := notifier.Notify(alert); err != nil {
+			log.Printf("发送库存提醒失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// BuildStockAlerts 根据预留流水找出本次扣减后越过阈值的商品和SKU，每次越过只提醒一次
+// 库存从大于0降到0及以下时为售罄提醒，从高于阈值降到阈值及以下时为低库存提醒
+// 商品阈值为0时使用defaultThreshold，SKU阈值为0时使用商品阈值，阈值为0时只有售罄提醒
+func BuildStockAlerts(entries []structs.GoodsStockLedger, products []structs.Product, skuMap map[int][]structs.GoodsSkuValue, defaultThreshold int) []structs.StockAlert {
+	productMap := make(map[int]structs.Product, len(products))
+	for _, product := range products {
+		productMap[product.ID] = product
+	}
+
+	var alerts []structs.StockAlert
+	for _, entry := range entries {
+		if entry.ChangeQuantity >= 0 {
+			continue
+		}
+		product, ok := productMap[entry.GoodsID]
+		if !ok {
+			continue
+		}
+		threshold := product.LowStock
+		if threshold <= 0 {
+			threshold = defaultThreshold
+		}
+		base := structs.StockAlert{
+			Weid:     product.Weid,
+			Sid:      product.Sid,
+			GoodsID:  product.ID,
+			Name:     product.Name,
+			RecordID: entry.RecordID,
+			OrderID:  entry.OrderID,
+		}
+		if alertType := CrossedStockAlert(entry.QuantityAfter, entry.ChangeQuantity, threshold); alertType != "" {
+			alert := base
+			alert.Type = alertType
+			alert.Quantity = entry.QuantityAfter
+			alert.Threshold = threshold
+			alerts = append(alerts, alert)
+		}
+
+		if entry.SkuvID == 0 {
+			continue
+		}
+		skuThreshold := threshold
+		for _, value := range skuMap[entry.GoodsID] {
+			if value.ID == entry.SkuvID && value.LowStock > 0 {
+				skuThreshold = value.LowStock
+			}
+		}
+		if alertType := CrossedStockAlert(entry.SkuQuantityAfter, entry.ChangeQuantity, skuThreshold); alertType != "" {
+			alert := base
+			alert.Type = alertType
+			alert.SkuvID = entry.SkuvID
+			alert.Sku = entry.Sku
+			alert.Quantity = entry.SkuQuantityAfter
+			alert.Threshold = skuThreshold
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// CrossedStockAlert 判断库存变动是否越过售罄或低库存阈值，没有越过时返回空字符串
+func CrossedStockAlert(after, change, threshold int) string {
+	before := after - change
+	switch {
+	case after <= 0 && before > 0:
+		return structs.StockAlertOut
+	case threshold > 0 && after <= threshold && before > threshold:
+		return structs.StockAlertLow
+	}
+	return ""
+}
@@ -12,7 +12,7 @@ type TuanGoodsService interface {
 	GetTuanGoodsByGoodsID(goodsID int) *structs.TuanGoods
 	GetTuanFoundByID(id int) *structs.TuanFound
 	GetTuanFollowCountByFoundID(foundID int) (int64, error)
-	ValidateCanJoinTuan(goodsID int, tuanID int, quantity int) (*structs.TuanGoods, error)
+	ValidateCanJoinTuan(goodsID int, tuanID int, foundID int, quantity int) (*structs.TuanGoods, error)
 }
 
 // tuanGoodsService 实现TuanGoodsService接口的结构体
@@ -43,8 +43,8 @@ func (s *tuanGoodsService) GetTuanFollowCountByFoundID(foundID int) (int64, erro
 	return models.GetTuanFollowCountByFoundID(foundID)
 }
 
-// ValidateCanJoinTuan 验证是否可平团
-func (s *tuanGoodsService) ValidateCanJoinTuan(goodsID int, tuanID int, quantity int) (*structs.TuanGoods, error) {
+// ValidateCanJoinTuan 验证是否可平团，foundID大于0时为参加已有的团
+func (s *tuanGoodsService) ValidateCanJoinTuan(goodsID int, tuanID int, foundID int, quantity int) (*structs.TuanGoods, error) {
 	// 参数验证
 	if goodsID <= 0 {
 		return nil, errors.New("商品ID无效")
@@ -57,7 +57,7 @@ func (s *tuanGoodsService) ValidateCanJoinTuan(goodsID int, tuanID int, quantity
 	}
 
 	// 调用model层的验证方法
-	tuanGoods, err := models.ValidateCanJoinTuan(int64(goodsID), int64(tuanID), int64(quantity))
+	tuanGoods, err := models.ValidateCanJoinTuan(int64(goodsID), int64(tuanID), int64(foundID), int64(quantity))
 	if err != nil {
 		return nil, err
 	}
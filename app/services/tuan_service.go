@@ -0,0 +1,371 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"myapi/app/config"
+	"myapi/app/helper"
+	"myapi/app/models"
+	"myapi/app/structs"
+)
+
+// TuanService 拼团服务接口，处理开团、参团、成团和拼团失败退款
+type TuanService interface {
+	Open(uid, tuanID int) (*structs.TuanFound, *structs.TuanFollow, error)
+	Join(uid, foundID int) (*structs.TuanFound, *structs.TuanFollow, error)
+	OnOrderPaid(order *structs.Order) error
+	ExpireFounds() error
+	FillRobots() error
+	SettleLuckyDraws() error
+	GetOpenFounds(weid, tuanID, page, limit int) (map[string]interface{}, error)
+	GetFoundDetail(id int) (map[string]interface{}, error)
+	GetMyFounds(uid, page, limit int) (map[string]interface{}, error)
+}
+
+// tuanService 实现TuanService接口的结构体
+type tuanService struct{}
+
+// NewTuanService 创建一个新的拼团服务实例
+func NewTuanService() TuanService {
+	return &tuanService{}
+}
+
+// Open 开团，返回开团记录和团长的参团记录，团长下单时以开团ID作为jointuanid
+func (s *tuanService) Open(uid, tuanID int) (*structs.TuanFound, *structs.TuanFollow, error) {
+	tuanGoods := models.GetTuanGoodsByID(tuanID)
+	if tuanGoods == nil || tuanGoods.Status != 1 {
+		return nil, nil, errors.New("该商品未开团或已下架")
+	}
+	now := time.Now().Unix()
+	if now < int64(tuanGoods.BeginDate) || now > int64(tuanGoods.EndDate) {
+		return nil, nil, errors.New("团购不在有效期内")
+	}
+	if tuanGoods.PeopleNum < 2 || tuanGoods.TimeLimit <= 0 {
+		return nil, nil, errors.New("团购配置错误")
+	}
+
+	member, err := models.GetMemberByID(uid)
+	if err != nil || member == nil {
+		return nil, nil, errors.New("会员不存在")
+	}
+	return models.CreateTuanFound(tuanGoods, member)
+}
+
+// Join 参加已有的团，团长支付后其他会员才能参团，重复参团返回原参团记录
+func (s *tuanService) Join(uid, foundID int) (*structs.TuanFound, *structs.TuanFollow, error) {
+	found := models.GetTuanFoundByID(foundID)
+	if found == nil {
+		return nil, nil, errors.New("开团记录不存在")
+	}
+	found, err := models.ValidateTuanFound(found.ID, found.TuanID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if found.Status != structs.TuanFoundOngoing && found.Uid != uid {
+		return nil, nil, errors.New("团长尚未支付，暂不能参团")
+	}
+
+	follow, err := models.GetTuanFollow(found.ID, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	if follow != nil {
+		if follow.Status == structs.TuanFollowUnpaid {
+			return found, follow, nil
+		}
+		return nil, nil, errors.New("您已参加该团")
+	}
+
+	member, err := models.GetMemberByID(uid)
+	if err != nil || member == nil {
+		return nil, nil, errors.New("会员不存在")
+	}
+	follow, err = models.CreateTuanFollow(found, member)
+	if err != nil {
+		return nil, nil, err
+	}
+	return found, follow, nil
+}
+
+// OnOrderPaid 拼团订单支付后占用名额，团已结束或已满员时把订单金额退回余额，并把order的状态改为已退款
+func (s *tuanService) OnOrderPaid(order *structs.Order) error {
+	found, err := models.PayTuanFollow(order)
+	if err == models.ErrTuanFoundClosed {
+		if err := models.RefundOrderToBalance(order.ID, "拼团已结束退款"); err != nil {
+			return err
+		}
+		invalidateOrderStockDetail(order.ID)
+		order.OrderStatusID = structs.OrderStatusRefunded
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if found.Status == structs.TuanFoundSuccess {
+		log.Printf("开团%d拼团成功，参团人数%d", found.ID, found.Join)
+	}
+	return nil
+}
+
+// ExpireFounds 把过期未成团的开团标记为失败，并给已支付的参团会员和抽奖未中奖的会员退款
+// 退款失败的记录在下次执行时重试
+func (s *tuanService) ExpireFounds() error {
+	ids, err := models.GetExpiredTuanFoundIDs(100)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := models.FailTuanFound(id); err != nil {
+			log.Printf("开团%d标记失败出错: %v", id, err)
+		}
+	}
+
+	follows, err := models.GetUnrefundedTuanFollows(100)
+	if err != nil {
+		return err
+	}
+	for i := range follows {
+		if err := models.RefundTuanFollow(&follows[i]); err != nil {
+			log.Printf("参团记录%d退款失败: %v", follows[i].ID, err)
+			continue
+		}
+		invalidateOrderStockDetail(follows[i].OrderID)
+	}
+	return nil
+}
+
+// FillRobots 开团即将过期且缺少人数不超过商品配置的机器人数量时，用机器人补满成团
+// 只处理开启了自动成团(auto_initiate=1)的团购商品
+func (s *tuanService) FillRobots() error {
+	ahead := int64(config.GetInt("tuanRobotFillAhead", 120))
+	founds, err := models.GetRobotFillTuanFounds(ahead, 100)
+	if err != nil {
+		return err
+	}
+
+	avatar := config.GetString("tuanRobotAvatar", "")
+	for i := range founds {
+		robots := make([]structs.TuanFollow, founds[i].Need)
+		for j := range robots {
+			robots[j].Nickname = robotNickname()
+			robots[j].Avatar = avatar
+		}
+		if err := models.FillTuanRobots(&founds[i], robots); err != nil {
+			if err != models.ErrTuanFoundClosed {
+				log.Printf("开团%d机器人成团失败: %v", founds[i].ID, err)
+			}
+			continue
+		}
+		log.Printf("开团%d已补充%d个机器人成团", founds[i].ID, len(robots))
+	}
+	return nil
+}
+
+// SettleLuckyDraws 给已成团的抽奖团开奖，中奖者正常发货，未中奖者由ExpireFounds退款
+// 每个团生成随机种子并与候选名单一起保存，可用DrawTuanWinners复核开奖结果
+func (s *tuanService) SettleLuckyDraws() error {
+	founds, err := models.GetPendingLuckyDrawFounds(100)
+	if err != nil {
+		return err
+	}
+
+	for _, found := range founds {
+		tuanGoods := models.GetTuanGoodsByID(found.TuanID)
+		if tuanGoods == nil {
+			continue
+		}
+		candidates, err := models.GetTuanDrawCandidates(found.ID)
+		if err != nil {
+			log.Printf("开团%d获取抽奖候选失败: %v", found.ID, err)
+			continue
+		}
+		seed, err := newDrawSeed()
+		if err != nil {
+			return err
+		}
+
+		winners := DrawTuanWinners(seed, candidates, tuanGoods.LuckyDrawNum)
+		draw := &structs.TuanLuckyDraw{
+			Weid:       found.Weid,
+			FoundID:    found.ID,
+			TuanID:     found.TuanID,
+			Seed:       seed,
+			Candidates: joinIDs(candidates),
+			Winners:    joinIDs(winners),
+			WinnerNum:  len(winners),
+			DrawTime:   int(time.Now().Unix()),
+		}
+		if err := models.SaveTuanLuckyDraw(draw, candidates, winners); err != nil {
+			log.Printf("开团%d开奖失败: %v", found.ID, err)
+			continue
+		}
+		log.Printf("开团%d开奖完成，候选%d人，中奖%d人", found.ID, len(candidates), len(winners))
+	}
+	return nil
+}
+
+// GetOpenFounds 获取团购商品正在拼团中的开团，供商品页展示可参加的团
+func (s *tuanService) GetOpenFounds(weid, tuanID, page, limit int) (map[string]interface{}, error) {
+	tuanGoods := models.GetTuanGoodsByID(tuanID)
+	if tuanGoods == nil || tuanGoods.Weid != weid {
+		return nil, errors.New("团购商品不存在")
+	}
+
+	list, total, err := models.GetOpenTuanFounds(tuanID, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	for i := range list {
+		list[i].Avatar = helper.ToImg(list[i].Avatar)
+		list[i].Remaining = TuanRemaining(list[i].Status, list[i].FoundEndTime, now)
+	}
+
+	return map[string]interface{}{
+		"list":  list,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	}, nil
+}
+
+// GetFoundDetail 获取开团详情和参团成员，供分享页展示
+func (s *tuanService) GetFoundDetail(id int) (map[string]interface{}, error) {
+	found, err := models.GetTuanFoundBrief(id)
+	if err != nil {
+		return nil, err
+	}
+	found.Avatar = helper.ToImg(found.Avatar)
+	found.Remaining = TuanRemaining(found.Status, found.FoundEndTime, time.Now().Unix())
+
+	followers, err := models.GetTuanFollowers(id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range followers {
+		followers[i].Avatar = helper.ToImg(followers[i].Avatar)
+	}
+	joinCount, err := models.GetTuanFollowCountByFoundID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"found":      found,
+		"followers":  followers,
+		"join_count": joinCount,
+	}
+	if tuanGoods := models.GetTuanGoodsByID(found.TuanID); tuanGoods != nil {
+		goods := map[string]interface{}{
+			"tuan_id":  tuanGoods.ID,
+			"goods_id": tuanGoods.GoodsID,
+			"title":    tuanGoods.Title,
+			"price":    tuanGoods.Price,
+		}
+		if product := models.GetProductByID(tuanGoods.GoodsID); product != nil {
+			goods["name"] = product.Name
+			goods["image"] = helper.ToImg(product.Image)
+			goods["original_price"] = product.Price
+		}
+		result["goods"] = goods
+	}
+	return result, nil
+}
+
+// GetMyFounds 获取会员参加的拼团列表
+func (s *tuanService) GetMyFounds(uid, page, limit int) (map[string]interface{}, error) {
+	list, total, err := models.GetMemberTuanFounds(uid, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	for i := range list {
+		list[i].Remaining = TuanRemaining(list[i].FoundStatus, list[i].FoundEndTime, now)
+	}
+
+	return map[string]interface{}{
+		"list":  list,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	}, nil
+}
+
+// TuanRemaining 计算开团剩余秒数，已结束的团返回0
+func TuanRemaining(status, foundEndTime int, now int64) int64 {
+	if status != structs.TuanFoundPending && status != structs.TuanFoundOngoing {
+		return 0
+	}
+	if remaining := int64(foundEndTime) - now; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// DrawTuanWinners 按种子从候选参团记录中抽取中奖者，结果只取决于种子和候选ID，可重复计算复核
+// 每个候选按sha256(seed:id)排序取前num个，num不大于0或不小于候选人数时全部中奖
+func DrawTuanWinners(seed string, candidates []int, num int) []int {
+	if num <= 0 || num >= len(candidates) {
+		return append([]int{}, candidates...)
+	}
+
+	type scored struct {
+		id    int
+		score string
+	}
+	list := make([]scored, len(candidates))
+	for i, id := range candidates {
+		sum := sha256.Sum256([]byte(seed + ":" + strconv.Itoa(id)))
+		list[i] = scored{id: id, score: hex.EncodeToString(sum[:])}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].score == list[j].score {
+			return list[i].id < list[j].id
+		}
+		return list[i].score < list[j].score
+	})
+
+	winners := make([]int, num)
+	for i := range winners {
+		winners[i] = list[i].id
+	}
+	sort.Ints(winners)
+	return winners
+}
+
+// newDrawSeed 生成开奖随机种子
+func newDrawSeed() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// robotNickname 生成机器人昵称
+func robotNickname() string {
+	n, err := rand.Int(rand.Reader, big.NewInt(10000))
+	if err != nil {
+		return "拼团用户"
+	}
+	return fmt.Sprintf("用户%04d", n.Int64())
+}
+
+// joinIDs 把ID列表拼接为逗号分隔的字符串
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"myapi/app/config"
+	"myapi/app/helper"
+	"myapi/app/models"
+	"myapi/app/structs"
+)
+
+// TuanzhangApplyRequest 团长申请参数，Imageurl1和Imageurl2为身份证正反面照片
+type TuanzhangApplyRequest struct {
+	Title          string `json:"title" binding:"required"`
+	CommunityTitle string `json:"community_title" binding:"required"`
+	Tel            string `json:"tel" binding:"required,len=11" customvalidate:"mobile"`
+	IdCart         string `json:"id_cart" binding:"required" customvalidate:"idcard"`
+	Imageurl1      string `json:"imageurl1" binding:"required"`
+	Imageurl2      string `json:"imageurl2" binding:"required"`
+	Imageurl3      string `json:"imageurl3"`
+	Imageurl4      string `json:"imageurl4"`
+	ProvinceName   string `json:"province_name"`
+	CityName       string `json:"city_name"`
+	DistrictName   string `json:"district_name"`
+	RegionName     string `json:"region_name"`
+	Dizhi          string `json:"dizhi"`
+	HouseNumber    string `json:"house_number"`
+	Longitude      string `json:"longitude"`
+	Latitude       string `json:"latitude"`
+	Introduction   string `json:"introduction"`
+}
+
+// TuanzhangService 团长服务接口，处理团长申请、审核、等级和订单收入
+type TuanzhangService interface {
+	Apply(weid, uid int, req *TuanzhangApplyRequest) (*structs.Tuanzhang, error)
+	GetMine(weid, uid int) (*structs.Tuanzhang, error)
+	GetList(weid, status, page, limit int) ([]structs.Tuanzhang, int64, error)
+	Audit(id int, pass bool, level int, remark string) error
+	SetLevel(id, level int) error
+	SettleOrderIncome(order *structs.Order) error
+	GetDashboard(weid, uid, months int) (map[string]interface{}, error)
+	GetIncomeLogs(weid, uid, page, limit int) ([]structs.TuanzhangIncomeLog, int64, error)
+}
+
+// tuanzhangService 实现TuanzhangService接口的结构体
+type tuanzhangService struct{}
+
+// NewTuanzhangService 创建一个新的团长服务实例
+func NewTuanzhangService() TuanzhangService {
+	return &tuanzhangService{}
+}
+
+// Apply 提交团长申请，已驳回的申请可以修改后重新提交
+func (s *tuanzhangService) Apply(weid, uid int, req *TuanzhangApplyRequest) (*structs.Tuanzhang, error) {
+	if req.Imageurl1 == "" || req.Imageurl2 == "" {
+		return nil, errors.New("请上传身份证正反面照片")
+	}
+
+	tuanzhang, err := models.GetTuanzhangByUid(weid, uid)
+	if err != nil {
+		return nil, err
+	}
+	if tuanzhang == nil {
+		tuanzhang = &structs.Tuanzhang{Weid: weid, Uid: uid}
+		if member, err := models.GetMemberByID(uid); err == nil && member != nil {
+			tuanzhang.Ocid = member.Ocid
+			tuanzhang.Touxiang = member.Userpic
+		}
+	} else if tuanzhang.Status != structs.TuanzhangRejected {
+		return nil, errors.New("申请正在审核或已通过")
+	}
+
+	tuanzhang.Title = req.Title
+	tuanzhang.CommunityTitle = req.CommunityTitle
+	tuanzhang.Tel = req.Tel
+	tuanzhang.IdCart = req.IdCart
+	tuanzhang.Imageurl1 = req.Imageurl1
+	tuanzhang.Imageurl2 = req.Imageurl2
+	tuanzhang.Imageurl3 = req.Imageurl3
+	tuanzhang.Imageurl4 = req.Imageurl4
+	tuanzhang.ProvinceName = req.ProvinceName
+	tuanzhang.CityName = req.CityName
+	tuanzhang.DistrictName = req.DistrictName
+	tuanzhang.RegionName = req.RegionName
+	tuanzhang.Dizhi = req.Dizhi
+	tuanzhang.HouseNumber = req.HouseNumber
+	tuanzhang.Longitude = req.Longitude
+	tuanzhang.Latitude = req.Latitude
+	tuanzhang.Introduction = req.Introduction
+	if err := models.SaveTuanzhangApply(tuanzhang); err != nil {
+		return nil, err
+	}
+	return tuanzhang, nil
+}
+
+// GetMine 获取会员自己的团长信息
+func (s *tuanzhangService) GetMine(weid, uid int) (*structs.Tuanzhang, error) {
+	tuanzhang, err := models.GetTuanzhangByUid(weid, uid)
+	if err != nil {
+		return nil, err
+	}
+	if tuanzhang == nil {
+		return nil, errors.New("您还不是团长")
+	}
+	formatTuanzhangImages(tuanzhang)
+	return tuanzhang, nil
+}
+
+// GetList 分页获取团长列表，供后台审核
+func (s *tuanzhangService) GetList(weid, status, page, limit int) ([]structs.Tuanzhang, int64, error) {
+	list, total, err := models.GetTuanzhangList(weid, status, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range list {
+		formatTuanzhangImages(&list[i])
+	}
+	return list, total, nil
+}
+
+// Audit 审核团长申请，通过时必须指定有效的团长等级
+func (s *tuanzhangService) Audit(id int, pass bool, level int, remark string) error {
+	if pass {
+		if err := s.checkLevel(level); err != nil {
+			return err
+		}
+	} else if remark == "" {
+		return errors.New("请填写驳回原因")
+	}
+	return models.AuditTuanzhang(id, pass, level, remark)
+}
+
+// SetLevel 调整团长等级
+func (s *tuanzhangService) SetLevel(id, level int) error {
+	if err := s.checkLevel(level); err != nil {
+		return err
+	}
+	return models.SetTuanzhangLevel(id, level)
+}
+
+// SettleOrderIncome 按团长等级的返点比例计算订单收入并写入收入明细
+// 等级未设置返点比例时使用tuanzhangDefaultPercent配置，团长未通过审核时不结算
+func (s *tuanzhangService) SettleOrderIncome(order *structs.Order) error {
+	if order.Tzid <= 0 {
+		return nil
+	}
+	tuanzhang, err := models.GetTuanzhangByID(order.Tzid)
+	if err != nil {
+		return err
+	}
+	if tuanzhang.Status != structs.TuanzhangApproved {
+		return nil
+	}
+
+	percent := config.GetFloat("tuanzhangDefaultPercent", 0)
+	if level, err := models.GetTuanzhangLevel(tuanzhang.Level); err == nil && level != nil && level.ReturnPercent > 0 {
+		percent = level.ReturnPercent
+	}
+	income := CalculateTuanzhangIncome(order.Total, percent)
+	if income <= 0 {
+		return nil
+	}
+	return models.CreateTuanzhangIncome(tuanzhang, order, income, percent)
+}
+
+// GetDashboard 团长工作台：团长信息、等级、可用收入、累计收入和最近几个月的收入汇总
+func (s *tuanzhangService) GetDashboard(weid, uid, months int) (map[string]interface{}, error) {
+	tuanzhang, err := s.getApproved(weid, uid)
+	if err != nil {
+		return nil, err
+	}
+	if months <= 0 || months > 24 {
+		months = 6
+	}
+
+	now := time.Now()
+	fromMonth := time.Date(now.Year(), now.Month()-time.Month(months-1), 1, 0, 0, 0, 0, now.Location()).Format("2006-01")
+	monthIncomes, err := models.GetTuanzhangMonthIncomes(tuanzhang.ID, fromMonth)
+	if err != nil {
+		return nil, err
+	}
+	monthIncome := 0.0
+	for _, item := range monthIncomes {
+		if item.MonthTime == now.Format("2006-01") {
+			monthIncome = item.Income
+		}
+	}
+
+	levelTitle := ""
+	if level, err := models.GetTuanzhangLevel(tuanzhang.Level); err == nil && level != nil {
+		levelTitle = level.Title
+	}
+	formatTuanzhangImages(tuanzhang)
+
+	return map[string]interface{}{
+		"tuanzhang":     tuanzhang,
+		"level_title":   levelTitle,
+		"income":        tuanzhang.Income,
+		"total_income":  tuanzhang.TotalIncome,
+		"month_income":  monthIncome,
+		"month_incomes": monthIncomes,
+	}, nil
+}
+
+// GetIncomeLogs 分页获取团长收入明细
+func (s *tuanzhangService) GetIncomeLogs(weid, uid, page, limit int) ([]structs.TuanzhangIncomeLog, int64, error) {
+	tuanzhang, err := s.getApproved(weid, uid)
+	if err != nil {
+		return nil, 0, err
+	}
+	return models.GetTuanzhangIncomeLogs(tuanzhang.ID, page, limit)
+}
+
+// getApproved 获取会员已通过审核的团长信息
+func (s *tuanzhangService) getApproved(weid, uid int) (*structs.Tuanzhang, error) {
+	tuanzhang, err := models.GetTuanzhangByUid(weid, uid)
+	if err != nil {
+		return nil, err
+	}
+	if tuanzhang == nil || tuanzhang.Status != structs.TuanzhangApproved {
+		return nil, errors.New("您还不是团长")
+	}
+	return tuanzhang, nil
+}
+
+// checkLevel 检查团长等级是否存在且启用
+func (s *tuanzhangService) checkLevel(level int) error {
+	if level <= 0 {
+		return errors.New("请选择团长等级")
+	}
+	l, err := models.GetTuanzhangLevel(level)
+	if err != nil {
+		return err
+	}
+	if l == nil {
+		return errors.New("团长等级不存在")
+	}
+	return nil
+}
+
+// CalculateTuanzhangIncome 按返点百分比计算团长收入，保留两位小数
+func CalculateTuanzhangIncome(total, percent float64) float64 {
+	if total <= 0 || percent <= 0 {
+		return 0
+	}
+	return math.Round(total*percent) / 100
+}
+
+// formatTuanzhangImages 转换团长头像和身份证照片地址
+func formatTuanzhangImages(tuanzhang *structs.Tuanzhang) {
+	tuanzhang.Touxiang = helper.ToImg(tuanzhang.Touxiang)
+	tuanzhang.Imageurl1 = helper.ToImg(tuanzhang.Imageurl1)
+	tuanzhang.Imageurl2 = helper.ToImg(tuanzhang.Imageurl2)
+	tuanzhang.Imageurl3 = helper.ToImg(tuanzhang.Imageurl3)
+	tuanzhang.Imageurl4 = helper.ToImg(tuanzhang.Imageurl4)
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"myapi/app/config"
+	"myapi/app/helper"
+	"myapi/app/models"
+	"myapi/app/structs"
+	"strconv"
+	"strings"
+)
+
+// WalletService 会员钱包服务接口
+type WalletService interface {
+	GetWallet(uid int) (map[string]interface{}, error)
+	GetLedger(uid, page, limit int) ([]structs.MemberWalletLedger, int64, error)
+	Recharge(weid, uid int, amount float64, paymentCode string) (map[string]interface{}, error)
+	PayOrder(uid, orderID int, seckillToken string) (*structs.Order, error)
+	Freeze(weid, uid int, amount float64, bizType string, bizID int, remark string) error
+	Unfreeze(weid, uid int, amount float64, bizType string, bizID int, remark string) error
+	ApplyWithdraw(withdraw *structs.Withdraw) error
+	GetWithdrawAccounts(weid, uid int) ([]map[string]interface{}, error)
+	GetWithdrawList(weid, uid int, ptype string, status, page, limit int) ([]structs.Withdraw, int64, error)
+	AuditWithdraw(id int, pass bool, remark string) error
+	ExportWithdrawBatch(weid int, batchNo string) (string, []byte, error)
+}
+
+// walletService 实现WalletService接口的结构体
+type walletService struct{}
+
+// NewWalletService 创建一个新的钱包服务实例
+func NewWalletService() WalletService {
+	return &walletService{}
+}
+
+// GetWallet 获取会员余额和冻结金额
+func (s *walletService) GetWallet(uid int) (map[string]interface{}, error) {
+	member, err := models.GetMemberByID(uid)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, errors.New("会员不存在")
+	}
+	return map[string]interface{}{
+		"balance": member.Balance,
+		"freeze":  member.Freeze,
+	}, nil
+}
+
+// GetLedger 获取钱包流水
+func (s *walletService) GetLedger(uid, page, limit int) ([]structs.MemberWalletLedger, int64, error) {
+	return models.GetWalletLedger(uid, page, limit)
+}
+
+// Recharge 创建充值单并向支付渠道下单
+func (s *walletService) Recharge(weid, uid int, amount float64, paymentCode string) (map[string]interface{}, error) {
+	minAmount := config.GetFloat("rechargeMinAmount", 1)
+	if amount < minAmount {
+		return nil, fmt.Errorf("充值金额不能低于%.2f元", minAmount)
+	}
+	if paymentCode == "balance" {
+		return nil, errors.New("不能使用余额充值")
+	}
+
+	channel, err := GetPayChannel(paymentCode)
+	if err != nil {
+		return nil, err
+	}
+
+	recharge := &structs.MemberRecharge{
+		Weid:        weid,
+		Uid:         uid,
+		RechargeSn:  helper.BuildOrderNo(rechargeSnPrefix),
+		Amount:      amount,
+		PaymentCode: paymentCode,
+	}
+	if err := models.CreateRecharge(recharge); err != nil {
+		return nil, fmt.Errorf("创建充值单失败: %v", err)
+	}
+
+	payParams, err := channel.Prepay(recharge.RechargeSn, recharge.Amount, "余额充值")
+	if err != nil {
+		return nil, fmt.Errorf("发起支付失败: %v", err)
+	}
+
+	return map[string]interface{}{
+		"recharge_sn": recharge.RechargeSn,
+		"amount":      recharge.Amount,
+		"pay_params":  payParams,
+	}, nil
+}
+
+// PayOrder 使用余额支付订单，秒杀订单须带上立即购买时返回的预留令牌
+func (s *walletService) PayOrder(uid, orderID int, seckillToken string) (*structs.Order, error) {
+	if orderID <= 0 {
+		return nil, errors.New("订单ID无效")
+	}
+	current, err := models.GetOrderByID(orderID)
+	if err != nil || current.UID != uid {
+		return nil, errors.New("订单不存在")
+	}
+	if current.MsID > 0 {
+		if err := NewSeckillService().CheckReservation(uid, current.MsID, seckillToken); err != nil {
+			return nil, err
+		}
+	}
+	order, err := models.PayOrderByBalance(uid, orderID)
+	if err != nil {
+		return nil, err
+	}
+	NewOrderService().AfterPaid(order)
+	return order, nil
+}
+
+// Freeze 冻结会员余额
+func (s *walletService) Freeze(weid, uid int, amount float64, bizType string, bizID int, remark string) error {
+	if uid <= 0 || amount <= 0 {
+		return errors.New("参数无效")
+	}
+	if bizType == "" {
+		bizType = "freeze"
+	}
+	return models.FreezeBalance(weid, uid, amount, bizType, bizID, remark)
+}
+
+// Unfreeze 解冻会员余额
+func (s *walletService) Unfreeze(weid, uid int, amount float64, bizType string, bizID int, remark string) error {
+	if uid <= 0 || amount <= 0 {
+		return errors.New("参数无效")
+	}
+	if bizType == "" {
+		bizType = "unfreeze"
+	}
+	return models.UnfreezeBalance(weid, uid, amount, bizType, bizID, remark)
+}
+
+// ApplyWithdraw 申请提现，可以从会员余额、城市代理收入或团长收入提现
+// 手续费按withdrawFeePercent计算并受withdrawFeeMin、withdrawFeeMax限制，从申请金额中扣除
+func (s *walletService) ApplyWithdraw(withdraw *structs.Withdraw) error {
+	minAmount := config.GetFloat("withdrawMinAmount", 1)
+	if withdraw.Amount < minAmount {
+		return fmt.Errorf("提现金额不能低于%.2f元", minAmount)
+	}
+	if withdraw.CollectType == "" || len(helper.GetCollectType(withdraw.CollectType)) == 0 {
+		return errors.New("请选择收款方式")
+	}
+	if strings.TrimSpace(withdraw.AccountName) == "" || strings.TrimSpace(withdraw.AccountNo) == "" {
+		return errors.New("请填写收款账户")
+	}
+	if withdraw.CollectType == "bank" && strings.TrimSpace(withdraw.BankName) == "" {
+		return errors.New("请填写开户银行")
+	}
+
+	switch withdraw.Ptype {
+	case "", structs.WithdrawPtypeMember:
+		withdraw.Ptype = structs.WithdrawPtypeMember
+		withdraw.AccountID = 0
+	case structs.WithdrawPtypeOperatingcity:
+		if withdraw.AccountID <= 0 {
+			return errors.New("请选择代理账户")
+		}
+	case structs.WithdrawPtypeTuanzhang:
+		tuanzhang, err := models.GetTuanzhangByUid(withdraw.Weid, withdraw.Uid)
+		if err != nil {
+			return err
+		}
+		if tuanzhang == nil || tuanzhang.Status != structs.TuanzhangApproved {
+			return errors.New("您还不是团长")
+		}
+		withdraw.AccountID = tuanzhang.ID
+	default:
+		return errors.New("不支持的提现账户类型")
+	}
+
+	withdraw.Fee = CalculateWithdrawFee(withdraw.Amount,
+		config.GetFloat("withdrawFeePercent", 0),
+		config.GetFloat("withdrawFeeMin", 0),
+		config.GetFloat("withdrawFeeMax", 0))
+	withdraw.ActualAmount = math.Round((withdraw.Amount-withdraw.Fee)*100) / 100
+	if withdraw.ActualAmount <= 0 {
+		return errors.New("提现金额不足以支付手续费")
+	}
+	withdraw.WithdrawSn = helper.BuildOrderNo("TX")
+	if err := models.CreateWithdraw(withdraw); err != nil {
+		if err == models.ErrInsufficientBalance {
+			return errors.New("可提现金额不足")
+		}
+		return err
+	}
+	return nil
+}
+
+// GetWithdrawAccounts 获取会员可提现的账户：余额、名下的城市代理收入和团长收入
+func (s *walletService) GetWithdrawAccounts(weid, uid int) ([]map[string]interface{}, error) {
+	member, err := models.GetMemberByID(uid)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, errors.New("会员不存在")
+	}
+	accounts := []map[string]interface{}{
+		{"ptype": structs.WithdrawPtypeMember, "account_id": 0, "title": "账户余额", "amount": member.Balance},
+	}
+
+	cities, err := models.GetOperatingcitiesByUid(weid, uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, city := range cities {
+		accounts = append(accounts, map[string]interface{}{
+			"ptype": structs.WithdrawPtypeOperatingcity, "account_id": city.ID, "title": city.Title, "amount": city.Income,
+		})
+	}
+
+	tuanzhang, err := models.GetTuanzhangByUid(weid, uid)
+	if err != nil {
+		return nil, err
+	}
+	if tuanzhang != nil && tuanzhang.Status == structs.TuanzhangApproved {
+		accounts = append(accounts, map[string]interface{}{
+			"ptype": structs.WithdrawPtypeTuanzhang, "account_id": tuanzhang.ID, "title": "团长收入", "amount": tuanzhang.Income,
+		})
+	}
+	return accounts, nil
+}
+
+// GetWithdrawList 获取提现申请列表
+func (s *walletService) GetWithdrawList(weid, uid int, ptype string, status, page, limit int) ([]structs.Withdraw, int64, error) {
+	return models.GetWithdrawList(weid, uid, ptype, status, page, limit)
+}
+
+// AuditWithdraw 审核提现申请，驳回时必须填写原因
+func (s *walletService) AuditWithdraw(id int, pass bool, remark string) error {
+	if id <= 0 {
+		return errors.New("提现申请ID无效")
+	}
+	if !pass && strings.TrimSpace(remark) == "" {
+		return errors.New("请填写驳回原因")
+	}
+	return models.AuditWithdraw(id, pass, remark)
+}
+
+// ExportWithdrawBatch 导出打款批次CSV文件，batchNo为空时把审核通过未打款的申请编入新批次
+// 返回批次号和文件内容
+func (s *walletService) ExportWithdrawBatch(weid int, batchNo string) (string, []byte, error) {
+	var list []structs.Withdraw
+	var err error
+	if batchNo == "" {
+		batchNo, list, err = models.CreateWithdrawBatch(weid, config.GetInt("withdrawBatchSize", 500))
+	} else {
+		list, err = models.GetWithdrawBatch(weid, batchNo)
+		if err == nil && len(list) == 0 {
+			err = errors.New("打款批次不存在")
+		}
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	rows := make([][]interface{}, 0, len(list))
+	for _, item := range list {
+		collectType := item.CollectType
+		if types := helper.GetCollectType(item.CollectType); len(types) > 0 {
+			collectType = fmt.Sprint(types[0]["key"])
+		}
+		rows = append(rows, []interface{}{
+			batchNo, item.WithdrawSn, item.Ptype, strconv.Itoa(item.Uid), collectType,
+			item.AccountName, item.AccountNo, item.BankName, item.Amount, item.Fee, item.ActualAmount,
+		})
+	}
+	data, err := helper.CSVBytes([]string{"批次号", "提现单号", "账户类型", "会员ID", "收款方式", "收款人", "收款账号", "开户银行", "申请金额", "手续费", "打款金额"}, rows)
+	if err != nil {
+		return "", nil, err
+	}
+	return batchNo, data, nil
+}
+
+// CalculateWithdrawFee 按百分比计算提现手续费，不低于minFee，maxFee大于0时不超过maxFee，且不超过提现金额
+func CalculateWithdrawFee(amount, percent, minFee, maxFee float64) float64 {
+	if amount <= 0 {
+		return 0
+	}
+	fee := math.Round(amount*percent) / 100
+	if fee < minFee {
+		fee = minFee
+	}
+	if maxFee > 0 && fee > maxFee {
+		fee = maxFee
+	}
+	if fee > amount {
+		fee = amount
+	}
+	if fee < 0 {
+		return 0
+	}
+	return fee
+}
@@ -0,0 +1,33 @@
+package structs
+
+// 分销佣金状态
+const (
+	CommissionFrozen   = 0 // 已冻结，订单完成后解冻
+	CommissionSettled  = 1 // 已结算到余额
+	CommissionReversed = 2 // 订单退款已冲回
+)
+
+// 分佣方式，与商品commission_method一致
+const (
+	CommissionMethodPercent = 0 // 按成交金额百分比
+	CommissionMethodFixed   = 1 // 每件固定金额
+)
+
+// MemberCommission 分销佣金记录表，对应ims_member_commission表
+// 订单支付后按推荐关系给上三级会员记录佣金，金额先进入冻结账户
+type MemberCommission struct {
+	ID         int     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Weid       int     `gorm:"column:weid" json:"weid"`
+	Uid        int     `gorm:"column:uid" json:"uid"`           // 获得佣金的会员
+	BuyerID    int     `gorm:"column:buyer_id" json:"buyer_id"` // 下单会员
+	OrderID    int     `gorm:"column:order_id" json:"order_id"`
+	GoodsID    int     `gorm:"column:goods_id" json:"goods_id"`
+	Level      int     `gorm:"column:level" json:"level"`   // 推荐层级1-3
+	Method     int     `gorm:"column:method" json:"method"` // 0百分比 1固定金额
+	Rate       float64 `gorm:"column:rate" json:"rate"`     // 百分比或每件金额
+	BaseAmount float64 `gorm:"column:base_amount" json:"base_amount"`
+	Amount     float64 `gorm:"column:amount" json:"amount"`
+	Status     int     `gorm:"column:status" json:"status"` // 0冻结 1已结算 2已冲回
+	CreateTime int     `gorm:"column:create_time" json:"create_time"`
+	SettleTime int     `gorm:"column:settle_time" json:"settle_time"`
+}
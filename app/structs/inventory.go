@@ -0,0 +1,87 @@
+package structs
+
+// 库存预留状态
+const (
+	StockReserved  = 1 // 已预留，立即购买时扣减
+	StockCommitted = 2 // 已确认，订单已支付
+	StockReleased  = 3 // 已释放，预留超时、订单取消或退款后回补
+)
+
+// 库存流水类型
+const (
+	StockMoveReserve = "reserve" // 立即购买预留
+	StockMoveCommit  = "commit"  // 支付确认
+	StockMoveRelease = "release" // 超时、取消或退款回补
+	StockMoveAdjust  = "adjust"  // 后台调整
+)
+
+// StockItem 需要预留库存的商品，Sku为空表示不区分规格
+type StockItem struct {
+	GoodsID  int    `json:"goods_id"`
+	Sku      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+// GoodsStockReservation 商品库存预留表，对应ims_goods_stock_reservation表
+// 立即购买时按购买记录预留，订单支付时关联到订单并确认；只记录扣减库存(subtract=1)的商品，每个商品规格一条
+type GoodsStockReservation struct {
+	ID         int    `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Weid       int    `gorm:"column:weid" json:"weid"`
+	Uid        int    `gorm:"column:uid" json:"uid"`
+	RecordID   int    `gorm:"column:record_id" json:"record_id"` // 立即购买记录ID，支付时库存预留已过期重新扣减的为0
+	OrderID    int    `gorm:"column:order_id" json:"order_id"`   // 支付前为0
+	GoodsID    int    `gorm:"column:goods_id" json:"goods_id"`
+	SkuvID     int    `gorm:"column:skuv_id" json:"skuv_id"` // 规格SKU ID，0表示不区分规格
+	Sku        string `gorm:"column:sku" json:"sku"`
+	Quantity   int    `gorm:"column:quantity" json:"quantity"`
+	Status     int    `gorm:"column:status" json:"status"`           // 1已预留 2已确认 3已释放
+	ExpireTime int    `gorm:"column:expire_time" json:"expire_time"` // 未支付时到期释放
+	CreateTime int    `gorm:"column:create_time" json:"create_time"`
+	UpdateTime int    `gorm:"column:update_time" json:"update_time"`
+}
+
+// GoodsStockLedger 商品库存流水表，对应ims_goods_stock_ledger表
+type GoodsStockLedger struct {
+	ID               int    `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Weid             int    `gorm:"column:weid" json:"weid"`
+	GoodsID          int    `gorm:"column:goods_id" json:"goods_id"`
+	SkuvID           int    `gorm:"column:skuv_id" json:"skuv_id"`
+	Sku              string `gorm:"column:sku" json:"sku"`
+	RecordID         int    `gorm:"column:record_id" json:"record_id"`                   // 触发预留的立即购买记录
+	OrderID          int    `gorm:"column:order_id" json:"order_id"`                     // 触发变动的订单，立即购买预留时为0
+	Type             string `gorm:"column:type" json:"type"`                             // reserve/commit/release/adjust
+	ChangeQuantity   int    `gorm:"column:change_quantity" json:"change_quantity"`       // 库存变化量，扣减为负数，确认为0
+	QuantityAfter    int    `gorm:"column:quantity_after" json:"quantity_after"`         // 变动后商品库存
+	SkuQuantityAfter int    `gorm:"column:sku_quantity_after" json:"sku_quantity_after"` // 变动后SKU库存，不区分规格时为0
+	Remark           string `gorm:"column:remark" json:"remark"`
+	CreateTime       int    `gorm:"column:create_time" json:"create_time"`
+}
+
+// StockLedgerFilter 库存流水查询条件
+type StockLedgerFilter struct {
+	Weid    int
+	GoodsID int
+	OrderID int
+	Type    string
+}
+
+// 库存提醒类型
+const (
+	StockAlertLow = "low" // 库存降到提醒阈值
+	StockAlertOut = "out" // 库存为0
+)
+
+// StockAlert 库存提醒，SkuvID为0时是商品总库存的提醒
+type StockAlert struct {
+	Type      string `json:"type"` // low/out
+	Weid      int    `json:"weid"`
+	Sid       int    `json:"sid"` // 店铺ID
+	GoodsID   int    `json:"goods_id"`
+	Name      string `json:"name"`
+	SkuvID    int    `json:"skuv_id"`
+	Sku       string `json:"sku"`
+	Quantity  int    `json:"quantity"`  // 当前库存
+	Threshold int    `json:"threshold"` // 提醒阈值
+	RecordID  int    `json:"record_id"` // 触发提醒的立即购买记录
+	OrderID   int    `json:"order_id"`  // 预留超时后支付重新扣减时触发提醒的订单
+}
@@ -20,6 +20,7 @@ type Member struct {
 	Sex               int     `gorm:"column:sex" json:"sex"`
 	Userpic           string  `gorm:"column:userpic" json:"userpic"`
 	Pid               int     `gorm:"column:pid" json:"pid"`
+	InviteCode        string  `gorm:"column:invite_code;default:null" json:"invite_code"` // 邀请码，未生成时为NULL
 	AgentLevel        int     `gorm:"column:agent_level" json:"agent_level"`
 	Freeze            float64 `gorm:"column:freeze" json:"freeze"`
 	Balance           float64 `gorm:"column:balance" json:"balance"`
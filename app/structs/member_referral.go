@@ -0,0 +1,26 @@
+package structs
+
+// MemberReferral 会员推荐关系闭包表，对应ims_member_referral表
+// 每个会员与自己有一条depth=0的记录，与每个上级各有一条记录，depth为相隔层级
+type MemberReferral struct {
+	ID           int `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Weid         int `gorm:"column:weid" json:"weid"`
+	AncestorID   int `gorm:"column:ancestor_id" json:"ancestor_id"`     // 上级会员
+	DescendantID int `gorm:"column:descendant_id" json:"descendant_id"` // 下级会员
+	Depth        int `gorm:"column:depth" json:"depth"`                 // 相隔层级，1为直推
+}
+
+// ReferralMember 团队成员或上级会员信息
+type ReferralMember struct {
+	ID       int    `gorm:"column:id" json:"id"`
+	Nickname string `gorm:"column:nickname" json:"nickname"`
+	Userpic  string `gorm:"column:userpic" json:"userpic"`
+	Regdate  int64  `gorm:"column:regdate" json:"regdate"`
+	Depth    int    `gorm:"column:depth" json:"depth"`
+}
+
+// ReferralDepthCount 团队各层级人数
+type ReferralDepthCount struct {
+	Depth int   `gorm:"column:depth" json:"depth"`
+	Count int64 `gorm:"column:count" json:"count"`
+}
@@ -44,3 +44,33 @@ type MiaoshaTime struct {
 	UpdateTime int    `gorm:"column:update_time" json:"update_time"`
 	Status     int    `gorm:"column:status" json:"status"`
 }
+
+// 秒杀场次状态
+const (
+	MiaoshaSessionEnded    = 0 // 已结束
+	MiaoshaSessionOngoing  = 1 // 抢购中
+	MiaoshaSessionUpcoming = 2 // 即将开始
+)
+
+// MiaoshaSession 当天的秒杀场次，由MiaoshaTime时间段计算得到
+type MiaoshaSession struct {
+	ID         int    `json:"id"`
+	BeginTime  string `json:"begin_time"`
+	EndTime    string `json:"end_time"`
+	StartAt    int64  `json:"start_at"`    // 场次开始时间戳
+	EndAt      int64  `json:"end_at"`      // 场次结束时间戳
+	Status     int    `json:"status"`      // 0已结束 1抢购中 2即将开始
+	StatusText string `json:"status_text"` // 状态文字
+	Countdown  int64  `json:"countdown"`   // 抢购中为距结束秒数，即将开始为距开始秒数
+}
+
+// MiaoshaSessionGoods 秒杀场次中的商品
+type MiaoshaSessionGoods struct {
+	MiaoshaGoods
+	Name          string  `json:"name"`
+	Image         string  `json:"image"`
+	OriginalPrice float64 `json:"original_price"`
+	Stock         int     `json:"stock" gorm:"-"`        // 剩余库存，-1表示不限量
+	Sold          int     `json:"sold" gorm:"-"`         // 已售数量
+	SoldPercent   int     `json:"sold_percent" gorm:"-"` // 已售百分比
+}
@@ -10,6 +10,7 @@ type Operatingcity struct {
 	Areatype     int8    `gorm:"column:areatype" json:"areatype"`
 	Title        string  `gorm:"column:title" json:"title"`
 	Income       float64 `gorm:"column:income" json:"income"`
+	IncomeFreeze float64 `gorm:"column:income_freeze" json:"income_freeze"` // 提现中冻结的收入
 	TotalIncome  float64 `gorm:"column:total_income" json:"total_income"`
 	CateIDs      string  `gorm:"column:cate_ids" json:"cate_ids"`
 	RegionName   string  `gorm:"column:region_name" json:"region_name"`
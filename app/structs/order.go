@@ -19,6 +19,7 @@ type Order struct {
 	Sendto               int     `json:"sendto" gorm:"column:sendto"`
 	TuanFoundID          int     `json:"tuan_found_id" gorm:"column:tuan_found_id"`
 	TuanID               int     `json:"tuan_id" gorm:"column:tuan_id"`
+	Tzid                 int     `json:"tzid" gorm:"column:tzid"`
 	MsID                 int     `json:"ms_id" gorm:"column:ms_id"`
 	Timesmum             int     `json:"timesmum" gorm:"column:timesmum"`
 	IsTimes              int     `json:"is_times" gorm:"column:is_times"`
@@ -58,6 +59,8 @@ type Order struct {
 	Remark               string  `json:"remark" gorm:"column:remark"`
 	Hangingpoint         string  `json:"hangingpoint" gorm:"column:hangingpoint"`
 	Total                float64 `json:"total" gorm:"column:total"`
+	Freight              float64 `json:"freight" gorm:"column:freight"` // 运费，不计入佣金
+	CommissionTotal      float64 `json:"commission_total" gorm:"column:commission_total"`
 	IsAdditional         int     `json:"is_additional" gorm:"column:is_additional"`
 	Additional           float64 `json:"additional" gorm:"column:additional"`
 	OrderStatusID        int     `json:"order_status_id" gorm:"column:order_status_id"`
@@ -76,3 +79,45 @@ type Order struct {
 	AdditionalPayTime    int     `json:"additional_pay_time" gorm:"column:additional_pay_time"`
 	HasInvoice           int     `json:"has_invoice" gorm:"column:has_invoice"`
 }
+
+// 订单状态 order_status_id
+const (
+	OrderStatusUnpaid    = 1 // 待付款
+	OrderStatusPaid      = 2 // 已付款
+	OrderStatusShipped   = 3 // 已发货
+	OrderStatusCompleted = 4 // 已完成
+	OrderStatusCancelled = 5 // 已取消
+	OrderStatusRefunded  = 6 // 已退款
+)
+
+// OrderGoods 订单商品结构体
+// 对应数据库中的ims_order_goods表
+type OrderGoods struct {
+	ID       int     `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Weid     int     `json:"weid" gorm:"column:weid"`
+	OrderID  int     `json:"order_id" gorm:"column:order_id"`
+	GoodsID  int     `json:"goods_id" gorm:"column:goods_id"`
+	Sid      int     `json:"sid" gorm:"column:sid"`
+	Name     string  `json:"name" gorm:"column:name"`
+	Image    string  `json:"image" gorm:"column:image"`
+	Sku      string  `json:"sku" gorm:"column:sku"`
+	Quantity int     `json:"quantity" gorm:"column:quantity"`
+	Price    float64 `json:"price" gorm:"column:price"`
+	Total    float64 `json:"total" gorm:"column:total"`
+}
+
+// OrderGoodsCombination 套装订单商品的组件收入分摊，对应ims_order_goods_combination表
+// 订单支付后按组件价值把订单商品金额分摊到各组件，供报表和佣金按组件统计
+type OrderGoodsCombination struct {
+	ID           int     `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Weid         int     `json:"weid" gorm:"column:weid"`
+	OrderID      int     `json:"order_id" gorm:"column:order_id"`
+	OrderGoodsID int     `json:"order_goods_id" gorm:"column:order_goods_id"`
+	ParentID     int     `json:"parent_id" gorm:"column:parent_id"` // 套装商品ID
+	GoodsID      int     `json:"goods_id" gorm:"column:goods_id"`   // 组件商品ID
+	Sku          string  `json:"sku" gorm:"column:sku"`
+	Quantity     int     `json:"quantity" gorm:"column:quantity"` // 组件数量，每套数量×购买套数
+	Price        float64 `json:"price" gorm:"column:price"`       // 组件单价
+	Amount       float64 `json:"amount" gorm:"column:amount"`     // 分摊到该组件的金额
+	CreateTime   int     `json:"create_time" gorm:"column:create_time"`
+}
@@ -21,6 +21,7 @@ type Product struct {
 	SaleCount            int     `gorm:"column:sale_count" json:"sale_count"`           // 销量
 	SaleCountBase        int     `gorm:"column:sale_count_base" json:"sale_count_base"` // 销量基数
 	StockStatusID        int     `gorm:"column:stock_status_id" json:"stock_status_id"` // 库存状态编号
+	LowStock             int     `gorm:"column:low_stock" json:"low_stock"`             // 低库存提醒阈值，0使用系统默认值
 	Image                string  `gorm:"column:image" json:"image"`
 	Videotype            int     `gorm:"column:videotype" json:"videotype"` // 1服务器2腾讯视频
 	Videoid              string  `gorm:"column:videoid" json:"videoid"`
@@ -41,6 +42,7 @@ type Product struct {
 	CouponNumber         int     `gorm:"column:coupon_number" json:"coupon_number"`                   // 购买送优惠券数量
 	DateAvailable        int     `gorm:"column:date_available" json:"date_available"`                 // 供货日期
 	Weight               float64 `gorm:"column:weight" json:"weight"`                                 // 重量
+	TransportID          int     `gorm:"column:transport_id" json:"transport_id"`                     // 运费模板ID，0使用店铺默认模板
 	Length               float64 `gorm:"column:length" json:"length"`                                 // 尺寸(长)
 	Width                float64 `gorm:"column:width" json:"width"`                                   // 尺寸(宽)
 	Height               float64 `gorm:"column:height" json:"height"`                                 // 尺寸(高)
@@ -80,6 +82,7 @@ type Product struct {
 	DistrictID           int     `gorm:"column:district_id" json:"district_id"`
 	CreateTime           int     `gorm:"column:create_time" json:"create_time"` // 添加时间
 	UpdateTime           int     `gorm:"column:update_time" json:"update_time"` // 更新时间
+	DeleteTime           int     `gorm:"column:delete_time" json:"delete_time"` // 删除时间，0未删除
 	Status               int     `gorm:"column:status" json:"status"`           // 是否公开
 }
 
@@ -176,10 +179,26 @@ type BuyNowInfoParams struct {
 
 // GoodsCombination 商品套装组合表，对应ims_goods_combination表
 type GoodsCombination struct {
-	ID       int `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	GoodsID  int `gorm:"column:goods_id" json:"goods_id"`   // 商品id
-	ParentID int `gorm:"column:parent_id" json:"parent_id"` // 所属套装主id
-	Numbers  int `gorm:"column:numbers" json:"numbers"`     // 数量
+	ID       int    `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	GoodsID  int    `gorm:"column:goods_id" json:"goods_id"`   // 商品id
+	ParentID int    `gorm:"column:parent_id" json:"parent_id"` // 所属套装主id
+	Numbers  int    `gorm:"column:numbers" json:"numbers"`     // 数量
+	Sku      string `gorm:"column:sku" json:"sku"`             // 组件规格，为空时使用商品库存
+}
+
+// CombinationItem 套装组件明细
+type CombinationItem struct {
+	GoodsID  int     `json:"goods_id"`
+	Name     string  `json:"name"`
+	Image    string  `json:"image"`
+	Sku      string  `json:"sku"`
+	SkuvID   int     `json:"skuvid"`   // 组件规格对应的goods_sku_value.id
+	Price    float64 `json:"price"`    // 组件单价，有规格时取规格价
+	Numbers  int     `json:"numbers"`  // 每套数量
+	Quantity int     `json:"quantity"` // 组件库存
+	Subtract int     `json:"-"`        // 组件是否扣减库存
+	Status   int     `json:"-"`
+	Amount   float64 `json:"amount"` // 分摊到该组件的收入
 }
 
 // GoodsCourse 课程表，对应ims_goods_course表
@@ -301,6 +320,7 @@ type GoodsSkuValue struct {
 	Sku      string  `gorm:"column:sku" json:"sku"`
 	Image    string  `gorm:"column:image" json:"image"`
 	Quantity int     `gorm:"column:quantity" json:"quantity"`
+	LowStock int     `gorm:"column:low_stock" json:"low_stock"` // 低库存提醒阈值，0使用商品的阈值
 	Price    float64 `gorm:"column:price;" json:"price"`
 }
 
@@ -320,3 +340,75 @@ type GoodsToCategory struct {
 	GoodsID    int `gorm:"column:goods_id;primaryKey" json:"goods_id"`
 	CategoryID int `gorm:"column:category_id;primaryKey" json:"category_id"`
 }
+
+// ProductSpec 商品规格，Items为该规格下的可选项
+type ProductSpec struct {
+	Name     string   `json:"name"`
+	Ptype    string   `json:"ptype"`
+	Required int      `json:"required"`
+	Items    []string `json:"items"`
+}
+
+// ProductDetail 商品详情，商品、图片、描述、规格和数量折扣按商品缓存，团购和秒杀活动每次实时查询
+type ProductDetail struct {
+	Goods       *Product                 `json:"goods"`
+	Images      []map[string]interface{} `json:"images"`
+	Description *GoodsDescription        `json:"description"`
+	Specs       []ProductSpec            `json:"specs"`
+	SkuValues   []GoodsSkuValue          `json:"sku_values"`
+	Discounts   []GoodsDiscount          `json:"discounts"`
+	Tuan        *TuanGoods               `json:"tuan,omitempty"`
+	TuanSkus    []TuanGoodsSkuValue      `json:"tuan_skus,omitempty"`
+	Miaosha     *MiaoshaGoods            `json:"miaosha,omitempty"`
+	MiaoshaSkus []MiaoshaGoodsSkuValue   `json:"miaosha_skus,omitempty"`
+}
+
+// 商品列表排序方式
+const (
+	ProductSortDefault = "default" // 按后台排序值
+	ProductSortPrice   = "price"   // 按价格
+	ProductSortSales   = "sales"   // 按销量，含销量基数
+	ProductSortNewest  = "newest"  // 按上架时间
+)
+
+// ProductCursor 商品列表游标，记录上一页最后一条的排序值和ID
+type ProductCursor struct {
+	Value float64
+	ID    int
+}
+
+// ProductListFilter 商品列表筛选条件，Cursor不为空时按游标翻页，否则按页码翻页
+type ProductListFilter struct {
+	Weid          int
+	CatID         int
+	Ocid          int
+	Sid           int
+	MinPrice      float64
+	MaxPrice      float64 // 0不限
+	IsHot         int     // 1只看热卖
+	IsNew         int     // 1只看新品
+	IsRecommended int     // 1只看推荐
+	Sort          string
+	Desc          bool
+	Cursor        *ProductCursor
+}
+
+// ProductList 商品列表分页结果，NextCursor为空表示没有下一页
+type ProductList struct {
+	List       []Product `json:"list"`
+	Total      int64     `json:"total"`
+	Page       int       `json:"page"`
+	Limit      int       `json:"limit"`
+	NextCursor string    `json:"next_cursor"`
+}
+
+// ProductForm 后台商品编辑数据，商品与规格、图片、描述、数量折扣和分类关联一起保存
+type ProductForm struct {
+	Goods       Product          `json:"goods"`
+	Skus        []GoodsSku       `json:"skus"`
+	SkuValues   []GoodsSkuValue  `json:"sku_values"`
+	Images      []GoodsImage     `json:"images"`
+	Description GoodsDescription `json:"description"`
+	Discounts   []GoodsDiscount  `json:"discounts"`
+	CategoryIDs []int            `json:"category_ids"` // 除主分类外的其他分类
+}
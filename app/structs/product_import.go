@@ -0,0 +1,76 @@
+package structs
+
+// 商品导入任务状态
+const (
+	ProductImportRunning = "running"
+	ProductImportDone    = "done"
+	ProductImportFailed  = "failed"
+)
+
+// 商品导入行的处理方式
+const (
+	ProductImportCreate = "create"
+	ProductImportUpdate = "update"
+)
+
+// ProductImportRow 导入文件中的一行，一行对应一个SKU，同一商品的多行按商品ID或型号归并
+// Sku为空的行只修改商品本身，SkuPrice和SkuQuantity为nil表示不修改
+type ProductImportRow struct {
+	Row           int      `json:"row"` // 文件中的行号，表头为第1行
+	ID            int      `json:"id"`
+	Model         string   `json:"model"`
+	Name          string   `json:"name"`
+	Keyword       string   `json:"keyword"`
+	CatID         int      `json:"cat_id"`
+	Price         float64  `json:"price"`
+	OriginalPrice float64  `json:"original_price"`
+	Quantity      int      `json:"quantity"`
+	QuantityUnit  string   `json:"quantity_unit"`
+	Image         string   `json:"image"`
+	Sort          int      `json:"sort"`
+	Status        int      `json:"status"`
+	Sku           string   `json:"sku"`
+	SkuPrice      *float64 `json:"sku_price"`
+	SkuQuantity   *int     `json:"sku_quantity"`
+}
+
+// ProductImportError 导入行的校验或保存错误
+type ProductImportError struct {
+	Row int    `json:"row"`
+	Msg string `json:"msg"`
+}
+
+// ProductImportItem 导入预览中的一个商品
+type ProductImportItem struct {
+	Rows   []int  `json:"rows"`
+	ID     int    `json:"id"` // 新增商品预览时为0
+	Model  string `json:"model"`
+	Name   string `json:"name"`
+	Action string `json:"action"` // create或update
+	Skus   int    `json:"skus"`   // 修改的SKU数
+}
+
+// ProductImportResult 商品导入结果，DryRun为true时只做校验不写入
+type ProductImportResult struct {
+	DryRun   bool                 `json:"dry_run"`
+	Rows     int                  `json:"rows"`
+	Products int                  `json:"products"`
+	Created  int                  `json:"created"`
+	Updated  int                  `json:"updated"`
+	Errors   []ProductImportError `json:"errors"`
+	Items    []ProductImportItem  `json:"items"`
+}
+
+// ProductImportJob 后台商品导入任务的进度
+type ProductImportJob struct {
+	JobID      string               `json:"job_id"`
+	Weid       int                  `json:"weid"`
+	AdminID    int                  `json:"admin_id"` // 创建任务的管理员，只有创建人可以查询进度
+	Status     string               `json:"status"`
+	Total      int                  `json:"total"`     // 待导入的商品数
+	Processed  int                  `json:"processed"` // 已处理的商品数
+	Msg        string               `json:"msg"`
+	Result     *ProductImportResult `json:"result"`
+	CreateTime int64                `json:"create_time"`
+	UpdateTime int64                `json:"update_time"`
+}
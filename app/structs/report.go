@@ -0,0 +1,41 @@
+package structs
+
+// 收入报表类型
+const (
+	ReportKindOperatingcity = "operatingcity" // 城市代理收入
+	ReportKindTuanzhang     = "tuanzhang"     // 团长收入
+)
+
+// 收入报表汇总周期
+const (
+	ReportPeriodMonth = "month"
+	ReportPeriodYear  = "year"
+)
+
+// IncomeReportFilter 收入报表查询条件
+// AccountIDs为空时不限代理或团长，From、To按周期格式(2006-01或2006)筛选，为空时不限
+type IncomeReportFilter struct {
+	Kind       string
+	Weid       int
+	AccountIDs []int
+	Period     string
+	From       string
+	To         string
+}
+
+// IncomeReport 代理或团长按月或按年汇总的收入
+type IncomeReport struct {
+	AccountID int     `gorm:"column:account_id" json:"account_id"`
+	Title     string  `gorm:"column:title" json:"title"`
+	Period    string  `gorm:"column:period" json:"period"`
+	Income    float64 `gorm:"column:income" json:"income"`
+	Orders    int64   `gorm:"column:orders" json:"orders"`
+	Lines     int64   `gorm:"column:lines" json:"lines"`
+}
+
+// IncomeReportTotal 收入报表合计
+type IncomeReportTotal struct {
+	Income float64 `gorm:"column:income" json:"income"`
+	Orders int64   `gorm:"column:orders" json:"orders"`
+	Lines  int64   `gorm:"column:lines" json:"lines"`
+}
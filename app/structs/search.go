@@ -0,0 +1,29 @@
+package structs
+
+// SearchProduct 商品搜索索引使用的商品字段
+type SearchProduct struct {
+	ID            int     `gorm:"column:id" json:"id"`
+	Weid          int     `gorm:"column:weid" json:"weid"`
+	Name          string  `gorm:"column:name" json:"name"`
+	Keyword       string  `gorm:"column:keyword" json:"keyword"`
+	Image         string  `gorm:"column:image" json:"image"`
+	Price         float64 `gorm:"column:price" json:"price"`
+	OriginalPrice float64 `gorm:"column:original_price" json:"original_price"`
+	SaleCount     int     `gorm:"column:sale_count" json:"sale_count"`
+	SaleCountBase int     `gorm:"column:sale_count_base" json:"sale_count_base"`
+}
+
+// SearchHit 商品搜索结果，Highlight为高亮后的商品名称
+type SearchHit struct {
+	SearchProduct
+	Highlight string  `json:"highlight"`
+	Score     float64 `json:"score"`
+}
+
+// SearchResult 商品搜索分页结果
+type SearchResult struct {
+	List  []SearchHit `json:"list"`
+	Total int         `json:"total"`
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
+}
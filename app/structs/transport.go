@@ -0,0 +1,31 @@
+package structs
+
+// 运费模板计费方式 charge_type
+const (
+	TransportChargeByPiece  = 1 // 按件数计费
+	TransportChargeByWeight = 2 // 按重量计费
+)
+
+// Transport 运费模板表，对应ims_transport表，地区运费规则见TransportExtend
+type Transport struct {
+	ID         int     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Weid       int     `gorm:"column:weid" json:"weid"`
+	Sid        int     `gorm:"column:sid" json:"sid"`                 // 店铺ID
+	Title      string  `gorm:"column:title" json:"title"`             // 模板名称
+	ChargeType int     `gorm:"column:charge_type" json:"charge_type"` // 计费方式 1按件 2按重量
+	FreeAmount float64 `gorm:"column:free_amount" json:"free_amount"` // 满额包邮，0不包邮
+	IsDefault  int     `gorm:"column:is_default" json:"is_default"`   // 是否店铺默认模板
+	Sort       int     `gorm:"column:sort" json:"sort"`
+	Status     int     `gorm:"column:status" json:"status"`
+	CreateTime int     `gorm:"column:create_time" json:"create_time"`
+}
+
+// FreightItem 参与运费计算的商品项
+type FreightItem struct {
+	Sid         int     `json:"sid"`          // 店铺ID
+	GoodsID     int     `json:"goods_id"`     // 商品ID
+	TransportID int     `json:"transport_id"` // 运费模板ID，0使用店铺默认模板
+	Quantity    int     `json:"quantity"`     // 购买数量
+	Weight      float64 `json:"weight"`       // 单件重量
+	Amount      float64 `json:"amount"`       // 商品金额，用于满额包邮
+}
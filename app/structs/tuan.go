@@ -54,6 +54,7 @@ type TuanFollow struct {
 	IsRobot     int    `gorm:"column:is_robot" json:"is_robot"`
 	PayTime     int    `gorm:"column:pay_time" json:"pay_time"`
 	IsRefund    int    `gorm:"column:is_refund" json:"is_refund"`
+	LuckyStatus int    `gorm:"column:lucky_status" json:"lucky_status"`
 	Status      int    `gorm:"column:status" json:"status"`
 	TuanEndTime int    `gorm:"column:tuan_end_time" json:"tuan_end_time"`
 }
@@ -113,6 +114,7 @@ type Tuanzhang struct {
 	CateIds        string  `gorm:"column:cate_ids" json:"cate_ids"`
 	TotalIncome    float64 `gorm:"column:total_income" json:"total_income"`
 	Income         float64 `gorm:"column:income" json:"income"`
+	IncomeFreeze   float64 `gorm:"column:income_freeze" json:"income_freeze"` // 提现中冻结的收入
 	Points         int     `gorm:"column:points" json:"points"`
 	Email          string  `gorm:"column:email" json:"email"`
 	Introduction   string  `gorm:"column:introduction" json:"introduction"`
@@ -131,6 +133,8 @@ type Tuanzhang struct {
 	HouseNumber    string  `gorm:"column:house_number" json:"house_number"`
 	Customtext     string  `gorm:"column:customtext" json:"customtext"`
 	CreateTime     int     `gorm:"column:create_time" json:"create_time"`
+	AuditTime      int     `gorm:"column:audit_time" json:"audit_time"`
+	AuditRemark    string  `gorm:"column:audit_remark" json:"audit_remark"`
 	IsBusiness     int     `gorm:"column:is_business" json:"is_business"`
 	IsStore        int     `gorm:"column:is_store" json:"is_store"`
 	Sort           int     `gorm:"column:sort" json:"sort"`
@@ -169,3 +173,88 @@ type TuanzhangLevel struct {
 	Sort          int     `gorm:"column:sort" json:"sort"`
 	Status        int     `gorm:"column:status" json:"status"`
 }
+
+// 开团状态
+const (
+	TuanFoundPending = 0 // 待成团，团长未支付
+	TuanFoundOngoing = 1 // 拼团中
+	TuanFoundSuccess = 2 // 拼团成功
+	TuanFoundFailed  = 3 // 拼团失败
+)
+
+// 参团状态
+const (
+	TuanFollowUnpaid  = 0 // 待支付
+	TuanFollowPaid    = 1 // 已支付，等待成团
+	TuanFollowSuccess = 2 // 拼团成功
+	TuanFollowFailed  = 3 // 拼团失败
+)
+
+// 抽奖团的中奖状态
+const (
+	TuanLuckyNone  = 0 // 未抽奖或非抽奖团
+	TuanLuckyWin   = 1 // 中奖，正常发货
+	TuanLuckyLoser = 2 // 未中奖，退款
+)
+
+// TuanLuckyDraw 抽奖团开奖记录，对应ims_tuan_lucky_draw表
+// 中奖者按sha256(seed:参团记录ID)从小到大排序取前WinnerNum个，可用Seed和Candidates复核
+type TuanLuckyDraw struct {
+	ID         int    `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Weid       int    `gorm:"column:weid" json:"weid"`
+	FoundID    int    `gorm:"column:found_id" json:"found_id"`
+	TuanID     int    `gorm:"column:tuan_id" json:"tuan_id"`
+	Seed       string `gorm:"column:seed" json:"seed"`
+	Candidates string `gorm:"column:candidates" json:"candidates"`
+	Winners    string `gorm:"column:winners" json:"winners"`
+	WinnerNum  int    `gorm:"column:winner_num" json:"winner_num"`
+	DrawTime   int    `gorm:"column:draw_time" json:"draw_time"`
+}
+
+// TuanFoundBrief 开团列表项，Remaining为剩余秒数
+type TuanFoundBrief struct {
+	TuanFound
+	PeopleNum int   `gorm:"column:people_num" json:"people_num"`
+	Remaining int64 `gorm:"-" json:"remaining"`
+}
+
+// TuanFollower 开团详情中展示的参团成员，不返回会员ID和订单信息
+type TuanFollower struct {
+	Nickname string `gorm:"column:nickname" json:"nickname"`
+	Avatar   string `gorm:"column:avatar" json:"avatar"`
+	IsHead   int    `gorm:"column:is_head" json:"is_head"`
+	PayTime  int    `gorm:"column:pay_time" json:"pay_time"`
+}
+
+// TuanMyFound 我的拼团列表项
+type TuanMyFound struct {
+	FollowID     int    `gorm:"column:follow_id" json:"follow_id"`
+	FoundID      int    `gorm:"column:found_id" json:"found_id"`
+	TuanID       int    `gorm:"column:tuan_id" json:"tuan_id"`
+	OrderID      int    `gorm:"column:order_id" json:"order_id"`
+	Title        string `gorm:"column:title" json:"title"`
+	PeopleNum    int    `gorm:"column:people_num" json:"people_num"`
+	IsHead       int    `gorm:"column:is_head" json:"is_head"`
+	IsRefund     int    `gorm:"column:is_refund" json:"is_refund"`
+	LuckyStatus  int    `gorm:"column:lucky_status" json:"lucky_status"`
+	FollowStatus int    `gorm:"column:follow_status" json:"follow_status"`
+	FoundStatus  int    `gorm:"column:found_status" json:"found_status"`
+	Join         int    `gorm:"column:join" json:"join"`
+	Need         int    `gorm:"column:need" json:"need"`
+	FoundEndTime int    `gorm:"column:found_end_time" json:"found_end_time"`
+	Remaining    int64  `gorm:"-" json:"remaining"`
+}
+
+// 团长审核状态
+const (
+	TuanzhangPending  = 0 // 待审核
+	TuanzhangApproved = 1 // 已通过
+	TuanzhangRejected = 2 // 已驳回
+)
+
+// TuanzhangMonthIncome 团长按月汇总的收入
+type TuanzhangMonthIncome struct {
+	MonthTime string  `gorm:"column:month_time" json:"month_time"`
+	Income    float64 `gorm:"column:income" json:"income"`
+	Orders    int     `gorm:"column:orders" json:"orders"`
+}
@@ -0,0 +1,87 @@
+package structs
+
+// 钱包账户，复式记账时每笔业务在两个账户间一借一贷
+const (
+	WalletAccountBalance = "balance" // 会员可用余额
+	WalletAccountFreeze  = "freeze"  // 会员冻结金额
+	WalletAccountCash    = "cash"    // 平台资金（支付渠道收款、提现打款）
+	WalletAccountOrder   = "order"   // 订单收款
+
+	WalletAccountIncome       = "income"        // 代理或团长可用收入
+	WalletAccountIncomeFreeze = "income_freeze" // 代理或团长提现中冻结的收入
+)
+
+// 分录方向
+const (
+	LedgerDebit  = 1 // 借
+	LedgerCredit = 2 // 贷
+)
+
+// MemberWalletLedger 会员钱包流水表，对应ims_member_wallet_ledger表
+// 每笔业务写入借贷两条分录，同一笔业务的分录共用TxnNo
+type MemberWalletLedger struct {
+	ID           int     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Weid         int     `gorm:"column:weid" json:"weid"`
+	Uid          int     `gorm:"column:uid" json:"uid"`
+	TxnNo        string  `gorm:"column:txn_no" json:"txn_no"`               // 业务流水号
+	BizType      string  `gorm:"column:biz_type" json:"biz_type"`           // 业务类型：recharge/pay/freeze/unfreeze/withdraw/refund/commission
+	BizID        int     `gorm:"column:biz_id" json:"biz_id"`               // 关联业务ID
+	Account      string  `gorm:"column:account" json:"account"`             // 账户
+	Direction    int     `gorm:"column:direction" json:"direction"`         // 1借 2贷
+	Amount       float64 `gorm:"column:amount" json:"amount"`               // 金额
+	BalanceAfter float64 `gorm:"column:balance_after" json:"balance_after"` // 分录后会员账户余额
+	Remark       string  `gorm:"column:remark" json:"remark"`
+	CreateTime   int     `gorm:"column:create_time" json:"create_time"`
+}
+
+// MemberRecharge 会员充值单，对应ims_member_recharge表
+type MemberRecharge struct {
+	ID          int     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Weid        int     `gorm:"column:weid" json:"weid"`
+	Uid         int     `gorm:"column:uid" json:"uid"`
+	RechargeSn  string  `gorm:"column:recharge_sn" json:"recharge_sn"`
+	Amount      float64 `gorm:"column:amount" json:"amount"`
+	PaymentCode string  `gorm:"column:payment_code" json:"payment_code"`
+	TradeNo     string  `gorm:"column:trade_no" json:"trade_no"` // 支付渠道交易号
+	PayTime     int     `gorm:"column:pay_time" json:"pay_time"`
+	CreateTime  int     `gorm:"column:create_time" json:"create_time"`
+	Status      int     `gorm:"column:status" json:"status"` // 0待支付 1已支付
+}
+
+// 提现账户类型
+const (
+	WithdrawPtypeMember        = "member"        // 会员余额，含已解冻的分销佣金
+	WithdrawPtypeOperatingcity = "operatingcity" // 城市代理收入
+	WithdrawPtypeTuanzhang     = "tuanzhang"     // 团长收入
+)
+
+// 提现状态，见helper.WithdrawStatus
+const (
+	WithdrawPending  = 0 // 待审核，申请金额已冻结
+	WithdrawApproved = 1 // 审核通过，等待批量打款
+	WithdrawRejected = 2 // 驳回，金额已退回
+)
+
+// Withdraw 提现申请表，对应ims_withdraw表
+type Withdraw struct {
+	ID           int     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Weid         int     `gorm:"column:weid" json:"weid"`
+	Ptype        string  `gorm:"column:ptype" json:"ptype"` // 提现账户类型：member/operatingcity/tuanzhang
+	Uid          int     `gorm:"column:uid" json:"uid"`
+	AccountID    int     `gorm:"column:account_id" json:"account_id"` // 代理或团长ID，会员余额提现为0
+	WithdrawSn   string  `gorm:"column:withdraw_sn" json:"withdraw_sn"`
+	Amount       float64 `gorm:"column:amount" json:"amount"`               // 申请金额
+	Fee          float64 `gorm:"column:fee" json:"fee"`                     // 手续费
+	ActualAmount float64 `gorm:"column:actual_amount" json:"actual_amount"` // 实际打款金额
+	CollectType  string  `gorm:"column:collect_type" json:"collect_type"`   // 收款方式，见helper.GetCollectType
+	AccountName  string  `gorm:"column:account_name" json:"account_name"`
+	AccountNo    string  `gorm:"column:account_no" json:"account_no"`
+	BankName     string  `gorm:"column:bank_name" json:"bank_name"`
+	Remark       string  `gorm:"column:remark" json:"remark"`     // 驳回原因
+	BatchNo      string  `gorm:"column:batch_no" json:"batch_no"` // 打款批次号
+	AuditTime    int     `gorm:"column:audit_time" json:"audit_time"`
+	CreateTime   int     `gorm:"column:create_time" json:"create_time"`
+	UpdateTime   int     `gorm:"column:update_time" json:"update_time"`
+	Status       int     `gorm:"column:status" json:"status"` // 0未处理 1已结算 2驳回，见helper.WithdrawStatus
+	StatusText   string  `gorm:"-" json:"status_text"`
+}
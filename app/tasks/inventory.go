@@ -0,0 +1,16 @@
+package tasks
+
+import (
+	"log"
+
+	"myapi/app/services"
+)
+
+// releaseStockReservations 释放超时未支付的商品库存预留
+func releaseStockReservations() error {
+	released, err := services.NewInventoryService().ReleaseExpired()
+	if released > 0 {
+		log.Printf("已释放%d个超时的库存预留", released)
+	}
+	return err
+}
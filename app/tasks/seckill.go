@@ -0,0 +1,43 @@
+package tasks
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"myapi/app/services"
+)
+
+// preloadSeckillStock 预热即将开始的秒杀活动库存
+func preloadSeckillStock() error {
+	return services.NewSeckillService().PreloadUpcoming()
+}
+
+// releaseSeckillReservations 释放支付超时的秒杀库存预留
+func releaseSeckillReservations() error {
+	released, err := services.NewSeckillService().ReleaseExpired()
+	if released > 0 {
+		log.Printf("已释放%d个超时的秒杀库存预留", released)
+	}
+	return err
+}
+
+// reconcileSeckillStock 把Redis中的秒杀库存和销量写回数据库
+func reconcileSeckillStock() error {
+	return services.NewSeckillService().Reconcile()
+}
+
+// warmMiaoshaSessions 在秒杀场次开始前预热商品列表和库存
+func warmMiaoshaSessions() error {
+	return services.NewMiaoshaService().WarmUpcomingSessions()
+}
+
+// consumeSeckillQueue 持续消费秒杀排队请求，异常退出后重新启动
+func consumeSeckillQueue() {
+	for {
+		run("秒杀排队处理", func() error {
+			return services.NewSeckillQueueService().Consume(context.Background())
+		})
+		time.Sleep(time.Second)
+	}
+}
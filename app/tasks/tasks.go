@@ -0,0 +1,50 @@
+package tasks
+
+import (
+	"log"
+	"time"
+
+	"myapi/app/config"
+	"myapi/app/storage"
+)
+
+// Start 启动后台定时任务，需要在MySQL和Redis初始化之后调用
+func Start() {
+	if storage.GetRedis() != nil {
+		every("秒杀库存预热", time.Duration(config.GetInt("miaoshaPreloadInterval", 30))*time.Second, preloadSeckillStock)
+		every("秒杀场次预热", time.Duration(config.GetInt("miaoshaPreloadInterval", 30))*time.Second, warmMiaoshaSessions)
+		every("秒杀预留超时释放", time.Duration(config.GetInt("miaoshaReleaseInterval", 10))*time.Second, releaseSeckillReservations)
+		every("秒杀库存同步", time.Duration(config.GetInt("miaoshaReconcileInterval", 5))*time.Second, reconcileSeckillStock)
+		go consumeSeckillQueue()
+	}
+	every("拼团过期处理", time.Duration(config.GetInt("tuanExpireInterval", 30))*time.Second, expireTuanFounds)
+	every("拼团机器人和开奖", time.Duration(config.GetInt("tuanSettleInterval", 30))*time.Second, settleTuanFounds)
+	every("库存预留超时释放", time.Duration(config.GetInt("stockReleaseInterval", 60))*time.Second, releaseStockReservations)
+	log.Println("后台任务已启动")
+}
+
+// every 按固定间隔在后台执行任务，任务panic不影响下一次执行
+func every(name string, interval time.Duration, job func() error) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run(name, job)
+		}
+	}()
+}
+
+// run 执行一次任务并记录错误
+func run(name string, job func() error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("后台任务[%s]异常: %v", name, r)
+		}
+	}()
+	if err := job(); err != nil {
+		log.Printf("后台任务[%s]执行失败: %v", name, err)
+	}
+}
@@ -0,0 +1,17 @@
+package tasks
+
+import "myapi/app/services"
+
+// expireTuanFounds 处理过期未成团的开团，并给参团会员退款
+func expireTuanFounds() error {
+	return services.NewTuanService().ExpireFounds()
+}
+
+// settleTuanFounds 机器人补满即将过期的团，并给已成团的抽奖团开奖
+func settleTuanFounds() error {
+	tuanService := services.NewTuanService()
+	if err := tuanService.FillRobots(); err != nil {
+		return err
+	}
+	return tuanService.SettleLuckyDraws()
+}
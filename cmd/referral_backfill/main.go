@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"myapi/app/config"
+	"myapi/app/services"
+	"myapi/app/storage"
+)
+
+// 根据会员表的pid回填推荐关系闭包表
+// 用法：go run ./cmd/referral_backfill [-weid 站点ID] [-reset]
+func main() {
+	weid := flag.Int("weid", 0, "只处理指定站点，0为全部站点")
+	reset := flag.Bool("reset", false, "先清空已有推荐关系再重建")
+	flag.Parse()
+
+	// 初始化配置
+	config.Init()
+
+	// 初始化MySQL连接
+	if err := storage.InitMySQL(); err != nil {
+		log.Fatalf("初始化MySQL连接失败: %v", err)
+	}
+	defer storage.CloseMySQL()
+
+	total, err := services.NewReferralService().Backfill(*weid, *reset)
+	if err != nil {
+		log.Fatalf("回填推荐关系失败: %v", err)
+	}
+	log.Printf("回填推荐关系完成，共写入%d条", total)
+}
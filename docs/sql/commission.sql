@@ -0,0 +1,28 @@
+-- 佣金：订单记录已分配的佣金总额，用于控制各方佣金不超过订单的配置比例
+
+ALTER TABLE `ims_order`
+  ADD COLUMN `commission_total` decimal(10,2) NOT NULL DEFAULT '0.00' COMMENT '已分配的佣金总额' AFTER `total`;
+
+ALTER TABLE `ims_operatingcity_incomelog`
+  ADD KEY `ocid_order` (`ocid`,`areatype`,`order_id`);
+
+-- 分销佣金：订单支付后给上三级推荐人记录佣金，订单完成前冻结，退款时冲回
+CREATE TABLE IF NOT EXISTS `ims_member_commission` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `weid` int(11) NOT NULL DEFAULT '0',
+  `uid` int(11) NOT NULL DEFAULT '0' COMMENT '获得佣金的会员',
+  `buyer_id` int(11) NOT NULL DEFAULT '0' COMMENT '下单会员',
+  `order_id` int(11) NOT NULL DEFAULT '0',
+  `goods_id` int(11) NOT NULL DEFAULT '0',
+  `level` tinyint(1) NOT NULL DEFAULT '1' COMMENT '推荐层级1-3',
+  `method` tinyint(1) NOT NULL DEFAULT '0' COMMENT '0百分比 1每件固定金额',
+  `rate` decimal(10,2) NOT NULL DEFAULT '0.00' COMMENT '百分比或每件金额',
+  `base_amount` decimal(10,2) NOT NULL DEFAULT '0.00' COMMENT '商品成交金额',
+  `amount` decimal(10,2) NOT NULL DEFAULT '0.00',
+  `status` tinyint(1) NOT NULL DEFAULT '0' COMMENT '0冻结 1已结算 2已冲回',
+  `create_time` int(11) NOT NULL DEFAULT '0',
+  `settle_time` int(11) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`id`),
+  KEY `order_id` (`order_id`,`status`),
+  KEY `uid` (`uid`,`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='分销佣金记录';
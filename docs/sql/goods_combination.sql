@@ -0,0 +1,22 @@
+-- 套装商品：组件可指定规格，库存和价格按该规格计算
+
+ALTER TABLE `ims_goods_combination`
+  ADD COLUMN `sku` varchar(255) NOT NULL DEFAULT '' COMMENT '组件规格，多个规格值逗号分隔' AFTER `goods_id`;
+
+-- 套装订单商品的组件收入分摊：订单支付后按组件价值分摊订单商品金额
+CREATE TABLE IF NOT EXISTS `ims_order_goods_combination` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `weid` int(11) NOT NULL DEFAULT '0',
+  `order_id` int(11) NOT NULL DEFAULT '0',
+  `order_goods_id` int(11) NOT NULL DEFAULT '0',
+  `parent_id` int(11) NOT NULL DEFAULT '0' COMMENT '套装商品ID',
+  `goods_id` int(11) NOT NULL DEFAULT '0' COMMENT '组件商品ID',
+  `sku` varchar(255) NOT NULL DEFAULT '',
+  `quantity` int(11) NOT NULL DEFAULT '0' COMMENT '组件数量，每套数量×购买套数',
+  `price` decimal(10,2) NOT NULL DEFAULT '0.00' COMMENT '组件单价',
+  `amount` decimal(10,2) NOT NULL DEFAULT '0.00' COMMENT '分摊到该组件的金额',
+  `create_time` int(11) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`id`),
+  KEY `order_id` (`order_id`),
+  KEY `weid_goods` (`weid`,`goods_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='套装订单商品组件收入分摊';
@@ -0,0 +1,48 @@
+-- 商品库存：立即购买库存预留、库存流水
+
+CREATE TABLE IF NOT EXISTS `ims_goods_stock_reservation` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `weid` int(11) NOT NULL DEFAULT '0',
+  `uid` int(11) NOT NULL DEFAULT '0',
+  `record_id` int(11) NOT NULL DEFAULT '0' COMMENT '立即购买记录ID',
+  `order_id` int(11) NOT NULL DEFAULT '0' COMMENT '支付后关联的订单，支付前为0',
+  `goods_id` int(11) NOT NULL DEFAULT '0',
+  `skuv_id` int(11) NOT NULL DEFAULT '0' COMMENT '规格SKU ID，0表示不区分规格',
+  `sku` varchar(255) NOT NULL DEFAULT '',
+  `quantity` int(11) NOT NULL DEFAULT '0',
+  `status` tinyint(1) NOT NULL DEFAULT '1' COMMENT '1已预留 2已确认 3已释放',
+  `expire_time` int(11) NOT NULL DEFAULT '0' COMMENT '未支付时到期释放',
+  `create_time` int(11) NOT NULL DEFAULT '0',
+  `update_time` int(11) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`id`),
+  KEY `record_id` (`record_id`),
+  KEY `order_status` (`order_id`,`status`),
+  KEY `uid_goods` (`uid`,`goods_id`,`status`),
+  KEY `status_expire` (`status`,`expire_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='商品库存预留';
+
+CREATE TABLE IF NOT EXISTS `ims_goods_stock_ledger` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `weid` int(11) NOT NULL DEFAULT '0',
+  `goods_id` int(11) NOT NULL DEFAULT '0',
+  `skuv_id` int(11) NOT NULL DEFAULT '0',
+  `sku` varchar(255) NOT NULL DEFAULT '',
+  `record_id` int(11) NOT NULL DEFAULT '0' COMMENT '触发预留的立即购买记录',
+  `order_id` int(11) NOT NULL DEFAULT '0' COMMENT '触发变动的订单',
+  `type` varchar(20) NOT NULL DEFAULT '' COMMENT 'reserve立即购买预留 commit支付确认 release超时、取消或退款回补 adjust后台调整',
+  `change_quantity` int(11) NOT NULL DEFAULT '0' COMMENT '库存变化量，扣减为负数',
+  `quantity_after` int(11) NOT NULL DEFAULT '0' COMMENT '变动后商品库存',
+  `sku_quantity_after` int(11) NOT NULL DEFAULT '0' COMMENT '变动后SKU库存',
+  `remark` varchar(255) NOT NULL DEFAULT '',
+  `create_time` int(11) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`id`),
+  KEY `weid_goods` (`weid`,`goods_id`),
+  KEY `record_id` (`record_id`),
+  KEY `order_id` (`order_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='商品库存流水';
+
+-- 低库存提醒阈值，0使用系统默认值(商品)或商品的阈值(SKU)
+ALTER TABLE `ims_goods`
+  ADD COLUMN `low_stock` int(11) NOT NULL DEFAULT '0' COMMENT '低库存提醒阈值' AFTER `stock_status_id`;
+ALTER TABLE `ims_goods_sku_value`
+  ADD COLUMN `low_stock` int(11) NOT NULL DEFAULT '0' COMMENT '低库存提醒阈值' AFTER `quantity`;
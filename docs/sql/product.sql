@@ -0,0 +1,17 @@
+-- 商品列表：按站点、状态筛选后按排序值、价格、上架时间排序
+ALTER TABLE `ims_goods`
+  ADD KEY `weid_status_sort` (`weid`,`status`,`sort`,`id`),
+  ADD KEY `weid_status_price` (`weid`,`status`,`price`,`id`),
+  ADD KEY `weid_status_create` (`weid`,`status`,`create_time`,`id`);
+
+-- 商品多分类：按分类查商品
+ALTER TABLE `ims_goods_to_category`
+  ADD KEY `category_id` (`category_id`);
+
+-- 商品软删除：删除时下架并记录删除时间
+ALTER TABLE `ims_goods`
+  ADD COLUMN `delete_time` int(11) NOT NULL DEFAULT '0' COMMENT '删除时间，0未删除' AFTER `update_time`;
+
+-- 商品导入：按型号匹配已有商品
+ALTER TABLE `ims_goods`
+  ADD KEY `weid_model` (`weid`,`model`);
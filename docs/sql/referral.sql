@@ -0,0 +1,20 @@
+-- 推荐关系闭包表：每个会员与自己一条depth=0的记录，与每个上级各一条记录
+-- 存量数据执行 go run ./cmd/referral_backfill 根据 ims_member.pid 回填
+CREATE TABLE IF NOT EXISTS `ims_member_referral` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `weid` int(11) NOT NULL DEFAULT '0',
+  `ancestor_id` int(11) NOT NULL DEFAULT '0' COMMENT '上级会员',
+  `descendant_id` int(11) NOT NULL DEFAULT '0' COMMENT '下级会员',
+  `depth` int(11) NOT NULL DEFAULT '0' COMMENT '相隔层级，1为直推',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `ancestor_descendant` (`ancestor_id`,`descendant_id`),
+  KEY `ancestor_depth` (`ancestor_id`,`depth`),
+  KEY `descendant_depth` (`descendant_id`,`depth`),
+  KEY `weid_depth` (`weid`,`depth`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='会员推荐关系闭包表';
+
+-- 邀请码：会员首次查看邀请信息时生成，用于注册或登录时绑定推荐人
+-- 唯一索引防止并发生成重复的邀请码，未生成时为NULL
+ALTER TABLE `ims_member`
+  ADD COLUMN `invite_code` varchar(16) DEFAULT NULL COMMENT '邀请码' AFTER `pid`,
+  ADD UNIQUE KEY `invite_code` (`invite_code`);
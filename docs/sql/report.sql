@@ -0,0 +1,8 @@
+-- 收入报表：按代理或团长与月份、年份汇总收入明细
+ALTER TABLE `ims_operatingcity_incomelog`
+  ADD KEY `weid_month` (`weid`,`month_time`,`ocid`),
+  ADD KEY `weid_year` (`weid`,`year_time`,`ocid`);
+
+ALTER TABLE `ims_tuanzhang_incomelog`
+  ADD KEY `weid_month` (`weid`,`month_time`,`tzid`),
+  ADD KEY `weid_year` (`weid`,`year_time`,`tzid`);
@@ -0,0 +1,19 @@
+-- 运费模板：模板按件数或重量计费，地区规则见ims_transport_extend
+
+CREATE TABLE IF NOT EXISTS `ims_transport` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `weid` int(11) NOT NULL DEFAULT '0',
+  `sid` int(11) NOT NULL DEFAULT '0' COMMENT '店铺ID',
+  `title` varchar(60) NOT NULL DEFAULT '' COMMENT '模板名称',
+  `charge_type` tinyint(1) NOT NULL DEFAULT '1' COMMENT '计费方式 1按件 2按重量',
+  `free_amount` decimal(10,2) NOT NULL DEFAULT '0.00' COMMENT '满额包邮，0不包邮',
+  `is_default` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否店铺默认模板',
+  `sort` int(11) NOT NULL DEFAULT '0',
+  `status` tinyint(1) NOT NULL DEFAULT '1',
+  `create_time` int(11) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`id`),
+  KEY `weid_sid` (`weid`,`sid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='运费模板';
+
+ALTER TABLE `ims_goods`
+  ADD COLUMN `transport_id` int(11) NOT NULL DEFAULT '0' COMMENT '运费模板ID，0使用店铺默认模板' AFTER `weight`;
@@ -0,0 +1,31 @@
+-- 拼团：抽奖团开奖记录、参团记录的中奖状态，团长审核和收入
+
+CREATE TABLE IF NOT EXISTS `ims_tuan_lucky_draw` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `weid` int(11) NOT NULL DEFAULT '0',
+  `found_id` int(11) NOT NULL DEFAULT '0' COMMENT '开团ID',
+  `tuan_id` int(11) NOT NULL DEFAULT '0' COMMENT '团购商品ID',
+  `seed` varchar(64) NOT NULL DEFAULT '' COMMENT '开奖随机种子',
+  `candidates` text COMMENT '参与抽奖的参团记录ID，逗号分隔',
+  `winners` text COMMENT '中奖的参团记录ID，逗号分隔',
+  `winner_num` int(11) NOT NULL DEFAULT '0' COMMENT '中奖人数',
+  `draw_time` int(11) NOT NULL DEFAULT '0' COMMENT '开奖时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `found_id` (`found_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='抽奖团开奖记录';
+
+ALTER TABLE `ims_tuan_follow`
+  ADD COLUMN `lucky_status` tinyint(1) NOT NULL DEFAULT '0' COMMENT '中奖状态 0未抽奖 1中奖 2未中奖' AFTER `is_refund`;
+
+-- 团长：审核信息，订单记录下单时所属的团长
+
+ALTER TABLE `ims_tuanzhang`
+  ADD COLUMN `audit_time` int(11) NOT NULL DEFAULT '0' COMMENT '审核时间' AFTER `create_time`,
+  ADD COLUMN `audit_remark` varchar(255) NOT NULL DEFAULT '' COMMENT '审核备注，驳回原因' AFTER `audit_time`;
+
+ALTER TABLE `ims_order`
+  ADD COLUMN `tzid` int(11) NOT NULL DEFAULT '0' COMMENT '团长ID' AFTER `tuan_id`;
+
+ALTER TABLE `ims_tuanzhang_incomelog`
+  ADD KEY `tzid_order` (`tzid`,`order_id`),
+  ADD KEY `tzid_month` (`tzid`,`month_time`);
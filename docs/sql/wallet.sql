@@ -0,0 +1,70 @@
+-- 会员钱包：复式记账流水、充值单、提现申请
+
+CREATE TABLE IF NOT EXISTS `ims_member_wallet_ledger` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `weid` int(11) NOT NULL DEFAULT '0',
+  `uid` int(11) NOT NULL DEFAULT '0',
+  `txn_no` varchar(40) NOT NULL DEFAULT '' COMMENT '业务流水号，同一笔业务的借贷分录相同',
+  `biz_type` varchar(30) NOT NULL DEFAULT '' COMMENT '业务类型',
+  `biz_id` int(11) NOT NULL DEFAULT '0' COMMENT '关联业务ID',
+  `account` varchar(20) NOT NULL DEFAULT '' COMMENT '账户 balance/freeze/cash/order/income/income_freeze',
+  `direction` tinyint(1) NOT NULL DEFAULT '1' COMMENT '1借 2贷',
+  `amount` decimal(10,2) NOT NULL DEFAULT '0.00',
+  `balance_after` decimal(10,2) NOT NULL DEFAULT '0.00' COMMENT '分录后会员账户余额',
+  `remark` varchar(255) NOT NULL DEFAULT '',
+  `create_time` int(11) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`id`),
+  KEY `uid_account` (`uid`,`account`),
+  KEY `txn_no` (`txn_no`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='会员钱包流水';
+
+CREATE TABLE IF NOT EXISTS `ims_member_recharge` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `weid` int(11) NOT NULL DEFAULT '0',
+  `uid` int(11) NOT NULL DEFAULT '0',
+  `recharge_sn` varchar(40) NOT NULL DEFAULT '',
+  `amount` decimal(10,2) NOT NULL DEFAULT '0.00',
+  `payment_code` varchar(30) NOT NULL DEFAULT '',
+  `trade_no` varchar(64) NOT NULL DEFAULT '' COMMENT '支付渠道交易号',
+  `pay_time` int(11) NOT NULL DEFAULT '0',
+  `create_time` int(11) NOT NULL DEFAULT '0',
+  `status` tinyint(1) NOT NULL DEFAULT '0' COMMENT '0待支付 1已支付',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `recharge_sn` (`recharge_sn`),
+  KEY `uid` (`uid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='会员充值单';
+
+CREATE TABLE IF NOT EXISTS `ims_withdraw` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `weid` int(11) NOT NULL DEFAULT '0',
+  `ptype` varchar(20) NOT NULL DEFAULT 'member' COMMENT '提现账户类型',
+  `uid` int(11) NOT NULL DEFAULT '0',
+  `withdraw_sn` varchar(40) NOT NULL DEFAULT '',
+  `amount` decimal(10,2) NOT NULL DEFAULT '0.00',
+  `collect_type` varchar(20) NOT NULL DEFAULT '' COMMENT 'bank/wechat/alipay',
+  `account_name` varchar(50) NOT NULL DEFAULT '',
+  `account_no` varchar(64) NOT NULL DEFAULT '',
+  `bank_name` varchar(100) NOT NULL DEFAULT '',
+  `remark` varchar(255) NOT NULL DEFAULT '' COMMENT '驳回原因',
+  `create_time` int(11) NOT NULL DEFAULT '0',
+  `update_time` int(11) NOT NULL DEFAULT '0',
+  `status` tinyint(1) NOT NULL DEFAULT '0' COMMENT '0未处理 1已结算 2驳回',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `withdraw_sn` (`withdraw_sn`),
+  KEY `uid_status` (`uid`,`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='提现申请';
+
+-- 统一提现：支持城市代理收入和团长收入提现，记录手续费、审核时间和打款批次
+ALTER TABLE `ims_withdraw`
+  ADD COLUMN `account_id` int(11) NOT NULL DEFAULT '0' COMMENT '代理或团长ID' AFTER `uid`,
+  ADD COLUMN `fee` decimal(10,2) NOT NULL DEFAULT '0.00' COMMENT '手续费' AFTER `amount`,
+  ADD COLUMN `actual_amount` decimal(10,2) NOT NULL DEFAULT '0.00' COMMENT '实际打款金额' AFTER `fee`,
+  ADD COLUMN `batch_no` varchar(40) NOT NULL DEFAULT '' COMMENT '打款批次号' AFTER `remark`,
+  ADD COLUMN `audit_time` int(11) NOT NULL DEFAULT '0' AFTER `batch_no`,
+  ADD KEY `weid_status_batch` (`weid`,`status`,`batch_no`);
+
+-- 代理和团长提现：申请金额从可用收入转入冻结收入，审核通过后扣除，驳回时退回，分录写入钱包流水
+ALTER TABLE `ims_operatingcity`
+  ADD COLUMN `income_freeze` decimal(10,2) NOT NULL DEFAULT '0.00' COMMENT '提现中冻结的收入' AFTER `income`;
+ALTER TABLE `ims_tuanzhang`
+  ADD COLUMN `income_freeze` decimal(10,2) NOT NULL DEFAULT '0.00' COMMENT '提现中冻结的收入' AFTER `income`;
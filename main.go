@@ -6,6 +6,7 @@ import (
 	"myapi/app/api"
 	"myapi/app/config"
 	"myapi/app/storage"
+	"myapi/app/tasks"
 	"os"
 	"os/signal"
 	"syscall"
@@ -31,6 +32,9 @@ func main() {
 	}
 	defer storage.CloseRedis()
 
+	// 启动后台定时任务
+	tasks.Start()
+
 	// 创建路由引擎但不设置路由
 	router := gin.New()
 	router.Use(gin.Recovery())
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"testing"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+	"myapi/app/structs"
+)
+
+// TestTreeHasCycle 测试移动分类时的成环判断
+func TestTreeHasCycle(t *testing.T) {
+	// 1 -> 2 -> 3，4为另一棵树，5和6互为上级
+	parents := map[int]int{1: 0, 2: 1, 3: 2, 4: 0, 5: 6, 6: 5}
+
+	cases := []struct {
+		id, pid int
+		cycle   bool
+	}{
+		{1, 0, false},
+		{1, 1, true},
+		{1, 3, true},
+		{3, 4, false},
+		{4, 3, false},
+		{2, 3, true},
+		{4, 5, true},
+	}
+	for _, tc := range cases {
+		if got := helper.TreeHasCycle(parents, tc.id, tc.pid); got != tc.cycle {
+			t.Errorf("分类%d移动到%d下，期望成环%v，实际%v", tc.id, tc.pid, tc.cycle, got)
+		}
+	}
+}
+
+// TestBuildCategoryTree 测试分类树组装和含下级分类的商品数合计
+func TestBuildCategoryTree(t *testing.T) {
+	categories := []structs.Category{
+		{ID: 1, Pid: 0, Title: "家电"},
+		{ID: 2, Pid: 1, Title: "空调"},
+		{ID: 3, Pid: 2, Title: "挂机"},
+		{ID: 4, Pid: 0, Title: "服务"},
+		{ID: 5, Pid: 9, Title: "孤立分类"},
+	}
+	counts := map[int]int{1: 1, 2: 2, 3: 3, 5: 8}
+
+	tree := services.BuildCategoryTree(categories, counts)
+	if len(tree) != 2 {
+		t.Fatalf("顶级分类数量错误: %d", len(tree))
+	}
+	if tree[0]["goods_total"] != 6 || tree[0]["goods_count"] != 1 {
+		t.Errorf("家电商品数错误: %v %v", tree[0]["goods_count"], tree[0]["goods_total"])
+	}
+	children := tree[0]["children"].([]map[string]interface{})
+	if len(children) != 1 || children[0]["goods_total"] != 5 {
+		t.Errorf("空调子树错误: %v", children)
+	}
+	if tree[1]["goods_total"] != 0 {
+		t.Errorf("服务商品数错误: %v", tree[1]["goods_total"])
+	}
+}
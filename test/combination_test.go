@@ -0,0 +1,138 @@
+package test
+
+import (
+	"math"
+	"testing"
+
+	"myapi/app/models"
+	"myapi/app/services"
+	"myapi/app/structs"
+)
+
+// TestAllocateCombinationRevenue 测试套装收入按组件价值分摊，且分摊合计等于套装金额
+func TestAllocateCombinationRevenue(t *testing.T) {
+	items := []structs.CombinationItem{
+		{GoodsID: 1, Price: 30, Numbers: 1},
+		{GoodsID: 2, Price: 20, Numbers: 2},
+		{GoodsID: 3, Price: 10, Numbers: 3},
+	}
+
+	allocated := services.AllocateCombinationRevenue(100, items)
+	if len(allocated) != len(items) {
+		t.Fatalf("分摊结果数量错误: %d", len(allocated))
+	}
+
+	// 权重 30:40:30
+	expected := []float64{30, 40, 30}
+	sum := 0.0
+	for i, item := range allocated {
+		if math.Abs(item.Amount-expected[i]) > 0.001 {
+			t.Errorf("组件%d分摊金额错误，期望%.2f，实际%.2f", item.GoodsID, expected[i], item.Amount)
+		}
+		sum += item.Amount
+	}
+	if math.Abs(sum-100) > 0.001 {
+		t.Errorf("分摊合计错误，期望100，实际%.2f", sum)
+	}
+
+	// 原切片不应被修改
+	if items[0].Amount != 0 {
+		t.Errorf("分摊不应修改传入的组件列表")
+	}
+}
+
+// TestAllocateCombinationRevenueRemainder 测试不能整除时尾差计入最后一个组件
+func TestAllocateCombinationRevenueRemainder(t *testing.T) {
+	items := []structs.CombinationItem{
+		{GoodsID: 1, Price: 10, Numbers: 1},
+		{GoodsID: 2, Price: 10, Numbers: 1},
+		{GoodsID: 3, Price: 10, Numbers: 1},
+	}
+
+	allocated := services.AllocateCombinationRevenue(10, items)
+	if allocated[0].Amount != 3.33 || allocated[1].Amount != 3.33 {
+		t.Errorf("前两个组件分摊金额错误: %.2f, %.2f", allocated[0].Amount, allocated[1].Amount)
+	}
+	if allocated[2].Amount != 3.34 {
+		t.Errorf("尾差应计入最后一个组件，期望3.34，实际%.2f", allocated[2].Amount)
+	}
+}
+
+// TestCombinationStock 测试套装可售套数取各组件可组成套数的最小值
+func TestCombinationStock(t *testing.T) {
+	items := []structs.CombinationItem{
+		{GoodsID: 1, Numbers: 2, Quantity: 9, Subtract: 1},
+		{GoodsID: 2, Numbers: 1, Quantity: 7, Subtract: 1},
+		{GoodsID: 3, Numbers: 1, Quantity: 0, Subtract: 0},
+	}
+	if stock := services.CombinationStock(items); stock != 4 {
+		t.Errorf("可售套数错误，期望4，实际%d", stock)
+	}
+
+	if stock := services.CombinationStock(items[2:]); stock != -1 {
+		t.Errorf("组件都不扣库存时应返回-1，实际%d", stock)
+	}
+}
+
+// TestCombinationStockItems 测试套装商品换成组件库存项，组件数量按每套数量×套数计算并与普通商品合并
+func TestCombinationStockItems(t *testing.T) {
+	items := []structs.StockItem{
+		{GoodsID: 10, Quantity: 2},
+		{GoodsID: 1, Sku: "红", Quantity: 1},
+		{GoodsID: 5, Quantity: 3},
+	}
+	combinations := map[int][]structs.GoodsCombination{
+		10: {
+			{GoodsID: 1, ParentID: 10, Sku: "红", Numbers: 2},
+			{GoodsID: 2, ParentID: 10, Numbers: 0},
+		},
+	}
+	got, err := models.CombinationStockItems(items, combinations)
+	if err != nil {
+		t.Fatalf("展开套装失败: %v", err)
+	}
+	want := []structs.StockItem{
+		{GoodsID: 1, Sku: "红", Quantity: 5},
+		{GoodsID: 2, Quantity: 2},
+		{GoodsID: 5, Quantity: 3},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("库存项数量错误: %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("第%d项错误: 期望%+v，实际%+v", i, want[i], got[i])
+		}
+	}
+
+	if _, err := models.CombinationStockItems(items, map[int][]structs.GoodsCombination{10: nil}); err == nil {
+		t.Errorf("套装没有组件时应返回错误")
+	}
+}
+
+// TestOrderCombinationRows 测试订单商品的组件分摊明细按订单行记录，组件数量按每套数量×购买套数计算
+func TestOrderCombinationRows(t *testing.T) {
+	goods := structs.OrderGoods{ID: 5, Weid: 1, OrderID: 9, GoodsID: 10, Quantity: 2, Total: 200}
+	items := services.AllocateCombinationRevenue(goods.Total, []structs.CombinationItem{
+		{GoodsID: 1, Sku: "大", Price: 30, Numbers: 1},
+		{GoodsID: 2, Price: 35, Numbers: 2},
+	})
+
+	rows := services.OrderCombinationRows(goods, items)
+	if len(rows) != 2 {
+		t.Fatalf("明细数量错误: %d", len(rows))
+	}
+	sum := 0.0
+	for _, row := range rows {
+		if row.OrderID != 9 || row.OrderGoodsID != 5 || row.ParentID != 10 || row.Weid != 1 {
+			t.Errorf("明细关联的订单行错误: %+v", row)
+		}
+		sum += row.Amount
+	}
+	if rows[0].Sku != "大" || rows[0].Quantity != 2 || rows[1].Quantity != 4 {
+		t.Errorf("组件数量或规格错误: %+v", rows)
+	}
+	if math.Abs(rows[0].Amount-60) > 0.001 || math.Abs(sum-200) > 0.001 {
+		t.Errorf("分摊金额错误: %+v", rows)
+	}
+}
@@ -0,0 +1,34 @@
+package test
+
+import (
+	"testing"
+
+	"myapi/app/models"
+)
+
+// TestCommissionableAmount 测试可计佣金额扣除运费且不小于0
+func TestCommissionableAmount(t *testing.T) {
+	if got := models.CommissionableAmount(108, 8); got != 100 {
+		t.Errorf("期望可计佣金额100，实际%.2f", got)
+	}
+	if got := models.CommissionableAmount(5, 8); got != 0 {
+		t.Errorf("运费大于实付金额时期望0，实际%.2f", got)
+	}
+}
+
+// TestCapCommission 测试佣金按订单上限截断
+func TestCapCommission(t *testing.T) {
+	cases := []struct {
+		income, base, used, maxPercent, want float64
+	}{
+		{5, 100, 0, 30, 5},
+		{5, 100, 27, 30, 3},
+		{5, 100, 30, 30, 0},
+		{5, 100, 35, 30, 0},
+	}
+	for _, tc := range cases {
+		if got := models.CapCommission(tc.income, tc.base, tc.used, tc.maxPercent); got != tc.want {
+			t.Errorf("佣金%.2f已分配%.2f上限%.0f%%，期望%.2f，实际%.2f", tc.income, tc.used, tc.maxPercent, tc.want, got)
+		}
+	}
+}
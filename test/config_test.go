@@ -0,0 +1,30 @@
+package test
+
+import (
+	"testing"
+
+	"myapi/app/config"
+)
+
+// TestConfigParseValue 测试配置值的类型转换，0和1应按整数加载
+func TestConfigParseValue(t *testing.T) {
+	cases := []struct {
+		value string
+		want  interface{}
+	}{
+		{"0", 0},
+		{"1", 1},
+		{"30", 30},
+		{"-2", -2},
+		{"0.6", 0.6},
+		{"true", true},
+		{"false", false},
+		{"log", "log"},
+		{"", ""},
+	}
+	for _, c := range cases {
+		if got := config.ParseValue(c.value); got != c.want {
+			t.Errorf("ParseValue(%q) = %#v, 期望 %#v", c.value, got, c.want)
+		}
+	}
+}
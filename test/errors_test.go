@@ -0,0 +1,27 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"myapi/app/helper"
+)
+
+// TestErrorCode 测试从错误中取业务错误码，包装后的错误也能取到
+func TestErrorCode(t *testing.T) {
+	bizErr := helper.NewBizError(helper.CodeSeckillMemberLimit, "该秒杀商品每人限购2件")
+
+	if code := helper.ErrorCode(bizErr); code != helper.CodeSeckillMemberLimit {
+		t.Errorf("期望错误码%d，实际%d", helper.CodeSeckillMemberLimit, code)
+	}
+
+	wrapped := fmt.Errorf("获取商品信息失败: %w", bizErr)
+	if code := helper.ErrorCode(wrapped); code != helper.CodeSeckillMemberLimit {
+		t.Errorf("包装后期望错误码%d，实际%d", helper.CodeSeckillMemberLimit, code)
+	}
+
+	if code := helper.ErrorCode(errors.New("普通错误")); code != helper.CodeError {
+		t.Errorf("普通错误期望错误码%d，实际%d", helper.CodeError, code)
+	}
+}
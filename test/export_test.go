@@ -0,0 +1,55 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"myapi/app/helper"
+)
+
+// TestCSVBytes 测试CSV导出带BOM且小数保留两位
+func TestCSVBytes(t *testing.T) {
+	data, err := helper.CSVBytes([]string{"名称", "收入"}, [][]interface{}{{"张三", 12.5}, {"李四,王五", 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\xEF\xBB\xBF名称,收入\n张三,12.50\n\"李四,王五\",3\n"
+	if string(data) != want {
+		t.Errorf("CSV内容错误: %q", data)
+	}
+}
+
+// TestXLSXBytes 测试XLSX导出的压缩包结构和单元格内容
+func TestXLSXBytes(t *testing.T) {
+	data, err := helper.XLSXBytes("收入报表", []string{"名称", "收入"}, [][]interface{}{{"A&B", 12.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("不是有效的xlsx文件: %v", err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(body)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("缺少%s", name)
+		}
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	if !strings.Contains(sheet, "A&amp;B") || !strings.Contains(sheet, "<c><v>12.5</v></c>") {
+		t.Errorf("工作表内容错误: %s", sheet)
+	}
+}
@@ -0,0 +1,37 @@
+package test
+
+import (
+	"testing"
+
+	"myapi/app/models"
+	"myapi/app/structs"
+)
+
+// TestOrderStockItems 测试订单商品按商品和规格合并为库存预留项
+func TestOrderStockItems(t *testing.T) {
+	goods := []structs.OrderGoods{
+		{GoodsID: 1, Sku: "红", Quantity: 2},
+		{GoodsID: 2, Quantity: 1},
+		{GoodsID: 1, Sku: "蓝", Quantity: 1},
+		{GoodsID: 1, Sku: "红", Quantity: 3},
+		{GoodsID: 2, Quantity: 4},
+	}
+	items := models.OrderStockItems(goods)
+	want := []structs.StockItem{
+		{GoodsID: 1, Sku: "红", Quantity: 5},
+		{GoodsID: 2, Quantity: 5},
+		{GoodsID: 1, Sku: "蓝", Quantity: 1},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("预留项数量错误: %+v", items)
+	}
+	for i := range want {
+		if items[i] != want[i] {
+			t.Errorf("第%d项错误: 期望%+v，实际%+v", i, want[i], items[i])
+		}
+	}
+
+	if items := models.OrderStockItems(nil); len(items) != 0 {
+		t.Errorf("没有订单商品时应返回空列表: %+v", items)
+	}
+}
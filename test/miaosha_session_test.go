@@ -0,0 +1,60 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"myapi/app/services"
+	"myapi/app/structs"
+)
+
+// TestBuildMiaoshaSession 测试秒杀场次状态和倒计时计算
+func TestBuildMiaoshaSession(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 30, 0, 0, time.Local)
+
+	cases := []struct {
+		name      string
+		slot      structs.MiaoshaTime
+		status    int
+		countdown int64
+	}{
+		{"已结束", structs.MiaoshaTime{ID: 1, BeginTime: "08:00", EndTime: "10:00"}, structs.MiaoshaSessionEnded, 0},
+		{"抢购中", structs.MiaoshaTime{ID: 2, BeginTime: "10:00", EndTime: "12:00"}, structs.MiaoshaSessionOngoing, 5400},
+		{"即将开始", structs.MiaoshaTime{ID: 3, BeginTime: "12:00:00", EndTime: "14:00:00"}, structs.MiaoshaSessionUpcoming, 5400},
+		{"跨零点", structs.MiaoshaTime{ID: 4, BeginTime: "22", EndTime: "02"}, structs.MiaoshaSessionUpcoming, 41400},
+	}
+	for _, tc := range cases {
+		session, ok := services.BuildMiaoshaSession(tc.slot, now)
+		if !ok {
+			t.Errorf("%s: 场次解析失败", tc.name)
+			continue
+		}
+		if session.Status != tc.status || session.Countdown != tc.countdown {
+			t.Errorf("%s: 期望状态%d倒计时%d，实际状态%d倒计时%d", tc.name, tc.status, tc.countdown, session.Status, session.Countdown)
+		}
+	}
+
+	// 跨零点场次在次日凌晨仍处于抢购中
+	night := time.Date(2024, 5, 2, 1, 0, 0, 0, time.Local)
+	session, _ := services.BuildMiaoshaSession(structs.MiaoshaTime{BeginTime: "22:00", EndTime: "02:00"}, night)
+	if session.Status != structs.MiaoshaSessionOngoing || session.Countdown != 3600 {
+		t.Errorf("跨零点场次状态错误: 状态%d倒计时%d", session.Status, session.Countdown)
+	}
+
+	if _, ok := services.BuildMiaoshaSession(structs.MiaoshaTime{BeginTime: "10:61", EndTime: "11:00"}, now); ok {
+		t.Errorf("无效时间不应解析成功")
+	}
+}
+
+// TestSoldPercent 测试已售百分比计算
+func TestSoldPercent(t *testing.T) {
+	if p := services.SoldPercent(30, 70); p != 30 {
+		t.Errorf("期望30，实际%d", p)
+	}
+	if p := services.SoldPercent(10, 0); p != 100 {
+		t.Errorf("售罄期望100，实际%d", p)
+	}
+	if p := services.SoldPercent(10, -1); p != 0 {
+		t.Errorf("不限量期望0，实际%d", p)
+	}
+}
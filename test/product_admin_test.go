@@ -0,0 +1,48 @@
+package test
+
+import (
+	"testing"
+
+	"myapi/app/services"
+)
+
+// TestProductValidatorScenes 测试商品校验场景，新增不校验ID，修改必须有ID
+func TestProductValidatorScenes(t *testing.T) {
+	valid := map[string]interface{}{
+		"name":           "空调清洗",
+		"cat_id":         3,
+		"price":          99.0,
+		"original_price": 129.0,
+		"quantity":       10,
+		"minimum":        1,
+	}
+	v := services.NewProductValidator()
+	if ok, msg := v.Check(valid, "create"); !ok {
+		t.Errorf("新增场景不应校验失败: %s", msg)
+	}
+	if ok, _ := v.Check(valid, "update"); ok {
+		t.Errorf("修改场景缺少商品ID应校验失败")
+	}
+
+	valid["id"] = 8
+	if ok, msg := v.Check(valid, "update"); !ok {
+		t.Errorf("修改场景不应校验失败: %s", msg)
+	}
+
+	cases := map[string]interface{}{
+		"name":     "",
+		"cat_id":   0,
+		"price":    -1.0,
+		"quantity": -2,
+	}
+	for field, value := range cases {
+		data := make(map[string]interface{}, len(valid))
+		for k, val := range valid {
+			data[k] = val
+		}
+		data[field] = value
+		if ok, _ := v.Check(data, "create"); ok {
+			t.Errorf("字段%s为%v时应校验失败", field, value)
+		}
+	}
+}
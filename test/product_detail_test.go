@@ -0,0 +1,30 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"myapi/app/services"
+	"myapi/app/structs"
+)
+
+// TestBuildProductSpecs 测试规格矩阵去掉空规格、空项和重复项
+func TestBuildProductSpecs(t *testing.T) {
+	skus := []structs.GoodsSku{
+		{GoodsID: 1, Name: "颜色", Item: "红色, 蓝色,,红色", Ptype: "radio", Required: 1},
+		{GoodsID: 1, Name: " ", Item: "无名"},
+		{GoodsID: 1, Name: "尺寸", Item: ","},
+		{GoodsID: 1, Name: "尺寸", Item: "S,M,L", Ptype: "radio"},
+	}
+
+	specs := services.BuildProductSpecs(skus)
+	if len(specs) != 2 {
+		t.Fatalf("规格数量错误: %d", len(specs))
+	}
+	if specs[0].Name != "颜色" || !reflect.DeepEqual(specs[0].Items, []string{"红色", "蓝色"}) || specs[0].Required != 1 {
+		t.Errorf("颜色规格错误: %+v", specs[0])
+	}
+	if !reflect.DeepEqual(specs[1].Items, []string{"S", "M", "L"}) {
+		t.Errorf("尺寸规格错误: %+v", specs[1])
+	}
+}
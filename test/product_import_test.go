@@ -0,0 +1,126 @@
+package test
+
+import (
+	"testing"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+	"myapi/app/structs"
+)
+
+// TestProductImportRoundTrip 测试导出的CSV和xlsx可以原样读回并解析
+func TestProductImportRoundTrip(t *testing.T) {
+	products := []structs.Product{
+		{ID: 1, Model: "KT-01", Name: "空调清洗", CatID: 3, Price: 99, OriginalPrice: 129, Quantity: 10, QuantityUnit: "次", Status: 1},
+		{ID: 2, Model: "", Name: "油烟机, 深度清洗", CatID: 4, Price: 158.5, Status: 0},
+	}
+	skuMap := map[int][]structs.GoodsSkuValue{
+		1: {
+			{ID: 11, GoodsID: 1, Sku: "1匹", Price: 99, Quantity: 5},
+			{ID: 12, GoodsID: 1, Sku: "2匹", Price: 139, Quantity: 0},
+		},
+	}
+	exported := services.ProductExportRows(products, skuMap)
+	if len(exported) != 3 {
+		t.Fatalf("导出行数错误: %d", len(exported))
+	}
+
+	for _, format := range []string{helper.ExportCSV, helper.ExportXLSX} {
+		data, err := helper.ExportFile(format, "商品", services.ProductImportHeader, exported)
+		if err != nil {
+			t.Fatalf("%s导出失败: %v", format, err)
+		}
+		records, err := helper.ReadSpreadsheet("products."+format, data)
+		if err != nil {
+			t.Fatalf("%s读取失败: %v", format, err)
+		}
+		rows, errs, err := services.ParseProductImportRows(records)
+		if err != nil || len(errs) > 0 {
+			t.Fatalf("%s解析失败: %v %v", format, err, errs)
+		}
+		if len(rows) != 3 {
+			t.Fatalf("%s解析行数错误: %d", format, len(rows))
+		}
+		first, last := rows[1], rows[2]
+		if first.Row != 3 || first.ID != 1 || first.Sku != "2匹" || first.SkuPrice == nil || *first.SkuPrice != 139 ||
+			first.SkuQuantity == nil || *first.SkuQuantity != 0 || first.QuantityUnit != "次" {
+			t.Errorf("%s第二行解析错误: %+v", format, first)
+		}
+		if last.Name != "油烟机, 深度清洗" || last.Price != 158.5 || last.Status != 0 || last.Sku != "" ||
+			last.SkuPrice != nil || last.SkuQuantity != nil {
+			t.Errorf("%s第三行解析错误: %+v", format, last)
+		}
+	}
+}
+
+// TestParseProductImportRows 测试按表头名称取列、缺列报错和单元格格式错误
+func TestParseProductImportRows(t *testing.T) {
+	if _, _, err := services.ParseProductImportRows([][]string{{"商品ID", "商品名称"}, {"1", "a"}}); err == nil {
+		t.Errorf("缺少列时应返回错误")
+	}
+
+	header := make([]string, len(services.ProductImportHeader))
+	copy(header, services.ProductImportHeader)
+	// 调换列顺序
+	header[0], header[2] = header[2], header[0]
+	row := func(values map[string]string) []string {
+		record := make([]string, len(header))
+		for i, name := range header {
+			record[i] = values[name]
+		}
+		return record
+	}
+	records := [][]string{
+		header,
+		row(map[string]string{"商品名称": "空调清洗", "商品ID": "7", "分类ID": "3.0", "价格": "99"}),
+		row(map[string]string{"商品名称": "价格错误", "型号": "A1", "价格": "abc"}),
+		row(map[string]string{"商品名称": "库存小数", "型号": "A2", "库存": "1.5"}),
+		row(map[string]string{"商品名称": "状态错误", "型号": "A3", "状态": "2"}),
+		row(map[string]string{"商品名称": "SKU库存", "型号": "A4", "SKU规格": "大", "SKU库存": "-1"}),
+		{"短行"},
+	}
+	rows, errs, err := services.ParseProductImportRows(records)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(rows) != 2 || rows[0].ID != 7 || rows[0].CatID != 3 || rows[0].Name != "空调清洗" || rows[0].Status != 1 {
+		t.Fatalf("解析结果错误: %+v", rows)
+	}
+	if rows[1].Row != 7 || rows[1].Name != "短行" || rows[1].Price != 0 {
+		t.Errorf("短行缺少的列应为空: %+v", rows[1])
+	}
+	wantRows := []int{3, 4, 5, 6}
+	if len(errs) != len(wantRows) {
+		t.Fatalf("错误数量错误: %+v", errs)
+	}
+	for i, e := range errs {
+		if e.Row != wantRows[i] || e.Msg == "" {
+			t.Errorf("第%d个错误不符: %+v", i, e)
+		}
+	}
+}
+
+// TestGroupProductImportRows 测试按商品ID或型号归并，以及缺少标识和SKU重复
+func TestGroupProductImportRows(t *testing.T) {
+	rows := []structs.ProductImportRow{
+		{Row: 2, ID: 1, Name: "a", Sku: "红"},
+		{Row: 3, Model: "M1", Name: "b"},
+		{Row: 4, ID: 1, Name: "a", Sku: "蓝"},
+		{Row: 5, Name: "c"},
+		{Row: 6, ID: 1, Name: "a", Sku: "红"},
+		{Row: 7, Model: "M1", Name: "b", Sku: "红"},
+	}
+	groups, errs := services.GroupProductImportRows(rows)
+	if len(groups) != 2 {
+		t.Fatalf("归并后商品数错误: %d", len(groups))
+	}
+	if len(groups[0]) != 2 || groups[0][1].Row != 4 {
+		t.Errorf("按商品ID归并错误: %+v", groups[0])
+	}
+	if len(groups[1]) != 2 || groups[1][1].Row != 7 {
+		t.Errorf("按型号归并错误: %+v", groups[1])
+	}
+	if len(errs) != 2 || errs[0].Row != 5 || errs[1].Row != 6 {
+		t.Errorf("错误行不符: %+v", errs)
+	}
+}
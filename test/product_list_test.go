@@ -0,0 +1,53 @@
+package test
+
+import (
+	"testing"
+
+	"myapi/app/services"
+	"myapi/app/structs"
+)
+
+// TestProductCursorRoundTrip 测试商品列表游标编码后可以原样解析
+func TestProductCursorRoundTrip(t *testing.T) {
+	cases := []struct {
+		value float64
+		id    int
+	}{
+		{12.5, 30},
+		{0, 1},
+		{-3, 7},
+		{1700000000, 99},
+	}
+	for _, tc := range cases {
+		cursor := services.EncodeProductCursor(tc.value, tc.id)
+		parsed, err := services.ParseProductCursor(cursor)
+		if err != nil {
+			t.Fatalf("解析游标%s失败: %v", cursor, err)
+		}
+		if parsed.Value != tc.value || parsed.ID != tc.id {
+			t.Errorf("游标%s解析错误: %+v", cursor, parsed)
+		}
+	}
+
+	for _, cursor := range []string{"", "abc", "_1", "1_", "1_x", "1_0", "x_1"} {
+		if _, err := services.ParseProductCursor(cursor); err == nil {
+			t.Errorf("无效游标%q应该解析失败", cursor)
+		}
+	}
+}
+
+// TestProductSortValue 测试各排序方式取值，销量包含销量基数
+func TestProductSortValue(t *testing.T) {
+	product := structs.Product{Price: 9.9, SaleCount: 10, SaleCountBase: 5, CreateTime: 1700000000, Sort: 3}
+	expected := map[string]float64{
+		structs.ProductSortPrice:   9.9,
+		structs.ProductSortSales:   15,
+		structs.ProductSortNewest:  1700000000,
+		structs.ProductSortDefault: 3,
+	}
+	for sort, value := range expected {
+		if got := services.ProductSortValue(product, sort); got != value {
+			t.Errorf("排序%s取值错误，期望%v，实际%v", sort, value, got)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"testing"
+
+	"myapi/app/services"
+	"myapi/app/structs"
+)
+
+// TestCalculateReferralCommission 测试按百分比和每件固定金额计算各级佣金
+func TestCalculateReferralCommission(t *testing.T) {
+	percent := services.ReferralRule{Method: structs.CommissionMethodPercent, Rates: []float64{10, 5, 2}}
+	fixed := services.ReferralRule{Method: structs.CommissionMethodFixed, Rates: []float64{3, 1.5, 0}}
+	cases := []struct {
+		rule     services.ReferralRule
+		level    int
+		total    float64
+		quantity int
+		want     float64
+	}{
+		{percent, 1, 99.9, 1, 9.99},
+		{percent, 2, 99.9, 1, 5},
+		{percent, 3, 99.9, 1, 2},
+		{percent, 4, 99.9, 1, 0},
+		{fixed, 1, 50, 3, 9},
+		{fixed, 2, 50, 3, 4.5},
+		{fixed, 3, 50, 3, 0},
+	}
+	for _, tc := range cases {
+		if got := services.CalculateReferralCommission(tc.rule, tc.level, tc.total, tc.quantity); got != tc.want {
+			t.Errorf("方式%d第%d级，期望%.2f，实际%.2f", tc.rule.Method, tc.level, tc.want, got)
+		}
+	}
+}
+
+// TestProductReferralRule 测试商品独立佣金按全局比例换算二、三级
+func TestProductReferralRule(t *testing.T) {
+	global := services.ReferralRule{Method: structs.CommissionMethodPercent, Rates: []float64{10, 5, 2}}
+	product := &structs.Product{IsCommission: 1, CommissionMethod: structs.CommissionMethodFixed, CommissionPrice: 4}
+
+	rule := services.ProductReferralRule(global, product)
+	if rule.Method != structs.CommissionMethodFixed || rule.Rates[0] != 4 || rule.Rates[1] != 2 || rule.Rates[2] != 0.8 {
+		t.Errorf("商品佣金规则换算错误: %+v", rule)
+	}
+
+	product.IsCommission = 0
+	if rule := services.ProductReferralRule(global, product); rule.Rates[0] != 10 {
+		t.Errorf("未开启独立佣金时应使用全局规则: %+v", rule)
+	}
+}
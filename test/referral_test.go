@@ -0,0 +1,26 @@
+package test
+
+import (
+	"testing"
+
+	"myapi/app/services"
+)
+
+// TestShareToken 测试分享令牌的签名校验和过期
+func TestShareToken(t *testing.T) {
+	token := services.SignShareToken("secret", 42, 2000)
+
+	uid, err := services.ParseShareToken("secret", token, 1000)
+	if err != nil || uid != 42 {
+		t.Fatalf("期望解析出邀请人42，实际%d，错误%v", uid, err)
+	}
+	if _, err := services.ParseShareToken("secret", token, 3000); err == nil {
+		t.Error("过期的分享令牌应校验失败")
+	}
+	if _, err := services.ParseShareToken("other", token, 1000); err == nil {
+		t.Error("密钥不同的分享令牌应校验失败")
+	}
+	if _, err := services.ParseShareToken("secret", "43.2000."+token[len("42.2000."):], 1000); err == nil {
+		t.Error("篡改邀请人的分享令牌应校验失败")
+	}
+}
@@ -0,0 +1,63 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"myapi/app/helper"
+	"myapi/app/services"
+)
+
+// TestSegmentText 测试索引分词，英文转小写整词保留，汉字输出单字和双字
+func TestSegmentText(t *testing.T) {
+	got := helper.SegmentText("iPhone15 苹果手机")
+	expected := []string{"iphone15", "苹", "苹果", "果", "果手", "手", "手机", "机"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("分词结果错误: %v", got)
+	}
+}
+
+// TestSegmentQuery 测试搜索词分词，汉字只取双字，单字保留，结果去重
+func TestSegmentQuery(t *testing.T) {
+	got := helper.SegmentQuery("苹果 苹果手机 Pro 杯")
+	expected := []string{"苹果", "果手", "手机", "pro", "杯"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("搜索词分词结果错误: %v", got)
+	}
+}
+
+// TestPinyinInitials 测试拼音首字母，字母数字保留，符号忽略
+func TestPinyinInitials(t *testing.T) {
+	cases := map[string]string{
+		"苹果手机":      "pgsj",
+		"蓝牙耳机 Pro":  "lyejpro",
+		"保温杯-500ml": "bwb500ml",
+	}
+	for text, expected := range cases {
+		if got := helper.PinyinInitials(text); got != expected {
+			t.Errorf("%s的拼音首字母错误，期望%s，实际%s", text, expected, got)
+		}
+	}
+}
+
+// TestHighlightText 测试高亮合并相邻命中、忽略大小写并转义HTML
+func TestHighlightText(t *testing.T) {
+	got := helper.HighlightText("Apple 苹果手机<新>", []string{"苹果", "果手", "apple"})
+	expected := "<em>Apple</em> <em>苹果手</em>机&lt;新&gt;"
+	if got != expected {
+		t.Errorf("高亮结果错误: %s", got)
+	}
+}
+
+// TestSearchScore 测试销量加权，相关度相同时销量高的排前面
+func TestSearchScore(t *testing.T) {
+	if services.SearchScore(3, 0, 0.1) != 3 {
+		t.Errorf("无销量时应等于相关度")
+	}
+	if services.SearchScore(3, 100, 0.1) <= services.SearchScore(3, 10, 0.1) {
+		t.Errorf("销量高的得分应更高")
+	}
+	if services.SearchScore(3, -5, 0.1) != 3 {
+		t.Errorf("负销量应按0处理")
+	}
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"testing"
+
+	"myapi/app/services"
+	"myapi/app/structs"
+)
+
+// TestCalculateTransportFee 测试首件续件运费计算
+func TestCalculateTransportFee(t *testing.T) {
+	rule := &structs.TransportExtend{Snum: 1, Sprice: 10, Xnum: 2, Xprice: 5}
+
+	cases := []struct {
+		measure  float64
+		expected float64
+	}{
+		{0, 0},
+		{1, 10},
+		{2, 15},
+		{3, 15},
+		{4, 20},
+		{1.5, 15},
+	}
+	for _, tc := range cases {
+		if fee := services.CalculateTransportFee(rule, tc.measure); fee != tc.expected {
+			t.Errorf("计费量%.1f的运费错误，期望%.2f，实际%.2f", tc.measure, tc.expected, fee)
+		}
+	}
+}
+
+// TestMatchTransportArea 测试按城市、省份、默认地区的顺序匹配运费规则
+func TestMatchTransportArea(t *testing.T) {
+	rules := []structs.TransportExtend{
+		{ID: 1, IsDefault: 1},
+		{ID: 2, TopAreaID: "440000", AreaID: "440300", AreaName: "广东省 深圳市"},
+		{ID: 3, AreaID: "440100,440600", AreaName: "广州市,佛山市"},
+	}
+
+	cases := []struct {
+		name     string
+		address  *structs.Address
+		expected int
+	}{
+		{"城市优先", &structs.Address{ProvinceId: 440000, CityId: 440100}, 3},
+		{"省份匹配", &structs.Address{ProvinceId: 440000, CityId: 441900}, 2},
+		{"默认地区", &structs.Address{ProvinceId: 110000, CityId: 110100}, 1},
+		{"名称匹配", &structs.Address{CityName: "佛山市"}, 3},
+	}
+	for _, tc := range cases {
+		rule := services.MatchTransportArea(rules, tc.address)
+		if rule == nil || rule.ID != tc.expected {
+			t.Errorf("%s: 期望匹配规则%d，实际%v", tc.name, tc.expected, rule)
+		}
+	}
+
+	if rule := services.MatchTransportArea(rules[1:], &structs.Address{ProvinceId: 110000}); rule != nil {
+		t.Errorf("没有默认地区时不应匹配规则，实际%d", rule.ID)
+	}
+}
@@ -0,0 +1,97 @@
+package test
+
+import (
+	"testing"
+
+	"myapi/app/services"
+	"myapi/app/structs"
+)
+
+// TestBuildStockAlerts 测试库存越过阈值和售罄时的提醒，以及SKU阈值继承商品阈值
+func TestBuildStockAlerts(t *testing.T) {
+	products := []structs.Product{
+		{ID: 1, Weid: 2, Sid: 3, Name: "空调清洗", LowStock: 5},
+		{ID: 2, Weid: 2, Name: "油烟机清洗"},
+		{ID: 3, Weid: 2, Name: "洗衣机清洗"},
+	}
+	skuMap := map[int][]structs.GoodsSkuValue{
+		1: {
+			{ID: 11, GoodsID: 1, Sku: "1匹", LowStock: 2},
+			{ID: 12, GoodsID: 1, Sku: "2匹"},
+		},
+	}
+	entries := []structs.GoodsStockLedger{
+		// 商品8降到5，越过商品阈值；SKU 3降到0售罄
+		{RecordID: 9, GoodsID: 1, SkuvID: 11, Sku: "1匹", ChangeQuantity: -3, QuantityAfter: 5, SkuQuantityAfter: 0},
+		// 商品5降到4已低于阈值不再提醒；SKU 6降到5，继承商品阈值5
+		{RecordID: 9, GoodsID: 1, SkuvID: 12, Sku: "2匹", ChangeQuantity: -1, QuantityAfter: 4, SkuQuantityAfter: 5},
+		// 使用默认阈值3
+		{RecordID: 9, GoodsID: 2, ChangeQuantity: -2, QuantityAfter: 3},
+		// 回补不提醒
+		{RecordID: 9, GoodsID: 3, ChangeQuantity: 2, QuantityAfter: 2},
+		// 商品不存在
+		{RecordID: 9, GoodsID: 4, ChangeQuantity: -1, QuantityAfter: 0},
+	}
+	alerts := services.BuildStockAlerts(entries, products, skuMap, 3)
+	want := []structs.StockAlert{
+		{Type: structs.StockAlertLow, Weid: 2, Sid: 3, GoodsID: 1, Name: "空调清洗", Quantity: 5, Threshold: 5, RecordID: 9},
+		{Type: structs.StockAlertOut, Weid: 2, Sid: 3, GoodsID: 1, Name: "空调清洗", SkuvID: 11, Sku: "1匹", Quantity: 0, Threshold: 2, RecordID: 9},
+		{Type: structs.StockAlertLow, Weid: 2, Sid: 3, GoodsID: 1, Name: "空调清洗", SkuvID: 12, Sku: "2匹", Quantity: 5, Threshold: 5, RecordID: 9},
+		{Type: structs.StockAlertLow, Weid: 2, GoodsID: 2, Name: "油烟机清洗", Quantity: 3, Threshold: 3, RecordID: 9},
+	}
+	if len(alerts) != len(want) {
+		t.Fatalf("提醒数量错误: %+v", alerts)
+	}
+	for i := range want {
+		if alerts[i] != want[i] {
+			t.Errorf("第%d条提醒错误: 期望%+v，实际%+v", i, want[i], alerts[i])
+		}
+	}
+
+	// 阈值为0时只有售罄提醒
+	alerts = services.BuildStockAlerts([]structs.GoodsStockLedger{
+		{GoodsID: 2, ChangeQuantity: -1, QuantityAfter: 1},
+		{GoodsID: 2, ChangeQuantity: -1, QuantityAfter: 0},
+	}, products, nil, 0)
+	if len(alerts) != 1 || alerts[0].Type != structs.StockAlertOut {
+		t.Errorf("阈值为0时应只有售罄提醒: %+v", alerts)
+	}
+}
+
+// TestCrossedStockAlert 测试库存变动越过售罄和低库存阈值的判断，已低于阈值或回补时不提醒
+func TestCrossedStockAlert(t *testing.T) {
+	cases := []struct {
+		after, change, threshold int
+		want                     string
+	}{
+		{0, -2, 5, structs.StockAlertOut},  // 2降到0售罄
+		{-1, -3, 0, structs.StockAlertOut}, // 超卖时同样为售罄
+		{5, -1, 5, structs.StockAlertLow},  // 6降到5越过阈值
+		{3, -4, 5, structs.StockAlertLow},  // 7降到3越过阈值
+		{4, -1, 5, ""},                     // 5降到4已低于阈值
+		{0, -1, 0, structs.StockAlertOut},  // 阈值为0时只有售罄提醒
+		{3, -1, 0, ""},
+		{0, 0, 5, ""}, // 支付确认不变动库存
+		{6, 2, 5, ""}, // 回补
+	}
+	for _, c := range cases {
+		if got := services.CrossedStockAlert(c.after, c.change, c.threshold); got != c.want {
+			t.Errorf("CrossedStockAlert(%d, %d, %d) = %q, 期望%q", c.after, c.change, c.threshold, got, c.want)
+		}
+	}
+}
+
+// TestBuildStockAlertsOrder 测试预留超时后支付重新扣减的流水提醒带上订单ID，没有扣减流水时不提醒
+func TestBuildStockAlertsOrder(t *testing.T) {
+	products := []structs.Product{{ID: 1, Weid: 2, Name: "空调清洗", LowStock: 5}}
+	alerts := services.BuildStockAlerts([]structs.GoodsStockLedger{
+		{OrderID: 7, GoodsID: 1, Type: structs.StockMoveReserve, ChangeQuantity: -2, QuantityAfter: 4},
+	}, products, nil, 0)
+	if len(alerts) != 1 || alerts[0].OrderID != 7 || alerts[0].RecordID != 0 || alerts[0].Type != structs.StockAlertLow {
+		t.Errorf("订单重新扣减的提醒错误: %+v", alerts)
+	}
+
+	if alerts := services.BuildStockAlerts(nil, products, nil, 0); len(alerts) != 0 {
+		t.Errorf("没有扣减流水时不应提醒: %+v", alerts)
+	}
+}
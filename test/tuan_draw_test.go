@@ -0,0 +1,46 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"myapi/app/services"
+)
+
+// TestDrawTuanWinners 测试抽奖结果只取决于种子和候选名单，且中奖人数正确
+func TestDrawTuanWinners(t *testing.T) {
+	candidates := []int{11, 12, 13, 14, 15, 16}
+	seed := "8f2c0a9d5e"
+
+	first := services.DrawTuanWinners(seed, candidates, 2)
+	if len(first) != 2 {
+		t.Fatalf("期望中奖2人，实际%d人", len(first))
+	}
+	second := services.DrawTuanWinners(seed, []int{16, 15, 14, 13, 12, 11}, 2)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("相同种子和候选名单的开奖结果不一致: %v %v", first, second)
+	}
+
+	for _, id := range first {
+		found := false
+		for _, c := range candidates {
+			if c == id {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("中奖者%d不在候选名单中", id)
+		}
+	}
+}
+
+// TestDrawTuanWinnersAll 测试中奖人数不限或不少于候选人数时全部中奖
+func TestDrawTuanWinnersAll(t *testing.T) {
+	candidates := []int{1, 2, 3}
+	for _, num := range []int{0, 3, 5} {
+		winners := services.DrawTuanWinners("seed", candidates, num)
+		if !reflect.DeepEqual(winners, candidates) {
+			t.Errorf("中奖人数%d时期望全部中奖，实际%v", num, winners)
+		}
+	}
+}
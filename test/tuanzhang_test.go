@@ -0,0 +1,27 @@
+package test
+
+import (
+	"testing"
+
+	"myapi/app/services"
+)
+
+// TestCalculateTuanzhangIncome 测试团长收入按返点百分比计算并保留两位小数
+func TestCalculateTuanzhangIncome(t *testing.T) {
+	cases := []struct {
+		total   float64
+		percent float64
+		want    float64
+	}{
+		{100, 5, 5},
+		{99.9, 5, 5},
+		{88.88, 3.5, 3.11},
+		{100, 0, 0},
+		{0, 5, 0},
+	}
+	for _, tc := range cases {
+		if got := services.CalculateTuanzhangIncome(tc.total, tc.percent); got != tc.want {
+			t.Errorf("订单金额%.2f返点%.2f%%，期望收入%.2f，实际%.2f", tc.total, tc.percent, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package test
+
+import (
+	"testing"
+
+	"myapi/app/config"
+	"myapi/app/services"
+
+	"github.com/joho/godotenv"
+)
+
+// TestCalculateWithdrawFee 测试提现手续费按比例计算并受最低、最高手续费限制
+func TestCalculateWithdrawFee(t *testing.T) {
+	cases := []struct {
+		amount, percent, minFee, maxFee, want float64
+	}{
+		{100, 0, 0, 0, 0},
+		{100, 0.6, 0, 0, 0.6},
+		{100, 0.6, 1, 0, 1},
+		{10000, 0.6, 1, 25, 25},
+		{0.5, 0, 1, 0, 0.5},
+	}
+	for _, tc := range cases {
+		if got := services.CalculateWithdrawFee(tc.amount, tc.percent, tc.minFee, tc.maxFee); got != tc.want {
+			t.Errorf("提现%.2f费率%.2f%%，期望手续费%.2f，实际%.2f", tc.amount, tc.percent, tc.want, got)
+		}
+	}
+}
+
+// TestWithdrawFeeConfigIsNumeric 测试mall.env中的提现手续费配置按数字加载，0不会被当作false
+func TestWithdrawFeeConfigIsNumeric(t *testing.T) {
+	envMap, err := godotenv.Read("../env/mall.env")
+	if err != nil {
+		t.Fatalf("读取配置文件失败: %v", err)
+	}
+	for _, key := range []string{"withdrawFeePercent", "withdrawFeeMin", "withdrawFeeMax"} {
+		value, ok := envMap[key]
+		if !ok {
+			t.Errorf("缺少配置%s", key)
+			continue
+		}
+		switch config.ParseValue(value).(type) {
+		case int, float64:
+		default:
+			t.Errorf("配置%s=%s没有按数字加载", key, value)
+		}
+	}
+}